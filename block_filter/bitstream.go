@@ -0,0 +1,53 @@
+package block_filter
+
+// writes bits most significant first, as used by the Golomb-Rice coding of filters
+type bitWriter struct {
+	data  []byte
+	nBits uint8 //bits used in the last byte
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.nBits == 0 {
+		w.data = append(w.data, 0)
+		w.nBits = 8
+	}
+	w.nBits--
+	if bit {
+		w.data[len(w.data)-1] |= 1 << w.nBits
+	}
+}
+
+func (w *bitWriter) writeBits(value uint64, n uint8) {
+	for i := int(n) - 1; i >= 0; i-- {
+		w.writeBit(value>>i&1 == 1)
+	}
+}
+
+type bitReader struct {
+	data []byte
+	pos  int //index of the next bit
+}
+
+func (r *bitReader) readBit() (bool, bool) {
+	if r.pos >= 8*len(r.data) {
+		return false, false
+	}
+	bit := r.data[r.pos/8]>>(7-r.pos%8)&1 == 1
+	r.pos++
+	return bit, true
+}
+
+func (r *bitReader) readBits(n uint8) (uint64, bool) {
+	var value uint64
+	for i := uint8(0); i < n; i++ {
+		bit, ok := r.readBit()
+		if !ok {
+			return 0, false
+		}
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,202 @@
+// Package block_filter implements BIP158 style compact block filters, which let light clients find out whether a
+// block is relevant to them without revealing which addresses they're interested in.
+//
+// A filter is a Golomb-coded set of the block's elements: the pubkey hash of each output (or its whole script if it
+// isn't pay to pubkey hash) and each outpoint spent by the block. Elements are hashed with SipHash keyed by the
+// first 16 bytes of the block hash into [0, N*M), sorted and the differences between them Golomb-Rice coded with
+// parameter P. Each filter is committed to by a filter header, sha256(filter hash || previous filter header),
+// which chain like block headers so that a client can check filters served by different peers.
+package block_filter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/bits"
+	"sort"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+// parameters of BIP158's basic filter, giving a false positive rate of about 1/M
+const P = 19
+const M = 784931
+
+type Filter struct {
+	N    uint32 //number of elements
+	Data []byte //Golomb-Rice coded differences between the sorted element hashes
+}
+
+// key of the filter of the block with the given hash
+func FilterKey(blockHash []byte) [16]byte {
+	var key [16]byte
+	copy(key[:], blockHash)
+	return key
+}
+
+// element matched by outputs paying to the given script
+func ScriptElement(scriptPubKey []byte) []byte {
+	if pubKeyHash := script.ExtractPubKeyHash(scriptPubKey); pubKeyHash != nil {
+		return pubKeyHash
+	}
+	return scriptPubKey
+}
+
+// element matched by inputs spending the given outpoint
+func OutPointElement(txid []byte, outIndex int) []byte {
+	return binary.LittleEndian.AppendUint32(append([]byte{}, txid...), uint32(outIndex))
+}
+
+// elements of the block with the given transactions
+func BlockElements(txs []*transactions.Transaction) [][]byte {
+	var elements [][]byte
+	for _, tx := range txs {
+		for _, out := range tx.Vout {
+			if out.IsData() || len(out.ScriptPubKey) == 0 {
+				continue
+			}
+			elements = append(elements, ScriptElement(out.ScriptPubKey))
+		}
+		if tx.IsCoinbase {
+			continue
+		}
+		for _, in := range tx.Vin {
+			elements = append(elements, OutPointElement(in.Txid, in.OutIndex))
+		}
+	}
+	return elements
+}
+
+// maps an element uniformly to [0, f)
+func hashToRange(key [16]byte, element []byte, f uint64) uint64 {
+	hi, _ := bits.Mul64(utils.SipHash(key, element), f)
+	return hi
+}
+
+func hashedSet(key [16]byte, elements [][]byte, n uint32) []uint64 {
+	f := uint64(n) * M
+	hashes := make([]uint64, 0, len(elements))
+	for _, element := range elements {
+		hashes = append(hashes, hashToRange(key, element, f))
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
+// builds the filter of the given elements, ignoring duplicates
+func NewFilter(key [16]byte, elements [][]byte) *Filter {
+	seen := make(map[string]bool)
+	var unique [][]byte
+	for _, element := range elements {
+		if !seen[string(element)] {
+			seen[string(element)] = true
+			unique = append(unique, element)
+		}
+	}
+
+	filter := &Filter{N: uint32(len(unique))}
+	var w bitWriter
+	var prev uint64
+	for _, hash := range hashedSet(key, unique, filter.N) {
+		delta := hash - prev
+		prev = hash
+		//quotient in unary, then the remainder's P bits
+		for q := delta >> P; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, P)
+	}
+	filter.Data = w.data
+	return filter
+}
+
+// decodes the element hashes, in increasing order
+func (filter *Filter) hashes() ([]uint64, error) {
+	r := bitReader{data: filter.Data}
+	hashes := make([]uint64, 0, filter.N)
+	var prev uint64
+	for i := uint32(0); i < filter.N; i++ {
+		var q uint64
+		for {
+			bit, ok := r.readBit()
+			if !ok {
+				return nil, &blockchain_errors.ErrMalformedEncoding{}
+			}
+			if !bit {
+				break
+			}
+			q++
+		}
+		remainder, ok := r.readBits(P)
+		if !ok {
+			return nil, &blockchain_errors.ErrMalformedEncoding{}
+		}
+		prev += q<<P | remainder
+		hashes = append(hashes, prev)
+	}
+	return hashes, nil
+}
+
+// whether any of the elements may be in the filter. false positives happen with probability about 1/M per element
+func (filter *Filter) MatchAny(key [16]byte, elements [][]byte) (bool, error) {
+	if filter.N == 0 || len(elements) == 0 {
+		return false, nil
+	}
+	hashes, err := filter.hashes()
+	if err != nil {
+		return false, err
+	}
+	queries := hashedSet(key, elements, filter.N)
+
+	//both lists are sorted, so they're merged in a single pass
+	for i, j := 0, 0; i < len(hashes) && j < len(queries); {
+		switch {
+		case hashes[i] == queries[j]:
+			return true, nil
+		case hashes[i] < queries[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return false, nil
+}
+
+func (filter *Filter) Match(key [16]byte, element []byte) (bool, error) {
+	return filter.MatchAny(key, [][]byte{element})
+}
+
+// encodes the filter as its number of elements (a varint) followed by the coded data
+func (filter *Filter) Serialize() []byte {
+	w := serialization.NewWriter()
+	w.WriteVarInt(uint64(filter.N))
+	return append(w.Bytes(), filter.Data...)
+}
+
+func DecodeFilter(data []byte) (*Filter, error) {
+	r := serialization.NewReader(data)
+	n := r.ReadVarInt()
+	if r.Err() != nil || n > uint64(8*r.Remaining()/(P+1)) { //each element takes at least P+1 bits
+		return nil, &blockchain_errors.ErrMalformedEncoding{}
+	}
+	return &Filter{N: uint32(n), Data: bytes.Clone(data[len(data)-r.Remaining():])}, nil
+}
+
+func (filter *Filter) Hash() []byte {
+	hash := sha256.Sum256(filter.Serialize())
+	return hash[:]
+}
+
+// header committing to the filter and, through the previous header, to the filters of all previous blocks.
+// the genesis block's previous header is all zeros
+func FilterHeader(filterHash, prevFilterHeader []byte) []byte {
+	hash := sha256.Sum256(append(append([]byte{}, filterHash...), prevFilterHeader...))
+	return hash[:]
+}
+
+var GenesisPrevFilterHeader = make([]byte, sha256.Size)
@@ -0,0 +1,43 @@
+package block_filter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	key := FilterKey([]byte("block hash used as the filter key"))
+	var elements, absent [][]byte
+	for i := 0; i < 200; i++ {
+		elements = append(elements, []byte(fmt.Sprintf("element %d", i)))
+		absent = append(absent, []byte(fmt.Sprintf("absent %d", i)))
+	}
+	//duplicates are only stored once
+	filter := NewFilter(key, append(elements, elements[0]))
+	if filter.N != uint32(len(elements)) {
+		t.Fatalf("Filter has %d elements instead of %d", filter.N, len(elements))
+	}
+
+	decoded, err := DecodeFilter(filter.Serialize())
+	if err != nil {
+		t.Fatalf("Error decoding filter: %s", err)
+	}
+	for _, element := range elements {
+		if match, err := decoded.Match(key, element); err != nil || !match {
+			t.Fatalf("Filter doesn't match its element %s: %v", element, err)
+		}
+	}
+	if match, _ := decoded.MatchAny(key, absent); match {
+		t.Fatalf("Filter matched elements which aren't in it")
+	}
+	if match, _ := decoded.Match(FilterKey([]byte("another block's hash")), elements[0]); match {
+		t.Fatalf("Filter matched an element under another key")
+	}
+
+	if empty := NewFilter(key, nil); empty.N != 0 || len(empty.Data) != 0 {
+		t.Fatalf("Empty filter isn't empty")
+	}
+	if _, err := DecodeFilter(append(filter.Serialize()[:1], 0xff)); err == nil {
+		t.Fatalf("Filter with more elements than its data can hold was decoded")
+	}
+}
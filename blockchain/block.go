@@ -3,22 +3,26 @@ package blockchain
 import (
 	"bytes"
 	"crypto/sha256"
-	"encoding/gob"
 	"fmt"
 	"log"
 	"math"
 	"math/big"
 	"time"
 
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
 	"github.com/pedrogomes29/blockchain_node/memory_pool"
 	"github.com/pedrogomes29/blockchain_node/merkle_tree"
+	"github.com/pedrogomes29/blockchain_node/serialization"
 	"github.com/pedrogomes29/blockchain_node/transactions"
-	"github.com/pedrogomes29/blockchain_node/utils"
 )
 
 type BlockHeader struct {
+	Version             int32 //set to the highest version active at the block's height
 	PrevBlockHeaderHash []byte
-	MerkleRootHash      []byte
+	MerkleRootHash      []byte //root of the tree of txids
+	WitnessRootHash     []byte //root of the tree of witness hashes, committing to the input scripts
+	Timestamp           int64  //unix time at which the block was created
 	Nonce               uint32
 	Height              int
 }
@@ -26,11 +30,37 @@ type BlockHeader struct {
 type Block struct {
 	Header       BlockHeader
 	Transactions []*transactions.Transaction
+	template     *blockTemplate
+}
+
+// state kept while the miner fills a block, so that adding a transaction doesn't reprocess the previous ones
+type blockTemplate struct {
+	size          int //serialized size of the block
+	txHashes      *merkle_tree.MerkleBuilder
+	witnessHashes *merkle_tree.MerkleBuilder
+}
+
+// the block's template, rebuilt if transactions were added without AddTransaction
+func (b *Block) templateState() *blockTemplate {
+	if b.template != nil && b.template.txHashes.NumLeaves() == len(b.Transactions) {
+		return b.template
+	}
+	b.template = &blockTemplate{
+		size:          len(b.Serialize()),
+		txHashes:      merkle_tree.NewMerkleBuilder(),
+		witnessHashes: merkle_tree.NewMerkleBuilder(),
+	}
+	for _, tx := range b.Transactions {
+		b.template.txHashes.Append(tx.Hash())
+		b.template.witnessHashes.Append(tx.WitnessHash())
+	}
+	return b.template
 }
 
 const MaxNonce = math.MaxUint32
 const targetBits = 14            //how many bits must be 0 in the header hash
-const maxBlockSize = 1024 * 1024 //1 MB
+const MaxBlockSize = 1024 * 1024 //1 MB
+const MaxBlockTxs = 10000
 
 var Target *big.Int
 
@@ -39,9 +69,12 @@ func init() {
 	Target.Lsh(Target, uint(256-targetBits))
 }
 
-func NewBlock(transactions []*transactions.Transaction, prevBlockHash []byte, height int) *Block {
+// a block with the highest version active at its height on the network with the given params
+func NewBlock(transactions []*transactions.Transaction, prevBlockHash []byte, height int, params *chain_params.Params) *Block {
 	blockHeader := BlockHeader{
+		Version:             params.MaxBlockVersion(height),
 		PrevBlockHeaderHash: prevBlockHash,
+		Timestamp:           time.Now().Unix(),
 		Height:              height,
 	}
 
@@ -51,29 +84,61 @@ func NewBlock(transactions []*transactions.Transaction, prevBlockHash []byte, he
 	}
 
 	block.Header.MerkleRootHash = block.MerkleRootHash()
+	block.Header.WitnessRootHash = block.WitnessRootHash()
 
 	return block
 }
 
-func NewGenesisBlock(miningChan chan struct{}, coinbase *transactions.Transaction) *Block {
-	genesisblock := NewBlock([]*transactions.Transaction{coinbase}, []byte{}, 0)
+func NewGenesisBlock(miningChan chan struct{}, coinbase *transactions.Transaction, params *chain_params.Params) *Block {
+	genesisblock := NewBlock([]*transactions.Transaction{coinbase}, []byte{}, 0, params)
 	genesisblock.POW(miningChan)
 	return genesisblock
 }
 
-func (b *Block) GetBlockHeaderHash() []byte {
-	data := bytes.Join(
-		[][]byte{
-			b.Header.PrevBlockHeaderHash,
-			b.Header.MerkleRootHash,
-		},
-		utils.Uint32ToHex(b.Header.Nonce),
-	)
-
-	blockHeaderHashArray := sha256.Sum256(data)
+func (header BlockHeader) write(w *serialization.Writer) {
+	w.WriteInt32(header.Version)
+	w.WriteVarBytes(header.PrevBlockHeaderHash)
+	w.WriteVarBytes(header.MerkleRootHash)
+	w.WriteVarBytes(header.WitnessRootHash)
+	w.WriteInt64(header.Timestamp)
+	w.WriteUint32(header.Nonce)
+	w.WriteUint32(uint32(header.Height))
+}
+
+func readBlockHeader(r *serialization.Reader) BlockHeader {
+	return BlockHeader{
+		Version:             r.ReadInt32(),
+		PrevBlockHeaderHash: r.ReadVarBytes(),
+		MerkleRootHash:      r.ReadVarBytes(),
+		WitnessRootHash:     r.ReadVarBytes(),
+		Timestamp:           r.ReadInt64(),
+		Nonce:               r.ReadUint32(),
+		Height:              int(r.ReadUint32()),
+	}
+}
+
+// encodes the header as described in the serialization package, which is also how every encoded block starts
+func (header BlockHeader) Serialize() []byte {
+	w := serialization.NewVersionedWriter()
+	header.write(w)
+	return w.Bytes()
+}
+
+func DecodeBlockHeader(data []byte) (BlockHeader, error) {
+	r := serialization.NewVersionedReader(data)
+	header := readBlockHeader(r)
+	return header, r.Finish()
+}
+
+func (header BlockHeader) Hash() []byte {
+	blockHeaderHashArray := sha256.Sum256(header.Serialize())
 	return blockHeaderHashArray[:]
 }
 
+func (b *Block) GetBlockHeaderHash() []byte {
+	return b.Header.Hash()
+}
+
 func (b *Block) POW(miningChan chan struct{}) bool {
 	for possibleNonce := 0; possibleNonce < MaxNonce; possibleNonce++ {
 		select {
@@ -93,9 +158,13 @@ func (b *Block) POW(miningChan chan struct{}) bool {
 }
 
 func (b *Block) ValidateNonce() bool {
+	return b.Header.ValidateNonce()
+}
+
+func (header BlockHeader) ValidateNonce() bool {
 	var hashInt big.Int
 
-	hash := b.GetBlockHeaderHash()
+	hash := header.Hash()
 	hashInt.SetBytes(hash)
 
 	isValid := hashInt.Cmp(Target) == -1
@@ -103,56 +172,162 @@ func (b *Block) ValidateNonce() bool {
 	return isValid
 }
 
+// adds a transaction to a block being mined, in O(log n) time besides encoding and hashing the transaction
 func (b *Block) AddTransaction(transaction *transactions.Transaction) bool {
-	blockWithTxSize := len(transaction.Serialize()) + len(b.Serialize())
-	if blockWithTxSize > maxBlockSize {
+	if len(b.Transactions) >= MaxBlockTxs {
+		return false
+	}
+	template := b.templateState()
+	txBytes := transaction.Serialize()
+	blockWithTxSize := len(txBytes) + template.size
+	if blockWithTxSize > MaxBlockSize {
 		return false
 	}
+
+	headerSize := len(b.Header.Serialize())
+	template.size += serialization.VarIntSize(uint64(len(b.Transactions)+1)) - serialization.VarIntSize(uint64(len(b.Transactions)))
+	template.size += serialization.VarIntSize(uint64(len(txBytes))) + len(txBytes)
 	b.Transactions = append(b.Transactions, transaction)
-	b.Header.MerkleRootHash = b.MerkleRootHash()
+
+	template.txHashes.Append(transaction.Hash())
+	template.witnessHashes.Append(transaction.WitnessHash())
+	b.Header.MerkleRootHash, _ = template.txHashes.Root()
+	b.Header.WitnessRootHash, _ = template.witnessHashes.Root()
+	template.size += len(b.Header.Serialize()) - headerSize
 	return true
 }
 
-func (b *Block) MerkleRootHash() []byte {
-	var transactions [][]byte
+// checks that the block's version is active at its height, which decides the rules the block is validated with
+func (b *Block) VerifyVersion(params *chain_params.Params) error {
+	return b.Header.VerifyVersion(params)
+}
+
+func (header BlockHeader) VerifyVersion(params *chain_params.Params) error {
+	if header.Version < 1 || header.Version > params.MaxBlockVersion(header.Height) {
+		return &blockchain_errors.ErrUnsupportedBlockVersion{}
+	}
+	return nil
+}
 
+// checks that the timestamp is later than the median time past of the previous blocks and not too far in the future
+func (header BlockHeader) VerifyTimestamp(medianTimePast int64) error {
+	if header.Timestamp <= medianTimePast {
+		return &blockchain_errors.ErrTimestampTooOld{}
+	}
+	if header.Timestamp > time.Now().Add(maxFutureBlockTime).Unix() {
+		return &blockchain_errors.ErrTimestampTooNew{}
+	}
+	return nil
+}
+
+// checks the consensus limits on the number of transactions and on the serialized size of the block and its transactions
+func (b *Block) VerifyLimits() error {
+	if len(b.Transactions) == 0 {
+		return &blockchain_errors.ErrEmptyBlock{}
+	}
+	if len(b.Transactions) > MaxBlockTxs {
+		return &blockchain_errors.ErrTooManyTxs{}
+	}
+	if len(b.Serialize()) > MaxBlockSize {
+		return &blockchain_errors.ErrBlockTooLarge{}
+	}
 	for _, tx := range b.Transactions {
-		transactions = append(transactions, tx.Serialize())
+		if len(tx.Serialize()) > transactions.MaxTxSize {
+			return &blockchain_errors.ErrTxTooLarge{}
+		}
 	}
+	return nil
+}
 
-	mTree := merkle_tree.NewMerkleTree(transactions)
-	return mTree.RootNode.Data
+func (b *Block) TxHashes() [][]byte {
+	txHashes := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		txHashes[i] = tx.Hash()
+	}
+	return txHashes
 }
 
-func (b *Block) Serialize() []byte {
-	var result bytes.Buffer
-	encoder := gob.NewEncoder(&result)
+func (b *Block) WitnessHashes() [][]byte {
+	witnessHashes := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		witnessHashes[i] = tx.WitnessHash()
+	}
+	return witnessHashes
+}
 
-	err := encoder.Encode(b)
-	if err != nil {
-		log.Panic(err)
+func (b *Block) MerkleRootHash() []byte {
+	root, _ := merkle_tree.MerkleRoot(b.TxHashes())
+	return root
+}
+
+func (b *Block) WitnessRootHash() []byte {
+	root, _ := merkle_tree.MerkleRoot(b.WitnessHashes())
+	return root
+}
+
+// checks that the transactions are the ones committed to by the header. a block failing this check doesn't mean
+// that the block with its header's hash is invalid, since anyone relaying it may have changed its transactions
+func (b *Block) VerifyTxCommitments() error {
+	merkleRoot, mutated := merkle_tree.MerkleRoot(b.TxHashes())
+	witnessRoot, witnessMutated := merkle_tree.MerkleRoot(b.WitnessHashes())
+	if mutated || witnessMutated {
+		return &blockchain_errors.ErrMutatedBlock{}
+	}
+	if !bytes.Equal(merkleRoot, b.Header.MerkleRootHash) || !bytes.Equal(witnessRoot, b.Header.WitnessRootHash) {
+		return &blockchain_errors.ErrMutatedBlock{}
 	}
+	return nil
+}
 
-	return result.Bytes()
+func (b *Block) Serialize() []byte {
+	w := serialization.NewVersionedWriter()
+	b.Header.write(w)
+	w.WriteVarInt(uint64(len(b.Transactions)))
+	for _, tx := range b.Transactions {
+		w.WriteVarBytes(tx.Serialize())
+	}
+	return w.Bytes()
 }
 
-func DeserializeBlock(d []byte) *Block {
-	var block Block
+// decodes a block received from a peer, rejecting anything but its canonical encoding
+func DecodeBlock(d []byte) (*Block, error) {
+	r := serialization.NewVersionedReader(d)
+	block := Block{Header: readBlockHeader(r)}
+	block.Transactions = make([]*transactions.Transaction, r.ReadCount(1))
+	for i := range block.Transactions {
+		txBytes := r.ReadVarBytes()
+		if r.Err() != nil {
+			return nil, r.Err()
+		}
+		tx, err := transactions.DecodeTransaction(txBytes)
+		if err != nil {
+			return nil, err
+		}
+		block.Transactions[i] = tx
+	}
+	if err := r.Finish(); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
 
-	decoder := gob.NewDecoder(bytes.NewReader(d))
-	err := decoder.Decode(&block)
+// decodes a block read from storage, which was encoded by this node
+func DeserializeBlock(d []byte) *Block {
+	block, err := DecodeBlock(d)
 	if err != nil {
 		log.Panic(err)
 	}
-
-	return &block
+	return block
 }
 
-func (b *Block) FillWithTxs(mp *memory_pool.MemoryPool) {
+func (b *Block) FillWithTxs(mp *memory_pool.MemoryPool, lockTimeCutoff int64) {
 	mp.GetRWMutex().RLock()
 	defer mp.GetRWMutex().RUnlock()
 	for txQueueElement := mp.GetTxQueue().Front(); txQueueElement != nil; txQueueElement = txQueueElement.Next() {
 		tx := txQueueElement.Value.(*transactions.Transaction)
+		if !tx.IsFinal(b.Header.Height, lockTimeCutoff) {
+			continue
+		}
 		txFitsInBlock := b.AddTransaction(tx)
 		if !txFitsInBlock {
 			break
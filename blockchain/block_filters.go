@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/pedrogomes29/blockchain_node/block_filter"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const FILTER_PREFIX string = "cfilter:"
+const FILTER_HEADER_PREFIX string = "cfheader:"
+
+// marks that every block has a filter, so that they're built for chains stored before filters existed
+const FILTERS_BUILT_KEY string = "cfiltersbuilt"
+
+// most filter headers returned at once
+const MaxFilterHeaders = 2000
+
+func filterKey(blockHash []byte) []byte {
+	return append([]byte(FILTER_PREFIX), blockHash...)
+}
+
+func filterHeaderKey(blockHash []byte) []byte {
+	return append([]byte(FILTER_HEADER_PREFIX), blockHash...)
+}
+
+func NewBlockFilter(block *Block, blockHash []byte) *block_filter.Filter {
+	return block_filter.NewFilter(block_filter.FilterKey(blockHash), block_filter.BlockElements(block.Transactions))
+}
+
+// stores the block's filter and its filter header, which chains to the filter header of the previous block
+func (bc *Blockchain) storeBlockFilter(block *Block, blockHash []byte) error {
+	prevFilterHeader := block_filter.GenesisPrevFilterHeader
+	if len(block.Header.PrevBlockHeaderHash) > 0 {
+		var err error
+		prevFilterHeader, err = bc.GetFilterHeader(block.Header.PrevBlockHeaderHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	filter := NewBlockFilter(block, blockHash)
+	batch := new(leveldb.Batch)
+	batch.Put(filterKey(blockHash), filter.Serialize())
+	batch.Put(filterHeaderKey(blockHash), block_filter.FilterHeader(filter.Hash(), prevFilterHeader))
+	return bc.BlocksDB.Write(batch, nil)
+}
+
+func (bc *Blockchain) deleteBlockFilter(blockHash []byte) error {
+	batch := new(leveldb.Batch)
+	batch.Delete(filterKey(blockHash))
+	batch.Delete(filterHeaderKey(blockHash))
+	return bc.BlocksDB.Write(batch, nil)
+}
+
+func (bc *Blockchain) GetBlockFilter(blockHash []byte) (*block_filter.Filter, error) {
+	filterBytes, err := bc.BlocksDB.Get(filterKey(blockHash), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, &blockchain_errors.ErrBlockNotFound{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return block_filter.DecodeFilter(filterBytes)
+}
+
+func (bc *Blockchain) GetFilterHeader(blockHash []byte) ([]byte, error) {
+	filterHeader, err := bc.BlocksDB.Get(filterHeaderKey(blockHash), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, &blockchain_errors.ErrBlockNotFound{}
+	}
+	return filterHeader, err
+}
+
+// filter headers of the blocks from startHeight up to (and including) the block with hash stopHash, in increasing
+// height order. the blocks are found by height and only their headers are decoded
+func (bc *Blockchain) FilterHeaders(startHeight int, stopHash []byte) ([][]byte, error) {
+	stopHeader, err := bc.GetHeader(stopHash)
+	if err != nil {
+		return nil, err
+	}
+	if startHeight < 0 || startHeight > stopHeader.Height || stopHeader.Height-startHeight >= MaxFilterHeaders {
+		return nil, &blockchain_errors.ErrInvalidFilterRange{}
+	}
+
+	filterHeaders := make([][]byte, stopHeader.Height-startHeight+1)
+	for i := range filterHeaders {
+		blockHash, err := bc.HashAtHeight(startHeight + i)
+		if err != nil {
+			return nil, err
+		}
+		filterHeaders[i], err = bc.GetFilterHeader(blockHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filterHeaders, nil
+}
+
+// builds the filters of every block if some are missing
+func (bc *Blockchain) syncBlockFilters() error {
+	_, err := bc.BlocksDB.Get([]byte(FILTERS_BUILT_KEY), nil)
+	if err == nil {
+		return nil
+	}
+	if err != leveldb.ErrNotFound {
+		return err
+	}
+
+	fmt.Println("Building block filters...")
+
+	for _, prefix := range []string{FILTER_PREFIX, FILTER_HEADER_PREFIX} {
+		iter := bc.BlocksDB.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+		for iter.Next() {
+			if err := bc.BlocksDB.Delete(iter.Key(), nil); err != nil {
+				iter.Release()
+				return err
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+
+	for _, block := range bc.GetBlocksStartingAtHash([]byte{}) {
+		if err := bc.storeBlockFilter(block, block.GetBlockHeaderHash()); err != nil {
+			return err
+		}
+	}
+
+	return bc.BlocksDB.Put([]byte(FILTERS_BUILT_KEY), []byte{}, nil)
+}
@@ -0,0 +1,92 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// the roots and size kept while adding transactions match the ones computed from scratch
+func TestAddTransaction(t *testing.T) {
+	coinbase := transactions.NewCoinbaseTX(testAddress, 1)
+	block := NewBlock([]*transactions.Transaction{coinbase}, []byte{}, 1, &chain_params.MainNetParams)
+
+	prevTx := coinbase
+	for i := 0; i < 300; i++ {
+		tx := spendTx(prevTx.Hash())
+		if !block.AddTransaction(tx) {
+			t.Fatalf("Transaction %d wasn't added", i)
+		}
+		prevTx = tx
+
+		if !bytes.Equal(block.Header.MerkleRootHash, block.MerkleRootHash()) ||
+			!bytes.Equal(block.Header.WitnessRootHash, block.WitnessRootHash()) {
+			t.Fatalf("Roots after adding %d transactions don't match the transactions", i+1)
+		}
+		if block.template.size != len(block.Serialize()) {
+			t.Fatalf("Size after adding %d transactions is %d instead of %d", i+1, block.template.size, len(block.Serialize()))
+		}
+	}
+	if err := block.VerifyTxCommitments(); err != nil {
+		t.Fatalf("Block's transactions don't match its header: %s", err)
+	}
+}
+
+// resizes the input script of the transaction until the encoding measured by encodedSize has exactly the given size
+func padScriptSig(t *testing.T, tx *transactions.Transaction, encodedSize func() int, size int) {
+	for currentSize := encodedSize(); currentSize != size; currentSize = encodedSize() {
+		scriptSigSize := len(tx.Vin[0].ScriptSig) + size - currentSize
+		if scriptSigSize < 0 {
+			t.Fatalf("Encoding is larger than %d bytes without padding", size)
+		}
+		tx.Vin[0].ScriptSig = make([]byte, scriptSigSize)
+	}
+}
+
+// the limits accept blocks and transactions of exactly the maximum size and count, and reject one byte or one
+// transaction more
+func TestVerifyLimits(t *testing.T) {
+	var txs []*transactions.Transaction
+	for len(txs) < 11 {
+		tx := transactions.NewCoinbaseTX(testAddress, 1)
+		tx.Vin[0].ScriptSig = make([]byte, 95000)
+		txs = append(txs, tx)
+	}
+	block := NewBlock(txs, []byte{}, 1, &chain_params.MainNetParams)
+
+	blockSize := func() int { return len(block.Serialize()) }
+	padScriptSig(t, block.Transactions[0], blockSize, MaxBlockSize)
+	if err := block.VerifyLimits(); err != nil {
+		t.Fatalf("Block of the maximum size was rejected: %s", err)
+	}
+	padScriptSig(t, block.Transactions[0], blockSize, MaxBlockSize+1)
+	if err := block.VerifyLimits(); !errors.Is(err, &blockchain_errors.ErrBlockTooLarge{}) {
+		t.Fatalf("Block one byte over the maximum size was accepted: %v", err)
+	}
+
+	txBlock := NewBlock([]*transactions.Transaction{transactions.NewCoinbaseTX(testAddress, 1)}, []byte{}, 1, &chain_params.MainNetParams)
+	tx := txBlock.Transactions[0]
+	for _, size := range []int{transactions.MaxTxSize, transactions.MaxTxSize + 1} {
+		padScriptSig(t, tx, func() int { return len(tx.Serialize()) }, size)
+		if err := txBlock.VerifyLimits(); (size > transactions.MaxTxSize) != errors.Is(err, &blockchain_errors.ErrTxTooLarge{}) {
+			t.Fatalf("Transaction of %d bytes got %v", size, err)
+		}
+	}
+
+	countBlock := NewBlock(nil, []byte{}, 1, &chain_params.MainNetParams)
+	for len(countBlock.Transactions) < MaxBlockTxs {
+		countBlock.Transactions = append(countBlock.Transactions, tx)
+	}
+	tx.Vin[0].ScriptSig = nil
+	if err := countBlock.VerifyLimits(); err != nil {
+		t.Fatalf("Block with the maximum number of transactions was rejected: %s", err)
+	}
+	countBlock.Transactions = append(countBlock.Transactions, tx)
+	if err := countBlock.VerifyLimits(); !errors.Is(err, &blockchain_errors.ErrTooManyTxs{}) {
+		t.Fatalf("Block with one transaction over the maximum was accepted: %v", err)
+	}
+}
@@ -2,18 +2,37 @@ package blockchain
 
 import (
 	"bytes"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"path/filepath"
 	"slices"
+	"sort"
+	"time"
 
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
 	"github.com/pedrogomes29/blockchain_node/memory_pool"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+	"github.com/pedrogomes29/blockchain_node/transactions"
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
 )
 
+// number of previous blocks whose median timestamp a new block's timestamp must exceed
+const MedianTimeSpan = 11
+
+// how far into the future (according to the local clock) a block's timestamp can be
+const maxFutureBlockTime = 2 * time.Hour
+
+// name of the network the blocks db was created on
+const NETWORK_KEY string = "network"
+
 type Blockchain struct {
 	BlocksDB     *leveldb.DB
 	ChainstateDB *leveldb.DB
+	TxIndex      bool                 //whether transactions are indexed by hash to the block containing them
+	Params       *chain_params.Params //consensus parameters of the network the blockchain belongs to
 }
 
 func (bc *Blockchain) VerifyBlock(block *Block) error {
@@ -23,12 +42,23 @@ func (bc *Blockchain) VerifyBlock(block *Block) error {
 	if !bytes.Equal(block.Header.PrevBlockHeaderHash, bc.LastBlockHash()) {
 		return errors.New("received block isn't sucessor of blockchain's last block")
 	}
-	if !bytes.Equal(block.MerkleRootHash(), block.Header.MerkleRootHash) {
-		return errors.New("merkle root doesn't match with transactions")
+	if err := block.VerifyVersion(bc.Params); err != nil {
+		return err
+	}
+	//checked before anything else about the transactions, so that a block whose transactions were tampered with is
+	//told apart from a block which is invalid itself
+	if err := block.VerifyTxCommitments(); err != nil {
+		return err
+	}
+	if err := block.VerifyLimits(); err != nil {
+		return err
 	}
 	if !block.ValidateNonce() {
 		return errors.New("nonce isn't valid")
 	}
+	if err := block.Header.VerifyTimestamp(bc.MedianTimePast(block.Header.PrevBlockHeaderHash)); err != nil {
+		return err
+	}
 	if err := bc.VerifyBlockTxs(block); err != nil {
 		return err
 	}
@@ -38,14 +68,48 @@ func (bc *Blockchain) VerifyBlock(block *Block) error {
 
 func (bc *Blockchain) VerifyBlockTxs(block *Block) error {
 	memoryPool := memory_pool.NewMemoryPool()
+	blockTxHashes := make(map[string]bool)
+	var blockFees transactions.Amount
+
+	if len(block.Transactions) == 0 || !block.Transactions[0].IsCoinbase {
+		return &blockchain_errors.ErrMissingCoinbase{}
+	}
+
+	lockTimeCutoff := bc.MedianTimePast(block.Header.PrevBlockHeaderHash)
+	var scriptChecks []transactions.ScriptCheck
+
+	for txIdx, tx := range block.Transactions {
+		if txIdx > 0 && tx.IsCoinbase {
+			return &blockchain_errors.ErrUnexpectedCoinbase{}
+		}
+
+		if !tx.IsFinal(block.Header.Height, lockTimeCutoff) {
+			return &blockchain_errors.ErrNonFinalTx{}
+		}
+
+		//returns an error if this tx is repeated in the new block or if it would overwrite unspent outputs of a
+		//transaction with the same hash
+		txHashString := hex.EncodeToString(tx.Hash())
+		if blockTxHashes[txHashString] {
+			return &blockchain_errors.ErrDuplicateTx{}
+		}
+		blockTxHashes[txHashString] = true
+
+		hasUnspentOutputs, err := transactions.HasUnspentOutputs(bc.ChainstateDB, tx.Hash())
+		if err != nil {
+			return err
+		}
+		if hasUnspentOutputs {
+			return &blockchain_errors.ErrDuplicateTx{}
+		}
 
-	for _, tx := range block.Transactions {
-		//returns an error if signature is invalid or the UTXOs are invalid according to the blockchain state
-		//(excluding other transactions in the new block)
-		err := tx.Verify(bc.ChainstateDB)
+		//returns an error if the UTXOs are invalid according to the blockchain state (excluding other transactions in
+		//the new block). the input scripts are executed afterwards, together with those of the other transactions
+		txScriptChecks, err := tx.VerifyWithoutScripts(bc.ChainstateDB, block.Header.Height, bc.Params)
 		if err != nil {
 			return err
 		}
+		scriptChecks = append(scriptChecks, txScriptChecks...)
 
 		//returns an error if this tx's UTXOs are already spent by some other tx in the new block
 		err = memoryPool.PushBackTxWithLock(tx)
@@ -53,6 +117,26 @@ func (bc *Blockchain) VerifyBlockTxs(block *Block) error {
 			return err
 		}
 
+		fee, err := tx.Fee(bc.ChainstateDB)
+		if err != nil {
+			return err
+		}
+		blockFees += fee
+		if !transactions.MoneyRange(blockFees) {
+			return &blockchain_errors.ErrAmountOutOfRange{}
+		}
+	}
+
+	if err := VerifyScripts(scriptChecks, ScriptVerificationWorkers); err != nil {
+		return err
+	}
+
+	coinbaseValue, err := block.Transactions[0].OutputsTotal()
+	if err != nil {
+		return err
+	}
+	if coinbaseValue > transactions.BlockSubsidy(block.Header.Height)+blockFees {
+		return &blockchain_errors.ErrCoinbaseValueTooLarge{}
 	}
 	return nil
 }
@@ -63,7 +147,8 @@ func (bc *Blockchain) AddBlock(newBlock *Block) error {
 
 	err := bc.VerifyBlock(newBlock)
 	if err != nil {
-		return err
+		//told apart from storage errors, which say nothing about the block
+		return fmt.Errorf("%w: %w", &blockchain_errors.ErrBlockFailedValidation{}, err)
 	}
 
 	err = bc.BlocksDB.Put(blockHash, newBlock.Serialize(), nil)
@@ -76,8 +161,26 @@ func (bc *Blockchain) AddBlock(newBlock *Block) error {
 		return err
 	}
 
+	err = bc.BlocksDB.Put(heightIndexKey(newBlock.Header.Height), blockHash, nil)
+	if err != nil {
+		return err
+	}
+
+	if bc.TxIndex {
+		err = bc.indexBlockTxs(newBlock, blockHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = bc.storeBlockFilter(newBlock, blockHash)
+	if err != nil {
+		return err
+	}
+
+	//the block's transactions were verified with VerifyBlock, so their scripts aren't executed again
 	for _, tx := range newBlock.Transactions {
-		err = tx.IndexUTXOs(bc.ChainstateDB)
+		err = tx.ConnectUTXOs(bc.ChainstateDB, newBlock.Header.Height)
 		if err != nil {
 			return err
 		}
@@ -108,8 +211,26 @@ func (bc *Blockchain) RemoveBlock(blockHash []byte) error {
 		return err
 	}
 
-	for _, tx := range block.Transactions {
-		err := tx.RevertUTXOIndex(bc.ChainstateDB)
+	err = bc.BlocksDB.Delete(heightIndexKey(block.Header.Height), nil)
+	if err != nil {
+		return err
+	}
+
+	if bc.TxIndex {
+		err = bc.deindexBlockTxs(block, blockHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = bc.deleteBlockFilter(blockHash)
+	if err != nil {
+		return err
+	}
+
+	//reverted from last to first, so that outputs spent later in the block are restored before their transaction is
+	for i := len(block.Transactions) - 1; i >= 0; i-- {
+		err := block.Transactions[i].RevertUTXOIndex(bc.ChainstateDB)
 		if err != nil {
 			return err
 		}
@@ -117,13 +238,18 @@ func (bc *Blockchain) RemoveBlock(blockHash []byte) error {
 	return nil
 }
 
-func NewBlockchain(miningChan chan struct{}, genesisAddress string) *Blockchain {
-	blocksDB, err := leveldb.OpenFile("blocks", nil)
+func NewBlockchain(miningChan chan struct{}, genesisAddress string, txIndex bool, params *chain_params.Params) *Blockchain {
+	return OpenBlockchain(".", txIndex, params)
+}
+
+// opens (or creates) the blockchain of the network with the given params whose databases are stored in dataDir
+func OpenBlockchain(dataDir string, txIndex bool, params *chain_params.Params) *Blockchain {
+	blocksDB, err := leveldb.OpenFile(filepath.Join(dataDir, "blocks"), nil)
 	if err != nil {
 		log.Panic(err)
 	}
 
-	chainstateDB, err := leveldb.OpenFile("chainstate", nil)
+	chainstateDB, err := leveldb.OpenFile(filepath.Join(dataDir, "chainstate"), nil)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -137,13 +263,66 @@ func NewBlockchain(miningChan chan struct{}, genesisAddress string) *Blockchain
 		if err != nil {
 			log.Panic(err)
 		}
+		err = blocksDB.Put([]byte(SERIALIZATION_VERSION_KEY), []byte{serialization.EncodingVersion}, nil)
+		if err != nil {
+			log.Panic(err)
+		}
 	} else if err != nil {
 		log.Panic(err)
 	} else {
 		fmt.Println("Blockchain found. Retrieving...")
 	}
 
-	return &Blockchain{blocksDB, chainstateDB}
+	bc := &Blockchain{blocksDB, chainstateDB, txIndex, params}
+
+	err = VerifyNetwork(blocksDB, params)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = bc.migrateSerialization()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	//stored once the blocks are valid under the network's params. data stored before the network was is assumed to
+	//belong to the network it's opened with
+	err = bc.BlocksDB.Put([]byte(NETWORK_KEY), []byte(params.Name), nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = bc.syncTxIndex()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = bc.syncHeightIndex()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	err = bc.syncBlockFilters()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return bc
+}
+
+// refuses to open data created on another network, whose blocks are only valid under that network's params
+func VerifyNetwork(db *leveldb.DB, params *chain_params.Params) error {
+	network, err := db.Get([]byte(NETWORK_KEY), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if string(network) != params.Name {
+		return fmt.Errorf("%w: it was created on %s, run the node with -network %s", &blockchain_errors.ErrNetworkMismatch{}, network, network)
+	}
+	return nil
 }
 
 // gets blocks from older to more recent starting from (but excluding) the argument received in the argument
@@ -217,3 +396,28 @@ func (bc *Blockchain) LastBlockHash() []byte {
 	}
 	return lastBlockHash
 }
+
+// median timestamp of the last MedianTimeSpan blocks ending at (and including) the given block
+func (bc *Blockchain) MedianTimePast(blockHash []byte) int64 {
+	var timestamps []int64
+	for i := 0; i < MedianTimeSpan && len(blockHash) > 0; i++ {
+		block := bc.GetBlock(blockHash)
+		if block == nil {
+			break
+		}
+		timestamps = append(timestamps, block.Header.Timestamp)
+		blockHash = block.Header.PrevBlockHeaderHash
+	}
+
+	return MedianTimestamp(timestamps)
+}
+
+func MedianTimestamp(timestamps []int64) int64 {
+	if len(timestamps) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(timestamps)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
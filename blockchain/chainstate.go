@@ -12,7 +12,7 @@ func (bc *Blockchain) ReindexUTXOs() {
 
 	for _, block := range blocks {
 		for _, tx := range block.Transactions {
-			tx.IndexUTXOs(bc.ChainstateDB)
+			tx.IndexUTXOs(bc.ChainstateDB, block.Header.Height, bc.Params)
 		}
 	}
 }
@@ -34,9 +34,9 @@ func (bc *Blockchain) FindUTXOs(pubKeyHash []byte) ([]transactions.TXOutput, err
 	return UTXOs, err
 }
 
-func (bc *Blockchain) FindSpendableUTXOs(pubKeyHash []byte, amount int) (int, map[string][]int, error) {
+func (bc *Blockchain) FindSpendableUTXOs(pubKeyHash []byte, amount transactions.Amount) (transactions.Amount, map[string][]int, error) {
 	UTXOs := make(map[string][]int)
-	utxoTotalAmount := 0
+	var utxoTotalAmount transactions.Amount
 	txUTXOsIter := bc.ChainstateDB.NewIterator(util.BytesPrefix([]byte(transactions.UTXO_PREFIX)), nil)
 	for txUTXOsIter.Next() {
 		txHash := txUTXOsIter.Key()[len(transactions.UTXO_PREFIX):]
@@ -0,0 +1,203 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+// short ids are the first 6 bytes of a salted SipHash of the witness hash, so that a tx is announced in 6 bytes
+const ShortIDSize = 6
+
+// a block announced by its header and the short ids of its transactions, which peers mostly already have in their
+// memory pools. transactions peers can't have, like the coinbase, are sent whole
+type CompactBlock struct {
+	Header       BlockHeader
+	Nonce        uint64 //salts the short ids, so that colliding transactions can't be made for every block
+	ShortIDs     []uint64
+	PrefilledTxs []PrefilledTx
+}
+
+type PrefilledTx struct {
+	Index int //position of the transaction in the block
+	Tx    *transactions.Transaction
+}
+
+func NewCompactBlock(block *Block) *CompactBlock {
+	var nonce [8]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		log.Panic(err)
+	}
+
+	compactBlock := &CompactBlock{
+		Header: block.Header,
+		Nonce:  binary.LittleEndian.Uint64(nonce[:]),
+	}
+	key := compactBlock.shortIDKey()
+	for i, tx := range block.Transactions {
+		if tx.IsCoinbase {
+			compactBlock.PrefilledTxs = append(compactBlock.PrefilledTxs, PrefilledTx{Index: i, Tx: tx})
+			continue
+		}
+		compactBlock.ShortIDs = append(compactBlock.ShortIDs, shortID(key, tx.WitnessHash()))
+	}
+	return compactBlock
+}
+
+// the SipHash key is taken from the hash of the header and nonce, so it differs for every announcement
+func (cb *CompactBlock) shortIDKey() [16]byte {
+	keyHash := sha256.Sum256(binary.LittleEndian.AppendUint64(cb.Header.Serialize(), cb.Nonce))
+	var key [16]byte
+	copy(key[:], keyHash[:])
+	return key
+}
+
+func shortID(key [16]byte, witnessHash []byte) uint64 {
+	return utils.SipHash(key, witnessHash) & (1<<(8*ShortIDSize) - 1)
+}
+
+func (cb *CompactBlock) NumTxs() int {
+	return len(cb.ShortIDs) + len(cb.PrefilledTxs)
+}
+
+func (cb *CompactBlock) Serialize() []byte {
+	w := serialization.NewVersionedWriter()
+	cb.Header.write(w)
+	w.WriteUint64(cb.Nonce)
+	w.WriteVarInt(uint64(len(cb.ShortIDs)))
+	for _, id := range cb.ShortIDs {
+		for i := 0; i < ShortIDSize; i++ {
+			w.WriteUint8(uint8(id >> (8 * i)))
+		}
+	}
+	w.WriteVarInt(uint64(len(cb.PrefilledTxs)))
+	for _, prefilledTx := range cb.PrefilledTxs {
+		w.WriteVarInt(uint64(prefilledTx.Index))
+		w.WriteVarBytes(prefilledTx.Tx.Serialize())
+	}
+	return w.Bytes()
+}
+
+// decodes a compact block received from a peer, rejecting prefilled transactions outside the block or out of order
+func DecodeCompactBlock(data []byte) (*CompactBlock, error) {
+	r := serialization.NewVersionedReader(data)
+	compactBlock := CompactBlock{
+		Header: readBlockHeader(r),
+		Nonce:  r.ReadUint64(),
+	}
+	compactBlock.ShortIDs = make([]uint64, r.ReadCount(ShortIDSize))
+	for i := range compactBlock.ShortIDs {
+		for j := 0; j < ShortIDSize; j++ {
+			compactBlock.ShortIDs[i] |= uint64(r.ReadUint8()) << (8 * j)
+		}
+	}
+
+	compactBlock.PrefilledTxs = make([]PrefilledTx, r.ReadCount(2))
+	for i := range compactBlock.PrefilledTxs {
+		index := int(r.ReadVarInt())
+		txBytes := r.ReadVarBytes()
+		if r.Err() != nil {
+			return nil, r.Err()
+		}
+		if (i > 0 && index <= compactBlock.PrefilledTxs[i-1].Index) || index >= compactBlock.NumTxs() {
+			return nil, &blockchain_errors.ErrInvalidCompactBlock{}
+		}
+		tx, err := transactions.DecodeTransaction(txBytes)
+		if err != nil {
+			return nil, err
+		}
+		compactBlock.PrefilledTxs[i] = PrefilledTx{Index: index, Tx: tx}
+	}
+	if err := r.Finish(); err != nil {
+		return nil, err
+	}
+	if compactBlock.NumTxs() > MaxBlockTxs {
+		return nil, &blockchain_errors.ErrTooManyTxs{}
+	}
+	return &compactBlock, nil
+}
+
+// a compact block's transactions, as far as they could be found
+type PartialBlock struct {
+	Header       BlockHeader
+	Transactions []*transactions.Transaction //nil where the transaction is missing
+}
+
+// places the prefilled transactions and those of the candidates whose short ids match. short ids matched by more
+// than one candidate are left missing, since which transaction is in the block can't be known
+func (cb *CompactBlock) Reconstruct(candidates []*transactions.Transaction) *PartialBlock {
+	partialBlock := &PartialBlock{
+		Header:       cb.Header,
+		Transactions: make([]*transactions.Transaction, cb.NumTxs()),
+	}
+
+	key := cb.shortIDKey()
+	candidatesByID := make(map[uint64]*transactions.Transaction)
+	collisions := make(map[uint64]bool)
+	for _, tx := range candidates {
+		witnessHash := tx.WitnessHash()
+		txShortID := shortID(key, witnessHash)
+		if other, ok := candidatesByID[txShortID]; ok && !bytes.Equal(other.WitnessHash(), witnessHash) {
+			collisions[txShortID] = true
+		}
+		candidatesByID[txShortID] = tx
+	}
+
+	prefilledIdx, shortIDIdx := 0, 0
+	for i := range partialBlock.Transactions {
+		if prefilledIdx < len(cb.PrefilledTxs) && cb.PrefilledTxs[prefilledIdx].Index == i {
+			partialBlock.Transactions[i] = cb.PrefilledTxs[prefilledIdx].Tx
+			prefilledIdx++
+			continue
+		}
+		txShortID := cb.ShortIDs[shortIDIdx]
+		shortIDIdx++
+		if !collisions[txShortID] {
+			partialBlock.Transactions[i] = candidatesByID[txShortID]
+		}
+	}
+	return partialBlock
+}
+
+// positions of the transactions which weren't found, in increasing order
+func (pb *PartialBlock) MissingIndexes() []int {
+	var missing []int
+	for i, tx := range pb.Transactions {
+		if tx == nil {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// places the missing transactions, given in the order of MissingIndexes
+func (pb *PartialBlock) FillMissing(txs []*transactions.Transaction) error {
+	missing := pb.MissingIndexes()
+	if len(txs) != len(missing) {
+		return &blockchain_errors.ErrInvalidCompactBlock{}
+	}
+	for i, index := range missing {
+		pb.Transactions[index] = txs[i]
+	}
+	return nil
+}
+
+// the block once every transaction was found. a short id collision or a peer sending the wrong transactions is only
+// caught by checking the block's commitments to them
+func (pb *PartialBlock) Block() (*Block, error) {
+	if len(pb.MissingIndexes()) > 0 {
+		return nil, &blockchain_errors.ErrInvalidCompactBlock{}
+	}
+	block := &Block{Header: pb.Header, Transactions: pb.Transactions}
+	if err := block.VerifyTxCommitments(); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestCompactBlock(t *testing.T) {
+	coinbase := transactions.NewCoinbaseTX(testAddress, 1)
+	txs := []*transactions.Transaction{coinbase}
+	for i := byte(0); i < 3; i++ {
+		txs = append(txs, spendTx(bytes.Repeat([]byte{i}, 32)))
+	}
+	block := NewBlock(txs, bytes.Repeat([]byte{0xab}, 32), 3, &chain_params.MainNetParams)
+
+	compactBlock := NewCompactBlock(block)
+	encoded := compactBlock.Serialize()
+	decoded, err := DecodeCompactBlock(encoded)
+	if err != nil {
+		t.Fatalf("Error decoding compact block: %s", err)
+	}
+	if !reflect.DeepEqual(decoded, compactBlock) {
+		t.Fatalf("Decoded compact block doesn't match the encoded one")
+	}
+	if len(encoded) >= len(block.Serialize()) {
+		t.Fatalf("Compact block takes %d bytes, not less than the block's %d", len(encoded), len(block.Serialize()))
+	}
+
+	//a copy of a transaction with a different input script has the same txid but isn't used for the block
+	malleated := *txs[2]
+	malleated.Vin = []transactions.TXInput{txs[2].Vin[0]}
+	malleated.Vin[0].ScriptSig = []byte{4, 5, 6}
+	unrelated := spendTx(bytes.Repeat([]byte{0xff}, 32))
+
+	partialBlock := decoded.Reconstruct([]*transactions.Transaction{txs[3], unrelated, txs[1], &malleated})
+	if missing := partialBlock.MissingIndexes(); !slices.Equal(missing, []int{2}) {
+		t.Fatalf("Transactions %v are missing, expected only the malleated one", missing)
+	}
+	if _, err := partialBlock.Block(); !errors.Is(err, &blockchain_errors.ErrInvalidCompactBlock{}) {
+		t.Fatalf("Block was built with missing transactions: %v", err)
+	}
+
+	wrongTx := *partialBlock
+	wrongTx.Transactions = slices.Clone(partialBlock.Transactions)
+	wrongTx.FillMissing([]*transactions.Transaction{&malleated})
+	if _, err := wrongTx.Block(); !errors.Is(err, &blockchain_errors.ErrMutatedBlock{}) {
+		t.Fatalf("Block was built with a transaction it doesn't commit to: %v", err)
+	}
+
+	if err := partialBlock.FillMissing([]*transactions.Transaction{txs[2], unrelated}); !errors.Is(err, &blockchain_errors.ErrInvalidCompactBlock{}) {
+		t.Fatalf("More transactions than were missing were accepted: %v", err)
+	}
+	if err := partialBlock.FillMissing([]*transactions.Transaction{txs[2]}); err != nil {
+		t.Fatalf("Error filling missing transactions: %s", err)
+	}
+	reconstructed, err := partialBlock.Block()
+	if err != nil {
+		t.Fatalf("Error reconstructing block: %s", err)
+	}
+	if !bytes.Equal(reconstructed.Serialize(), block.Serialize()) {
+		t.Fatalf("Reconstructed block doesn't match the original block")
+	}
+}
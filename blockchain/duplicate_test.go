@@ -0,0 +1,41 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestDuplicateTxs(t *testing.T) {
+	lowerTestTarget(t)
+	bc := OpenBlockchain(t.TempDir(), false, &chain_params.MainNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	genesis := addTestBlock(t, bc)
+	block := addTestBlock(t, bc)
+
+	//the copies aren't siblings in the merkle tree, so the block isn't reported as mutated. the duplicate is found
+	//before the input scripts are executed
+	tx, other := spendTx(genesis.Transactions[0].Hash()), spendTx(block.Transactions[0].Hash())
+	coinbase := transactions.NewCoinbaseTX(testAddress, bc.Height()+1)
+	repeated := mineTestBlock(t, bc, coinbase, tx, other, tx)
+	if err := bc.VerifyBlock(repeated); !errors.Is(err, &blockchain_errors.ErrDuplicateTx{}) {
+		t.Fatalf("Block with a repeated transaction was accepted: %v", err)
+	}
+
+	//an identical coinbase would overwrite the outputs of the first one, which are still unspent
+	identical := mineTestBlock(t, bc, block.Transactions[0])
+	if err := bc.VerifyBlock(identical); !errors.Is(err, &blockchain_errors.ErrDuplicateTx{}) {
+		t.Fatalf("Block re-creating a transaction with unspent outputs was accepted: %v", err)
+	}
+	if err := bc.AddBlock(identical); !errors.Is(err, &blockchain_errors.ErrDuplicateTx{}) {
+		t.Fatalf("Block re-creating a transaction with unspent outputs was added: %v", err)
+	}
+	if _, err := transactions.GetUTXO(bc.ChainstateDB, block.Transactions[0].Hash(), 0); err != nil {
+		t.Fatalf("Outputs of the first transaction were lost: %s", err)
+	}
+}
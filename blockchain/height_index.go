@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// hashes of the blocks of the chain by height
+const HEIGHT_INDEX_PREFIX string = "height:"
+
+// marks that every block is indexed by height, so that the index is built for chains stored before it existed
+const HEIGHT_INDEX_BUILT_KEY string = "heightindexbuilt"
+
+func heightIndexKey(height int) []byte {
+	return binary.BigEndian.AppendUint32([]byte(HEIGHT_INDEX_PREFIX), uint32(height))
+}
+
+// hash of the block of the chain at the given height
+func (bc *Blockchain) HashAtHeight(height int) ([]byte, error) {
+	blockHash, err := bc.BlocksDB.Get(heightIndexKey(height), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, &blockchain_errors.ErrBlockNotFound{}
+	}
+	return blockHash, err
+}
+
+// decodes only the header of a stored block, which is how its encoding starts
+func (bc *Blockchain) GetHeader(blockHash []byte) (BlockHeader, error) {
+	blockBytes, err := bc.BlocksDB.Get(blockHash, nil)
+	if err == leveldb.ErrNotFound {
+		return BlockHeader{}, &blockchain_errors.ErrBlockNotFound{}
+	}
+	if err != nil {
+		return BlockHeader{}, err
+	}
+	r := serialization.NewVersionedReader(blockBytes)
+	header := readBlockHeader(r)
+	return header, r.Err()
+}
+
+// headers of at most maxHeaders blocks of the chain after the block with the given hash (or from the genesis block if
+// the hash is empty), in increasing height order
+func (bc *Blockchain) HeadersAfter(blockHash []byte, maxHeaders int) ([]BlockHeader, error) {
+	startHeight := 0
+	if len(blockHash) > 0 {
+		header, err := bc.GetHeader(blockHash)
+		if err != nil {
+			return nil, err
+		}
+		startHeight = header.Height + 1
+	}
+
+	var headers []BlockHeader
+	for height := startHeight; height < startHeight+maxHeaders; height++ {
+		headerHash, err := bc.HashAtHeight(height)
+		if errors.Is(err, &blockchain_errors.ErrBlockNotFound{}) { //past the last block
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		header, err := bc.GetHeader(headerHash)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// indexes every block by height if the index isn't complete
+func (bc *Blockchain) syncHeightIndex() error {
+	_, err := bc.BlocksDB.Get([]byte(HEIGHT_INDEX_BUILT_KEY), nil)
+	if err == nil {
+		return nil
+	}
+	if err != leveldb.ErrNotFound {
+		return err
+	}
+
+	fmt.Println("Building height index...")
+
+	iter := bc.BlocksDB.NewIterator(util.BytesPrefix([]byte(HEIGHT_INDEX_PREFIX)), nil)
+	for iter.Next() {
+		if err := bc.BlocksDB.Delete(iter.Key(), nil); err != nil {
+			iter.Release()
+			return err
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	for _, block := range bc.GetBlocksStartingAtHash([]byte{}) {
+		err = bc.BlocksDB.Put(heightIndexKey(block.Header.Height), block.GetBlockHeaderHash(), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bc.BlocksDB.Put([]byte(HEIGHT_INDEX_BUILT_KEY), []byte{}, nil)
+}
@@ -0,0 +1,52 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+)
+
+// headers are read by height, up to the given number of them, and removed blocks are no longer indexed
+func TestHeadersAfter(t *testing.T) {
+	lowerTestTarget(t)
+	bc := OpenBlockchain(t.TempDir(), false, &chain_params.MainNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	var blocks []*Block
+	for i := 0; i < 4; i++ {
+		blocks = append(blocks, addTestBlock(t, bc))
+	}
+
+	headers, err := bc.HeadersAfter(blocks[0].GetBlockHeaderHash(), 2)
+	if err != nil {
+		t.Fatalf("Error reading headers: %s", err)
+	}
+	if len(headers) != 2 || !bytes.Equal(headers[0].Hash(), blocks[1].GetBlockHeaderHash()) ||
+		!bytes.Equal(headers[1].Hash(), blocks[2].GetBlockHeaderHash()) {
+		t.Fatalf("Headers after the first block aren't the next two blocks' headers")
+	}
+
+	if err := bc.RemoveBlock(blocks[3].GetBlockHeaderHash()); err != nil {
+		t.Fatalf("Error removing block: %s", err)
+	}
+	headers, err = bc.HeadersAfter([]byte{}, 10)
+	if err != nil || len(headers) != 3 {
+		t.Fatalf("Removed block is still indexed: %d headers, %v", len(headers), err)
+	}
+
+	//chains stored before the index existed are indexed when opened
+	if err := bc.BlocksDB.Delete([]byte(HEIGHT_INDEX_BUILT_KEY), nil); err != nil {
+		t.Fatalf("Error deleting key: %s", err)
+	}
+	if err := bc.BlocksDB.Delete(heightIndexKey(1), nil); err != nil {
+		t.Fatalf("Error deleting key: %s", err)
+	}
+	if err := bc.syncHeightIndex(); err != nil {
+		t.Fatalf("Error building height index: %s", err)
+	}
+	if blockHash, err := bc.HashAtHeight(1); err != nil || !bytes.Equal(blockHash, blocks[1].GetBlockHeaderHash()) {
+		t.Fatalf("Height index wasn't rebuilt: %v", err)
+	}
+}
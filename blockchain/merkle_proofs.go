@@ -0,0 +1,42 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/merkle_tree"
+)
+
+// proof that the transaction with the given hash is committed to by the block's merkle root
+func (b *Block) MerkleProof(txHash []byte) (*merkle_tree.MerkleProof, error) {
+	blockTxHashes := b.TxHashes()
+	for i, blockTxHash := range blockTxHashes {
+		if bytes.Equal(blockTxHash, txHash) {
+			return merkle_tree.NewMerkleProof(blockTxHashes, i)
+		}
+	}
+	return nil, &blockchain_errors.ErrTxNotInBlock{}
+}
+
+// partial merkle tree proving that all the transactions with the given hashes are in the block
+func (b *Block) PartialMerkleTree(txHashes [][]byte) (*merkle_tree.PartialMerkleTree, error) {
+	wanted := make(map[string]bool)
+	for _, txHash := range txHashes {
+		wanted[hex.EncodeToString(txHash)] = true
+	}
+
+	blockTxHashes := b.TxHashes()
+	matches := make([]bool, len(blockTxHashes))
+	numMatches := 0
+	for i, txHash := range blockTxHashes {
+		if wanted[hex.EncodeToString(txHash)] {
+			matches[i] = true
+			numMatches++
+		}
+	}
+	if numMatches != len(wanted) {
+		return nil, &blockchain_errors.ErrTxNotInBlock{}
+	}
+	return merkle_tree.NewPartialMerkleTree(blockTxHashes, matches), nil
+}
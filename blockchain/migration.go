@@ -0,0 +1,189 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"slices"
+
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	legacy_transactions "github.com/pedrogomes29/blockchain_node/legacy/transactions"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// encoding version of the blocks stored in the blocks db, absent if they were stored with gob
+const SERIALIZATION_VERSION_KEY string = "serializationversion"
+
+// marks that the chainstate must be rebuilt from the blocks, so that a migration interrupted midway is completed
+const REINDEX_CHAINSTATE_KEY string = "reindexchainstate"
+
+// blocks as they were stored with gob, before the binary encoding existed
+type legacyBlock struct {
+	Header       legacyBlockHeader
+	Transactions []*legacy_transactions.Transaction
+}
+
+type legacyBlockHeader struct {
+	PrevBlockHeaderHash []byte
+	MerkleRootHash      []byte
+	Nonce               uint32
+	Height              int
+}
+
+// decodes a block stored with gob
+func decodeLegacyBlock(blockBytes []byte) (*legacyBlock, error) {
+	var block legacyBlock
+	if err := gob.NewDecoder(bytes.NewReader(blockBytes)).Decode(&block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// converts blocks stored with gob (before the binary encoding existed) to the binary encoding.
+// their transactions become legacy transactions, which keep their ids and signatures, and every block is mined again
+// on top of its migrated parent, since header hashes are computed over the encoding. the nonces are searched from 0,
+// so every node migrating the same chain ends up with the same blocks
+func (bc *Blockchain) migrateSerialization() error {
+	version, err := bc.BlocksDB.Get([]byte(SERIALIZATION_VERSION_KEY), nil)
+	if err == nil {
+		if !bytes.Equal(version, []byte{serialization.EncodingVersion}) {
+			return fmt.Errorf("blocks were stored with encoding version %v, which this build can't read", version)
+		}
+		return bc.reindexChainstateIfNeeded()
+	}
+	if err != leveldb.ErrNotFound {
+		return err
+	}
+
+	legacyBlocks, err := bc.legacyBlocks()
+	if err != nil {
+		return err
+	}
+	if len(legacyBlocks) > 0 && !bc.Params.LegacyTxs {
+		return fmt.Errorf("blocks stored with gob can only be migrated on the legacy network, run the node with -network %s", chain_params.LegacyNetParams.Name)
+	}
+
+	batch := new(leveldb.Batch)
+	iter := bc.BlocksDB.NewIterator(nil, nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	if len(legacyBlocks) > 0 {
+		fmt.Printf("Migrating %d blocks to the binary encoding...\n", len(legacyBlocks))
+	}
+	prevBlockHash := []byte{}
+	for _, legacyBlock := range legacyBlocks {
+		height := legacyBlock.Header.Height
+		//legacy headers had no version or timestamp. increasing timestamps satisfy the median time past rule
+		block := &Block{Header: BlockHeader{
+			Version:             1,
+			PrevBlockHeaderHash: prevBlockHash,
+			Timestamp:           int64(height) + 1,
+			Height:              height,
+		}}
+		for _, legacyTx := range legacyBlock.Transactions {
+			tx, err := transactions.FromLegacy(*legacyTx)
+			if err != nil {
+				return fmt.Errorf("transaction of block %d can't be migrated: %w", height, err)
+			}
+			block.Transactions = append(block.Transactions, tx)
+		}
+		block.Header.MerkleRootHash = block.MerkleRootHash()
+		block.Header.WitnessRootHash = block.WitnessRootHash()
+		if !block.remine() {
+			return fmt.Errorf("no valid nonce found for migrated block %d", height)
+		}
+		prevBlockHash = block.GetBlockHeaderHash()
+		batch.Put(prevBlockHash, block.Serialize())
+	}
+	batch.Put([]byte("l"), prevBlockHash)
+	batch.Put([]byte(SERIALIZATION_VERSION_KEY), []byte{serialization.EncodingVersion})
+	batch.Put([]byte(REINDEX_CHAINSTATE_KEY), []byte{})
+
+	//the blocks are replaced at once, so that an interrupted migration leaves either the legacy or the migrated chain
+	if err := bc.BlocksDB.Write(batch, nil); err != nil {
+		return err
+	}
+	return bc.reindexChainstateIfNeeded()
+}
+
+// reads the chain stored with gob, from the genesis block to the last block
+func (bc *Blockchain) legacyBlocks() ([]*legacyBlock, error) {
+	var blocks []*legacyBlock
+	blockHash, err := bc.BlocksDB.Get([]byte("l"), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	for err == nil && len(blockHash) > 0 {
+		var blockBytes []byte
+		blockBytes, err = bc.BlocksDB.Get(blockHash, nil)
+		if err != nil {
+			break
+		}
+		var block *legacyBlock
+		block, err = decodeLegacyBlock(blockBytes)
+		if err != nil {
+			break
+		}
+		blocks = append(blocks, block)
+		blockHash = block.Header.PrevBlockHeaderHash
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(blocks)
+	return blocks, nil
+}
+
+// searches the first valid nonce, without pausing between attempts like POW
+func (b *Block) remine() bool {
+	for nonce := 0; nonce < MaxNonce; nonce++ {
+		b.Header.Nonce = uint32(nonce)
+		if b.ValidateNonce() {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuilds the chainstate from the blocks if a migration changed them.
+// the transactions' scripts aren't executed again, since they were verified when the blocks were added
+func (bc *Blockchain) reindexChainstateIfNeeded() error {
+	_, err := bc.BlocksDB.Get([]byte(REINDEX_CHAINSTATE_KEY), nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	iter := bc.ChainstateDB.NewIterator(nil, nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if err := bc.ChainstateDB.Write(batch, nil); err != nil {
+		return err
+	}
+
+	for _, block := range bc.GetBlocksStartingAtHash([]byte{}) {
+		for _, tx := range block.Transactions {
+			if err := tx.ConnectUTXOs(bc.ChainstateDB, block.Header.Height); err != nil {
+				return err
+			}
+		}
+	}
+	return bc.BlocksDB.Delete([]byte(REINDEX_CHAINSTATE_KEY), nil)
+}
@@ -0,0 +1,133 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/merkle_tree"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// the chain in testdata/legacy_chain was stored by the node before the binary encoding existed: alice mined the
+// genesis block, paid bob 4 coins in block 1 and bob paid them back in block 2
+const legacyAlice = "14AWXqzQjwn8mSnCbdR9U8Tb2tqC2Zt5vx"
+const legacyPaymentTxid = "cd468b4f1a74aead34c9729c260b09a6bdc5a1c00ba91e23f09f344879914a0a"
+const legacyRefundTxid = "5cdd0558824e43f99fc0e896286411dc5325a803b25ec1f3e4501c69702a0410"
+
+// writes the "<hex key> <hex value>" lines of a dump into a new db at the given path
+func loadTestDB(t *testing.T, path string, dumpFile string) *leveldb.DB {
+	dump, err := os.Open(dumpFile)
+	if err != nil {
+		t.Fatalf("Error opening dump: %s", err)
+	}
+	defer dump.Close()
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		t.Fatalf("Error opening db: %s", err)
+	}
+	scanner := bufio.NewScanner(dump)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		keyBytes, _ := hex.DecodeString(key)
+		valueBytes, _ := hex.DecodeString(value)
+		if err := db.Put(keyBytes, valueBytes, nil); err != nil {
+			t.Fatalf("Error loading dump: %s", err)
+		}
+	}
+	return db
+}
+
+func loadLegacyChain(t *testing.T, dataDir string) {
+	loadTestDB(t, filepath.Join(dataDir, "blocks"), "testdata/legacy_chain/blocks.hex").Close()
+	loadTestDB(t, filepath.Join(dataDir, "chainstate"), "testdata/legacy_chain/chainstate.hex").Close()
+}
+
+// a chain stored with gob is re-encoded when it's opened, keeping its transactions' ids and signatures valid
+func TestMigrateSerialization(t *testing.T) {
+	lowerTestTarget(t)
+	dataDir := t.TempDir()
+	loadLegacyChain(t, dataDir)
+
+	bc := OpenBlockchain(dataDir, true, &chain_params.LegacyNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	blocks := bc.GetBlocksStartingAtHash([]byte{})
+	if len(blocks) != 3 || bc.Height() != 2 {
+		t.Fatalf("Migrated chain has %d blocks at height %d", len(blocks), bc.Height())
+	}
+	paymentTxid, _ := hex.DecodeString(legacyPaymentTxid)
+	refundTxid, _ := hex.DecodeString(legacyRefundTxid)
+	payment, _, err := bc.FindTransaction(paymentTxid)
+	if err != nil || payment.Version != transactions.LegacyTxVersion {
+		t.Fatalf("Migrated payment isn't indexed by its legacy id: %v", err)
+	}
+
+	//legacy transactions are committed to by their legacy ids
+	proof, err := blocks[1].MerkleProof(paymentTxid)
+	if err != nil || !merkle_tree.VerifyMerkleProof(blocks[1].Header.MerkleRootHash, paymentTxid, proof) {
+		t.Fatalf("Proof of the migrated payment doesn't verify against its block's header: %v", err)
+	}
+
+	aliceScript, _ := transactions.AddressScript(legacyAlice)
+	if _, err := transactions.GetUTXO(bc.ChainstateDB, paymentTxid, 0); err == nil {
+		t.Fatalf("Output spent in the migrated chain is unspent")
+	}
+	for _, expected := range []struct {
+		txid     []byte
+		outIndex int
+		coins    transactions.Amount
+	}{{paymentTxid, 1, 5}, {refundTxid, 0, 4}} {
+		utxo, err := transactions.GetUTXO(bc.ChainstateDB, expected.txid, expected.outIndex)
+		if err != nil || utxo.Value != expected.coins*transactions.BaseUnitsPerCoin || !bytes.Equal(utxo.ScriptPubKey, aliceScript) {
+			t.Fatalf("Migrated output %x:%d doesn't pay %d coins to alice: %v", expected.txid, expected.outIndex, expected.coins, err)
+		}
+	}
+
+	//a node syncing the migrated chain from the genesis block verifies it, including the legacy signatures
+	fresh := OpenBlockchain(t.TempDir(), false, &chain_params.LegacyNetParams)
+	defer fresh.BlocksDB.Close()
+	defer fresh.ChainstateDB.Close()
+	for _, block := range blocks {
+		if err := fresh.AddBlock(block); err != nil {
+			t.Fatalf("Migrated block %d was rejected: %s", block.Header.Height, err)
+		}
+	}
+
+	//the legacy signatures commit to the outputs
+	refund := *blocks[2].Transactions[1]
+	refund.Vout = []transactions.TXOutput{{Value: refund.Vout[0].Value - transactions.BaseUnitsPerCoin, ScriptPubKey: refund.Vout[0].ScriptPubKey}}
+	if err := transactions.NewScriptCheck(&refund, 0, payment.Vout[0], bc.Params).Execute(); !errors.Is(err, &blockchain_errors.ErrInvalidTxInputSignature{}) {
+		t.Fatalf("Legacy signature over other outputs was accepted: %v", err)
+	}
+}
+
+// legacy transactions only exist on the legacy network, so a chain stored with gob isn't migrated on other networks
+func TestMigrateSerializationOtherNetwork(t *testing.T) {
+	dataDir := t.TempDir()
+	loadLegacyChain(t, dataDir)
+	bc := &Blockchain{
+		BlocksDB:     loadTestDB(t, filepath.Join(dataDir, "blocks"), os.DevNull),
+		ChainstateDB: loadTestDB(t, filepath.Join(dataDir, "chainstate"), os.DevNull),
+		Params:       &chain_params.MainNetParams,
+	}
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	if err := bc.migrateSerialization(); err == nil {
+		t.Fatalf("Chain stored with gob was migrated on %s", bc.Params.Name)
+	}
+	if legacyBlocks, err := bc.legacyBlocks(); err != nil || len(legacyBlocks) != 3 {
+		t.Fatalf("Chain stored with gob was changed by the refused migration: %v", err)
+	}
+}
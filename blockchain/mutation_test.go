@@ -0,0 +1,35 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestMutatedBlock(t *testing.T) {
+	bc := OpenBlockchain(t.TempDir(), false, &chain_params.MainNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	coinbase := transactions.NewCoinbaseTX(testAddress, 0)
+	tx := spendTx(coinbase.Hash())
+	block := NewBlock([]*transactions.Transaction{coinbase, tx, spendTx(tx.Hash())}, []byte{}, 0, &chain_params.MainNetParams)
+	if err := block.VerifyTxCommitments(); err != nil {
+		t.Fatalf("Block's transactions don't match its header: %s", err)
+	}
+
+	mutated := *block
+	mutated.Transactions = append(mutated.Transactions, block.Transactions[2])
+	if err := bc.VerifyBlock(&mutated); !errors.Is(err, &blockchain_errors.ErrMutatedBlock{}) {
+		t.Fatalf("Block with a duplicated transaction wasn't reported as mutated: %v", err)
+	}
+
+	tampered := *block
+	tampered.Transactions = block.Transactions[:2]
+	if err := bc.VerifyBlock(&tampered); !errors.Is(err, &blockchain_errors.ErrMutatedBlock{}) {
+		t.Fatalf("Block with transactions not matching its header wasn't reported as mutated: %v", err)
+	}
+}
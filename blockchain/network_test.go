@@ -0,0 +1,23 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+)
+
+// the network a blockchain was created on is stored with it, and opening it on another network is refused
+func TestVerifyNetwork(t *testing.T) {
+	bc := OpenBlockchain(t.TempDir(), false, &chain_params.LegacyNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	if err := VerifyNetwork(bc.BlocksDB, &chain_params.LegacyNetParams); err != nil {
+		t.Fatalf("Blockchain was refused on its own network: %s", err)
+	}
+	if err := VerifyNetwork(bc.BlocksDB, &chain_params.MainNetParams); !errors.Is(err, &blockchain_errors.ErrNetworkMismatch{}) {
+		t.Fatalf("Blockchain of the legacy network was opened on mainnet: %v", err)
+	}
+}
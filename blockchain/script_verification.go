@@ -0,0 +1,58 @@
+package blockchain
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// maximum number of goroutines executing the scripts of a block's inputs
+var ScriptVerificationWorkers = runtime.NumCPU()
+
+// executes the script checks across a bounded pool of workers. checks are handed out in order and the error of the
+// first failing check (in that order) is returned, so the result doesn't depend on scheduling. once a check fails,
+// the checks after it are skipped
+func VerifyScripts(scriptChecks []transactions.ScriptCheck, numWorkers int) error {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if numWorkers > len(scriptChecks) {
+		numWorkers = len(scriptChecks)
+	}
+
+	var nextCheck atomic.Int64
+	var firstFailure atomic.Int64
+	firstFailure.Store(int64(len(scriptChecks)))
+	errs := make([]error, len(scriptChecks))
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				checkIdx := nextCheck.Add(1) - 1
+				if checkIdx >= firstFailure.Load() {
+					return
+				}
+				if err := scriptChecks[checkIdx].Execute(); err != nil {
+					errs[checkIdx] = err
+					//lowers the first failure, unless an earlier check already failed
+					for failure := firstFailure.Load(); checkIdx < failure; failure = firstFailure.Load() {
+						if firstFailure.CompareAndSwap(failure, checkIdx) {
+							break
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failure := firstFailure.Load(); failure < int64(len(scriptChecks)) {
+		return errs[failure]
+	}
+	return nil
+}
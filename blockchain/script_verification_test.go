@@ -0,0 +1,166 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+// builds the script checks of a transaction with numInputs signed inputs, each spending a pay to pubkey hash output
+func signedScriptChecks(tb testing.TB, numInputs int) []transactions.ScriptCheck {
+	privKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		tb.Fatalf("Error generating key: %s", err)
+	}
+	pubKey := privKey.PubKey().SerializeCompressed()
+	scriptPubKey, _ := script.PayToPubKeyHashScript(utils.HashPublicKey(pubKey))
+	spentOutput := transactions.TXOutput{Value: 10, ScriptPubKey: scriptPubKey}
+
+	tx := &transactions.Transaction{Version: transactions.TxVersion}
+	for i := 0; i < numInputs; i++ {
+		tx.Vin = append(tx.Vin, transactions.TXInput{Txid: utils.Int64ToHex(int64(i)), Sequence: transactions.MaxSequence})
+	}
+	tx.Vout = []transactions.TXOutput{spentOutput}
+
+	signTestTx(tb, tx, privKey, spentOutput)
+	var scriptChecks []transactions.ScriptCheck
+	for i := range tx.Vin {
+		scriptChecks = append(scriptChecks, transactions.NewScriptCheck(tx, i, spentOutput, &chain_params.MainNetParams))
+	}
+	return scriptChecks
+}
+
+func TestVerifyScripts(t *testing.T) {
+	scriptChecks := signedScriptChecks(t, 50)
+	for _, numWorkers := range []int{1, 4, 64} {
+		if err := VerifyScripts(scriptChecks, numWorkers); err != nil {
+			t.Fatalf("Valid scripts were rejected with %d workers: %s", numWorkers, err)
+		}
+	}
+
+	//corrupts a signature in the middle of the checks
+	invalidChecks := signedScriptChecks(t, 50)
+	tx := *invalidChecks[0].Tx()
+	tx.Vin = append([]transactions.TXInput{}, tx.Vin...)
+	tx.Vin[25].ScriptSig = append([]byte{}, tx.Vin[0].ScriptSig...)
+	for i := range invalidChecks {
+		invalidChecks[i] = transactions.NewScriptCheck(&tx, i, invalidChecks[i].SpentOutput(), &chain_params.MainNetParams)
+	}
+	for _, numWorkers := range []int{1, 4, 64} {
+		err := VerifyScripts(invalidChecks, numWorkers)
+		if !errors.Is(err, &blockchain_errors.ErrInvalidTxInputSignature{}) {
+			t.Fatalf("Invalid script was accepted with %d workers: %v", numWorkers, err)
+		}
+		if !strings.Contains(err.Error(), fmt.Sprintf("input 25 of transaction %x", tx.Hash())) {
+			t.Fatalf("Error doesn't name the failing input with %d workers: %s", numWorkers, err)
+		}
+	}
+
+	if err := VerifyScripts(nil, 4); err != nil {
+		t.Fatalf("Empty checks were rejected: %s", err)
+	}
+}
+
+func BenchmarkVerifyScripts(b *testing.B) {
+	scriptChecks := signedScriptChecks(b, 1000)
+	benchmarks := []struct {
+		name       string
+		numWorkers int
+	}{
+		{"serial", 1},
+		{fmt.Sprintf("parallel-%d", runtime.NumCPU()), runtime.NumCPU()},
+	}
+	for _, benchmark := range benchmarks {
+		numWorkers := benchmark.numWorkers
+		b.Run(benchmark.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := VerifyScripts(scriptChecks, numWorkers); err != nil {
+					b.Fatalf("Valid scripts were rejected: %s", err)
+				}
+			}
+		})
+	}
+}
+
+// signs every input of the transaction, which must all spend the key's pay to pubkey hash outputs
+func signTestTx(tb testing.TB, tx *transactions.Transaction, privKey *btcec.PrivateKey, spentOutput transactions.TXOutput) {
+	pubKey := privKey.PubKey().SerializeCompressed()
+	for i := range tx.Vin {
+		sigHash, err := tx.SignatureHash(i, transactions.SigHashAll, spentOutput)
+		if err != nil {
+			tb.Fatalf("Error computing signature hash: %s", err)
+		}
+		signature := append(ecdsa.Sign(privKey, sigHash).Serialize(), byte(transactions.SigHashAll))
+		tx.Vin[i].ScriptSig, _ = script.PubKeyHashScriptSig(signature, pubKey)
+	}
+}
+
+// adds a block of single input transactions end to end, from its validation to the chainstate update
+func BenchmarkAddBlock(b *testing.B) {
+	const numTxs = 500
+	lowerTestTarget(b)
+	sigCache := transactions.SignatureCache
+	b.Cleanup(func() { transactions.SignatureCache = sigCache })
+	transactions.SignatureCache = transactions.NewSigCache(0) //every iteration verifies the signatures
+
+	privKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		b.Fatalf("Error generating key: %s", err)
+	}
+	address := transactions.PubKeyHashAddress(utils.HashPublicKey(privKey.PubKey().SerializeCompressed()))
+	bc := OpenBlockchain(b.TempDir(), false, &chain_params.MainNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	//the genesis coinbase is split into the outputs spent by the benchmarked block
+	coinbase := transactions.NewCoinbaseTX(address, 0)
+	if err := bc.AddBlock(mineTestBlock(b, bc, coinbase)); err != nil {
+		b.Fatalf("Error adding genesis block: %s", err)
+	}
+	output, _ := transactions.NewTXOutput(coinbase.Vout[0].Value/numTxs, address)
+	split := &transactions.Transaction{
+		Version: transactions.TxVersion,
+		Vin:     []transactions.TXInput{{Txid: coinbase.Hash(), OutIndex: 0, Sequence: transactions.MaxSequence}},
+	}
+	for i := 0; i < numTxs; i++ {
+		split.Vout = append(split.Vout, *output)
+	}
+	signTestTx(b, split, privKey, coinbase.Vout[0])
+	if err := bc.AddBlock(mineTestBlock(b, bc, transactions.NewCoinbaseTX(address, 1), split)); err != nil {
+		b.Fatalf("Error adding split block: %s", err)
+	}
+
+	txs := []*transactions.Transaction{transactions.NewCoinbaseTX(address, 2)}
+	for i := 0; i < numTxs; i++ {
+		tx := &transactions.Transaction{
+			Version: transactions.TxVersion,
+			Vin:     []transactions.TXInput{{Txid: split.Hash(), OutIndex: i, Sequence: transactions.MaxSequence}},
+			Vout:    []transactions.TXOutput{*output},
+		}
+		signTestTx(b, tx, privKey, *output)
+		txs = append(txs, tx)
+	}
+	block := mineTestBlock(b, bc, txs...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := bc.AddBlock(block); err != nil {
+			b.Fatalf("Valid block was rejected: %s", err)
+		}
+		b.StopTimer()
+		if err := bc.RemoveBlock(block.GetBlockHeaderHash()); err != nil {
+			b.Fatalf("Error removing block: %s", err)
+		}
+		b.StartTimer()
+	}
+}
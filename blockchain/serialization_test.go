@@ -0,0 +1,73 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestBlockEncoding(t *testing.T) {
+	coinbase := transactions.NewCoinbaseTX(testAddress, 3)
+	block := NewBlock([]*transactions.Transaction{coinbase, spendTx(coinbase.Hash())}, bytes.Repeat([]byte{0xab}, 32), 3, &chain_params.MainNetParams)
+	encoded := block.Serialize()
+
+	decoded, err := DecodeBlock(encoded)
+	if err != nil {
+		t.Fatalf("Error decoding block: %s", err)
+	}
+	if !reflect.DeepEqual(decoded, block) || !bytes.Equal(decoded.Serialize(), encoded) {
+		t.Fatalf("Decoded block doesn't match the encoded block")
+	}
+	if !bytes.HasPrefix(encoded, block.Header.Serialize()) {
+		t.Fatalf("Block encoding doesn't start with its header")
+	}
+
+	unknownVersion := append([]byte{}, encoded...)
+	unknownVersion[0]++
+	for name, data := range map[string][]byte{
+		"trailing bytes":  append(append([]byte{}, encoded...), 0),
+		"truncated":       encoded[:len(encoded)-1],
+		"unknown version": unknownVersion,
+		"huge count":      append(block.Header.Serialize(), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f),
+	} {
+		if _, err := DecodeBlock(data); !errors.Is(err, &blockchain_errors.ErrMalformedEncoding{}) {
+			t.Errorf("Block with %s was decoded: %v", name, err)
+		}
+	}
+
+	//version byte, 4 byte tx version, flags and then the number of inputs
+	txBytes := coinbase.Serialize()
+	unknownFlags := append([]byte{}, txBytes...)
+	unknownFlags[5] |= 2
+	nonMinimalCount := append(append(append([]byte{}, txBytes[:6]...), 0xfd, txBytes[6], 0), txBytes[7:]...)
+	for name, data := range map[string][]byte{"unknown flags": unknownFlags, "non minimal count": nonMinimalCount} {
+		if _, err := transactions.DecodeTransaction(data); !errors.Is(err, &blockchain_errors.ErrMalformedEncoding{}) {
+			t.Errorf("Transaction with %s was decoded: %v", name, err)
+		}
+	}
+}
+
+// the layout of header encoding version 1, which changes only with a new version
+func TestBlockHeaderEncoding(t *testing.T) {
+	header := BlockHeader{
+		Version:             2,
+		PrevBlockHeaderHash: bytes.Repeat([]byte{0xab}, 32),
+		MerkleRootHash:      bytes.Repeat([]byte{0xcd}, 32),
+		WitnessRootHash:     bytes.Repeat([]byte{0xef}, 32),
+		Timestamp:           1700000000,
+		Nonce:               7,
+		Height:              3,
+	}
+	expected := "0102000000" + "20" + strings.Repeat("ab", 32) + "20" + strings.Repeat("cd", 32) + "20" + strings.Repeat("ef", 32) +
+		"00f1536500000000" + "07000000" + "03000000"
+	if encoded := hex.EncodeToString(header.Serialize()); encoded != expected {
+		t.Fatalf("Header encoding changed: %s", encoded)
+	}
+}
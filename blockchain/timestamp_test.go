@@ -0,0 +1,87 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestMedianTimestamp(t *testing.T) {
+	tests := []struct {
+		timestamps []int64
+		median     int64
+	}{
+		{nil, 0},
+		{[]int64{5}, 5},
+		{[]int64{30, 10, 20}, 20},
+		{[]int64{40, 10, 30, 20}, 30}, //the upper of the two middle timestamps
+	}
+	for _, test := range tests {
+		if median := MedianTimestamp(test.timestamps); median != test.median {
+			t.Errorf("Median of %v is %d, expected %d", test.timestamps, median, test.median)
+		}
+	}
+}
+
+func TestVerifyTimestamp(t *testing.T) {
+	const medianTimePast = 1000
+	maxTimestamp := time.Now().Add(maxFutureBlockTime).Unix()
+	tests := []struct {
+		name      string
+		timestamp int64
+		err       error
+	}{
+		{"at the median time past", medianTimePast, &blockchain_errors.ErrTimestampTooOld{}},
+		{"after the median time past", medianTimePast + 1, nil},
+		{"at the maximum future time", maxTimestamp, nil},
+		{"a minute past the maximum future time", maxTimestamp + 60, &blockchain_errors.ErrTimestampTooNew{}},
+	}
+	for _, test := range tests {
+		err := BlockHeader{Timestamp: test.timestamp}.VerifyTimestamp(medianTimePast)
+		if (test.err == nil && err != nil) || (test.err != nil && !errors.Is(err, test.err)) {
+			t.Errorf("Timestamp %s: got %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
+
+// the median time past covers the last MedianTimeSpan blocks, and a block at exactly the median time past of its
+// parent is rejected
+func TestMedianTimePast(t *testing.T) {
+	lowerTestTarget(t)
+	bc := OpenBlockchain(t.TempDir(), false, &chain_params.MainNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	mineAt := func(timestamp int64) *Block {
+		block := NewBlock([]*transactions.Transaction{transactions.NewCoinbaseTX(testAddress, bc.Height()+1)}, bc.LastBlockHash(), bc.Height()+1, bc.Params)
+		block.Header.Timestamp = timestamp
+		if !block.remine() {
+			t.Fatalf("Error mining block %d", block.Header.Height)
+		}
+		return block
+	}
+	//blocks alternate between far and just after the median time past, so that the oldest blocks' timestamps
+	//would change the median if they were counted
+	var timestamps []int64
+	for i := 0; i < MedianTimeSpan+5; i++ {
+		timestamp := bc.MedianTimePast(bc.LastBlockHash()) + 1 + int64(i%2)*100
+		if err := bc.AddBlock(mineAt(timestamp)); err != nil {
+			t.Fatalf("Error adding block %d: %s", i, err)
+		}
+		timestamps = append(timestamps, timestamp)
+	}
+	medianTimePast := bc.MedianTimePast(bc.LastBlockHash())
+	if expected := MedianTimestamp(timestamps[len(timestamps)-MedianTimeSpan:]); medianTimePast != expected || expected == MedianTimestamp(timestamps) {
+		t.Fatalf("Median time past is %d, expected the median of the last %d blocks %d", medianTimePast, MedianTimeSpan, expected)
+	}
+	if err := bc.AddBlock(mineAt(medianTimePast)); !errors.Is(err, &blockchain_errors.ErrTimestampTooOld{}) {
+		t.Fatalf("Block at the median time past was accepted: %v", err)
+	}
+	if err := bc.AddBlock(mineAt(medianTimePast + 1)); err != nil {
+		t.Fatalf("Block after the median time past was rejected: %s", err)
+	}
+}
@@ -0,0 +1,147 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const TX_INDEX_PREFIX string = "txindex:"
+
+// marks that the tx index is complete, so that it's rebuilt if it was disabled at some point
+const TX_INDEX_BUILT_KEY string = "txindexbuilt"
+
+type TxLocation struct {
+	BlockHash []byte
+	Position  int
+}
+
+func (loc TxLocation) Serialize() []byte {
+	w := serialization.NewVersionedWriter()
+	w.WriteVarBytes(loc.BlockHash)
+	w.WriteUint32(uint32(loc.Position))
+	return w.Bytes()
+}
+
+func DeserializeTxLocation(data []byte) TxLocation {
+	r := serialization.NewVersionedReader(data)
+	loc := TxLocation{BlockHash: r.ReadVarBytes(), Position: int(r.ReadUint32())}
+	if err := r.Finish(); err != nil {
+		log.Panic(err)
+	}
+	return loc
+}
+
+func txIndexKey(txHash []byte) []byte {
+	return append([]byte(TX_INDEX_PREFIX), txHash...)
+}
+
+func (bc *Blockchain) indexBlockTxs(block *Block, blockHash []byte) error {
+	for position, tx := range block.Transactions {
+		loc := TxLocation{BlockHash: blockHash, Position: position}
+		err := bc.BlocksDB.Put(txIndexKey(tx.Hash()), loc.Serialize(), nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (bc *Blockchain) deindexBlockTxs(block *Block, blockHash []byte) error {
+	for _, tx := range block.Transactions {
+		key := txIndexKey(tx.Hash())
+		locBytes, err := bc.BlocksDB.Get(key, nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		//only delete the entry if it points to this block (an equal tx may have been indexed by another block)
+		if !bytes.Equal(DeserializeTxLocation(locBytes).BlockHash, blockHash) {
+			continue
+		}
+		err = bc.BlocksDB.Delete(key, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builds the tx index from scratch if it isn't complete, or drops the completion mark if the index is disabled
+func (bc *Blockchain) syncTxIndex() error {
+	_, err := bc.BlocksDB.Get([]byte(TX_INDEX_BUILT_KEY), nil)
+	isBuilt := err == nil
+	if err != nil && err != leveldb.ErrNotFound {
+		return err
+	}
+
+	if !bc.TxIndex {
+		if isBuilt {
+			return bc.BlocksDB.Delete([]byte(TX_INDEX_BUILT_KEY), nil)
+		}
+		return nil
+	}
+
+	if isBuilt {
+		return nil
+	}
+
+	fmt.Println("Building transaction index...")
+
+	txIndexIter := bc.BlocksDB.NewIterator(util.BytesPrefix([]byte(TX_INDEX_PREFIX)), nil)
+	for txIndexIter.Next() {
+		err = bc.BlocksDB.Delete(txIndexIter.Key(), nil)
+		if err != nil {
+			txIndexIter.Release()
+			return err
+		}
+	}
+	txIndexIter.Release()
+	if err = txIndexIter.Error(); err != nil {
+		return err
+	}
+
+	for _, block := range bc.GetBlocksStartingAtHash([]byte{}) {
+		err = bc.indexBlockTxs(block, block.GetBlockHeaderHash())
+		if err != nil {
+			return err
+		}
+	}
+
+	return bc.BlocksDB.Put([]byte(TX_INDEX_BUILT_KEY), []byte{}, nil)
+}
+
+// returns the confirmed transaction with the given hash and the block containing it
+func (bc *Blockchain) FindTransaction(txHash []byte) (*transactions.Transaction, *Block, error) {
+	if !bc.TxIndex {
+		return nil, nil, &blockchain_errors.ErrTxIndexDisabled{}
+	}
+
+	locBytes, err := bc.BlocksDB.Get(txIndexKey(txHash), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil, &blockchain_errors.ErrTxNotFound{}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc := DeserializeTxLocation(locBytes)
+	block := bc.GetBlock(loc.BlockHash)
+	if block == nil || loc.Position >= len(block.Transactions) {
+		return nil, nil, &blockchain_errors.ErrTxNotFound{}
+	}
+
+	return block.Transactions[loc.Position], block, nil
+}
+
+func (bc *Blockchain) Confirmations(block *Block) int {
+	return bc.Height() - block.Header.Height + 1
+}
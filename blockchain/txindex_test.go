@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+const testAddress = "1BoatSLRHtKNngkdXEeobR76b53LETtpyT"
+
+// spends the first output of the given transaction without lock times, leaving its input script unsigned
+func spendTx(txid []byte) *transactions.Transaction {
+	out, _ := transactions.NewTXOutput(9, testAddress)
+	return &transactions.Transaction{
+		Version: transactions.TxVersion,
+		Vin:     []transactions.TXInput{{Txid: txid, OutIndex: 0, ScriptSig: []byte{1, 2, 3}, Sequence: transactions.MaxSequence}},
+		Vout:    []transactions.TXOutput{*out},
+	}
+}
+
+// blocks are mined with almost no work, so that tests don't wait on proof of work
+func lowerTestTarget(t testing.TB) {
+	target := Target
+	t.Cleanup(func() { Target = target })
+	Target = new(big.Int).Lsh(big.NewInt(1), 255)
+}
+
+// mines a block with the given transactions on top of the chain, without adding it
+func mineTestBlock(t testing.TB, bc *Blockchain, txs ...*transactions.Transaction) *Block {
+	block := NewBlock(txs, bc.LastBlockHash(), bc.Height()+1, bc.Params)
+	if medianTimePast := bc.MedianTimePast(block.Header.PrevBlockHeaderHash); block.Header.Timestamp <= medianTimePast {
+		block.Header.Timestamp = medianTimePast + 1
+	}
+	if !block.remine() {
+		t.Fatalf("Error mining block %d", block.Header.Height)
+	}
+	return block
+}
+
+// mines a block with the given transactions after a coinbase on top of the chain and adds it
+func addTestBlock(t *testing.T, bc *Blockchain, txs ...*transactions.Transaction) *Block {
+	block := mineTestBlock(t, bc, append([]*transactions.Transaction{transactions.NewCoinbaseTX(testAddress, bc.Height()+1)}, txs...)...)
+	if err := bc.AddBlock(block); err != nil {
+		t.Fatalf("Error adding block %d: %s", block.Header.Height, err)
+	}
+	return block
+}
+
+// removing a block removes its transactions from the index, while the transactions of earlier blocks stay indexed
+func TestTxIndexReorg(t *testing.T) {
+	lowerTestTarget(t)
+	bc := OpenBlockchain(t.TempDir(), true, &chain_params.MainNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+
+	genesis := addTestBlock(t, bc)
+	block := addTestBlock(t, bc)
+	coinbase := block.Transactions[0]
+	tx, foundBlock, err := bc.FindTransaction(coinbase.Hash())
+	if err != nil || !bytes.Equal(tx.Hash(), coinbase.Hash()) || !bytes.Equal(foundBlock.GetBlockHeaderHash(), block.GetBlockHeaderHash()) {
+		t.Fatalf("Indexed transaction wasn't found in its block: %v", err)
+	}
+
+	if err := bc.RemoveBlock(block.GetBlockHeaderHash()); err != nil {
+		t.Fatalf("Error removing block: %s", err)
+	}
+	if _, _, err := bc.FindTransaction(coinbase.Hash()); !errors.Is(err, &blockchain_errors.ErrTxNotFound{}) {
+		t.Fatalf("Transaction of a removed block was found: %v", err)
+	}
+	if _, err := transactions.GetUTXO(bc.ChainstateDB, coinbase.Hash(), 0); err == nil {
+		t.Fatalf("Output of a removed block is unspent")
+	}
+	if _, _, err := bc.FindTransaction(genesis.Transactions[0].Hash()); err != nil {
+		t.Fatalf("Transaction of the remaining block wasn't found: %s", err)
+	}
+}
+
+// enabling the index on a chain built without it indexes the blocks added so far
+func TestTxIndexBackfill(t *testing.T) {
+	lowerTestTarget(t)
+	dataDir := t.TempDir()
+
+	bc := OpenBlockchain(dataDir, false, &chain_params.MainNetParams)
+	blocks := []*Block{addTestBlock(t, bc), addTestBlock(t, bc)}
+	if _, _, err := bc.FindTransaction(blocks[0].Transactions[0].Hash()); !errors.Is(err, &blockchain_errors.ErrTxIndexDisabled{}) {
+		t.Fatalf("Transaction was looked up with the index disabled: %v", err)
+	}
+	bc.BlocksDB.Close()
+	bc.ChainstateDB.Close()
+
+	bc = OpenBlockchain(dataDir, true, &chain_params.MainNetParams)
+	blocks = append(blocks, addTestBlock(t, bc))
+	bc.BlocksDB.Close()
+	bc.ChainstateDB.Close()
+
+	//blocks added while the index is disabled again are indexed when it's re-enabled
+	bc = OpenBlockchain(dataDir, false, &chain_params.MainNetParams)
+	blocks = append(blocks, addTestBlock(t, bc))
+	bc.BlocksDB.Close()
+	bc.ChainstateDB.Close()
+
+	bc = OpenBlockchain(dataDir, true, &chain_params.MainNetParams)
+	defer bc.BlocksDB.Close()
+	defer bc.ChainstateDB.Close()
+	for _, block := range blocks {
+		_, foundBlock, err := bc.FindTransaction(block.Transactions[0].Hash())
+		if err != nil || !bytes.Equal(foundBlock.GetBlockHeaderHash(), block.GetBlockHeaderHash()) {
+			t.Fatalf("Transaction of block %d wasn't indexed: %v", block.Header.Height, err)
+		}
+	}
+}
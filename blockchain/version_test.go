@@ -0,0 +1,48 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestVersionDeployments(t *testing.T) {
+	params := chain_params.MainNetParams
+	params.Deployments = append(params.Deployments, chain_params.Deployment{Name: "v2", TxVersion: 2, BlockVersion: 2, ActivationHeight: 10})
+
+	if params.MaxBlockVersion(9) != 1 || params.MaxBlockVersion(10) != 2 || params.MaxTxVersion(10) != 2 {
+		t.Fatalf("Versions don't follow the deployment's activation height")
+	}
+
+	coinbase := transactions.NewCoinbaseTX(testAddress, 9)
+	before, after := NewBlock([]*transactions.Transaction{coinbase}, []byte{}, 9, &params), NewBlock([]*transactions.Transaction{coinbase}, []byte{}, 10, &params)
+	if before.Header.Version != 1 || after.Header.Version != 2 {
+		t.Fatalf("New blocks don't use the highest active version")
+	}
+	if before.VerifyVersion(&params) != nil || after.VerifyVersion(&params) != nil {
+		t.Fatalf("Block with an active version was rejected")
+	}
+
+	early := *after
+	early.Header.Height = 9
+	if err := early.VerifyVersion(&params); !errors.Is(err, &blockchain_errors.ErrUnsupportedBlockVersion{}) {
+		t.Fatalf("Block with a version not yet active was accepted: %v", err)
+	}
+	hashBefore := early.GetBlockHeaderHash()
+	early.Header.Version = 1
+	if bytes.Equal(hashBefore, early.GetBlockHeaderHash()) {
+		t.Fatalf("Block hash doesn't commit to the version")
+	}
+
+	for _, version := range []int32{0, 3} {
+		tx := *coinbase
+		tx.Version = version
+		if err := tx.Verify(nil, 10, &params); !errors.Is(err, &blockchain_errors.ErrUnsupportedTxVersion{}) {
+			t.Fatalf("Transaction with version %d was accepted: %v", version, err)
+		}
+	}
+}
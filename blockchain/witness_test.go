@@ -0,0 +1,41 @@
+package blockchain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// re-encoding a transaction's signatures keeps its txid, but not its witness hash or the block's witness root
+func TestWitnessMalleability(t *testing.T) {
+	coinbase := transactions.NewCoinbaseTX(testAddress, 1)
+	tx := spendTx(coinbase.Hash())
+	block := NewBlock([]*transactions.Transaction{coinbase, tx}, []byte{}, 1, &chain_params.MainNetParams)
+
+	malleated := *tx
+	malleated.Vin = append([]transactions.TXInput{}, tx.Vin...)
+	malleated.Vin[0].ScriptSig = append([]byte{0x00}, tx.Vin[0].ScriptSig...)
+	if !bytes.Equal(malleated.Hash(), tx.Hash()) {
+		t.Fatalf("Changing the input script changed the txid")
+	}
+	if bytes.Equal(malleated.WitnessHash(), tx.WitnessHash()) {
+		t.Fatalf("Changing the input script didn't change the witness hash")
+	}
+
+	otherCoinbase := *coinbase
+	otherCoinbase.Vin = []transactions.TXInput{{OutIndex: -1, ScriptSig: []byte("other"), Sequence: transactions.MaxSequence}}
+	if bytes.Equal(otherCoinbase.Hash(), coinbase.Hash()) {
+		t.Fatalf("Coinbases with different input scripts have the same txid")
+	}
+
+	malleatedBlock := *block
+	malleatedBlock.Transactions = []*transactions.Transaction{coinbase, &malleated}
+	if !bytes.Equal(malleatedBlock.MerkleRootHash(), block.Header.MerkleRootHash) {
+		t.Fatalf("Changing an input script changed the merkle root")
+	}
+	if bytes.Equal(malleatedBlock.WitnessRootHash(), block.Header.WitnessRootHash) {
+		t.Fatalf("Witness root doesn't commit to the input scripts")
+	}
+}
@@ -0,0 +1,91 @@
+package blockchain_errors
+
+type ErrEmptyBlock struct{}
+
+func (m *ErrEmptyBlock) Error() string {
+	return "invalid block, block has no transactions"
+}
+
+type ErrBlockTooLarge struct{}
+
+func (m *ErrBlockTooLarge) Error() string {
+	return "invalid block, serialized block exceeds the maximum block size"
+}
+
+type ErrTooManyTxs struct{}
+
+func (m *ErrTooManyTxs) Error() string {
+	return "invalid block, block exceeds the maximum number of transactions"
+}
+
+type ErrTxTooLarge struct{}
+
+func (m *ErrTxTooLarge) Error() string {
+	return "invalid transaction, serialized transaction exceeds the maximum transaction size"
+}
+
+type ErrMissingCoinbase struct{}
+
+func (m *ErrMissingCoinbase) Error() string {
+	return "invalid block, first transaction isn't a coinbase"
+}
+
+type ErrCoinbaseValueTooLarge struct{}
+
+func (m *ErrCoinbaseValueTooLarge) Error() string {
+	return "invalid block, coinbase pays more than the block subsidy plus fees"
+}
+
+type ErrTimestampTooOld struct{}
+
+func (m *ErrTimestampTooOld) Error() string {
+	return "invalid block, timestamp isn't later than the median time of the previous blocks"
+}
+
+type ErrTimestampTooNew struct{}
+
+func (m *ErrTimestampTooNew) Error() string {
+	return "invalid block, timestamp is too far in the future"
+}
+
+type ErrUnsupportedBlockVersion struct{}
+
+func (m *ErrUnsupportedBlockVersion) Error() string {
+	return "invalid block, version isn't active at the block's height"
+}
+
+type ErrTxNotInBlock struct{}
+
+func (m *ErrTxNotInBlock) Error() string {
+	return "transaction isn't in the block"
+}
+
+type ErrMutatedBlock struct{}
+
+func (m *ErrMutatedBlock) Error() string {
+	return "invalid block, transactions don't match the header or duplicate transactions that keep the merkle root"
+}
+
+type ErrBlockNotFound struct{}
+
+func (m *ErrBlockNotFound) Error() string {
+	return "block not found"
+}
+
+type ErrInvalidFilterRange struct{}
+
+func (m *ErrInvalidFilterRange) Error() string {
+	return "invalid range of block filters"
+}
+
+type ErrInvalidCompactBlock struct{}
+
+func (m *ErrInvalidCompactBlock) Error() string {
+	return "invalid compact block, its transactions can't be placed in the block"
+}
+
+type ErrBlockFailedValidation struct{}
+
+func (m *ErrBlockFailedValidation) Error() string {
+	return "block failed validation"
+}
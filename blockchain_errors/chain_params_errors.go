@@ -0,0 +1,13 @@
+package blockchain_errors
+
+type ErrUnknownNetwork struct{}
+
+func (m *ErrUnknownNetwork) Error() string {
+	return "unknown network, must be mainnet, testnet or legacy"
+}
+
+type ErrNetworkMismatch struct{}
+
+func (m *ErrNetworkMismatch) Error() string {
+	return "the data directory belongs to another network, run the node with the network it was created on"
+}
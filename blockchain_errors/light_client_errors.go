@@ -0,0 +1,19 @@
+package blockchain_errors
+
+type ErrAddressNotWatched struct{}
+
+func (m *ErrAddressNotWatched) Error() string {
+	return "address isn't watched by the light client"
+}
+
+type ErrInvalidProofOfWork struct{}
+
+func (m *ErrInvalidProofOfWork) Error() string {
+	return "invalid block header, hash doesn't meet the proof of work target"
+}
+
+type ErrInvalidFilter struct{}
+
+func (m *ErrInvalidFilter) Error() string {
+	return "block filter doesn't match its filter header"
+}
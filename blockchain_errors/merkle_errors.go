@@ -0,0 +1,13 @@
+package blockchain_errors
+
+type ErrLeafIndexOutOfRange struct{}
+
+func (m *ErrLeafIndexOutOfRange) Error() string {
+	return "leaf index is out of the merkle tree's range"
+}
+
+type ErrInvalidPartialMerkleTree struct{}
+
+func (m *ErrInvalidPartialMerkleTree) Error() string {
+	return "partial merkle tree is malformed"
+}
@@ -0,0 +1,115 @@
+package blockchain_errors
+
+type ErrScriptTooLarge struct{}
+
+func (m *ErrScriptTooLarge) Error() string {
+	return "invalid script, script exceeds the maximum script size"
+}
+
+type ErrMalformedPush struct{}
+
+func (m *ErrMalformedPush) Error() string {
+	return "invalid script, push opcode exceeds the end of the script"
+}
+
+type ErrNonMinimalPush struct{}
+
+func (m *ErrNonMinimalPush) Error() string {
+	return "invalid script, data isn't pushed with the smallest possible opcode"
+}
+
+type ErrElementTooLarge struct{}
+
+func (m *ErrElementTooLarge) Error() string {
+	return "invalid script, pushed element exceeds the maximum element size"
+}
+
+type ErrTooManyOps struct{}
+
+func (m *ErrTooManyOps) Error() string {
+	return "invalid script, script exceeds the maximum number of operations"
+}
+
+type ErrStackOverflow struct{}
+
+func (m *ErrStackOverflow) Error() string {
+	return "invalid script, stack exceeds the maximum number of elements"
+}
+
+type ErrStackUnderflow struct{}
+
+func (m *ErrStackUnderflow) Error() string {
+	return "invalid script, operation needs more elements than those on the stack"
+}
+
+type ErrInvalidOpcode struct{}
+
+func (m *ErrInvalidOpcode) Error() string {
+	return "invalid script, unknown or disabled opcode"
+}
+
+type ErrUnbalancedConditional struct{}
+
+func (m *ErrUnbalancedConditional) Error() string {
+	return "invalid script, conditional isn't properly closed"
+}
+
+type ErrNumberTooLarge struct{}
+
+func (m *ErrNumberTooLarge) Error() string {
+	return "invalid script, numeric operand exceeds the maximum number size"
+}
+
+type ErrNonMinimalNumber struct{}
+
+func (m *ErrNonMinimalNumber) Error() string {
+	return "invalid script, numeric operand isn't minimally encoded"
+}
+
+type ErrEarlyReturn struct{}
+
+func (m *ErrEarlyReturn) Error() string {
+	return "invalid script, script executed OP_RETURN"
+}
+
+type ErrVerifyFailed struct{}
+
+func (m *ErrVerifyFailed) Error() string {
+	return "invalid script, verify operation failed"
+}
+
+type ErrEvalFalse struct{}
+
+func (m *ErrEvalFalse) Error() string {
+	return "invalid script, script finished with an empty or false stack top"
+}
+
+type ErrScriptSigNotPushOnly struct{}
+
+func (m *ErrScriptSigNotPushOnly) Error() string {
+	return "invalid script, unlocking script can only push data"
+}
+
+type ErrInvalidPubKeyCount struct{}
+
+func (m *ErrInvalidPubKeyCount) Error() string {
+	return "invalid script, multisig pubkey count is negative or exceeds the maximum"
+}
+
+type ErrInvalidSignatureCount struct{}
+
+func (m *ErrInvalidSignatureCount) Error() string {
+	return "invalid script, multisig signature count is negative or exceeds the pubkey count"
+}
+
+type ErrUnsatisfiedLockTime struct{}
+
+func (m *ErrUnsatisfiedLockTime) Error() string {
+	return "invalid script, transaction's lock time doesn't satisfy the script's lock time"
+}
+
+type ErrInvalidHTLC struct{}
+
+func (m *ErrInvalidHTLC) Error() string {
+	return "invalid HTLC, hash must have 32 bytes, pubkey hashes 20 bytes and the lock time must be a positive 32 bit value"
+}
@@ -0,0 +1,7 @@
+package blockchain_errors
+
+type ErrMalformedEncoding struct{}
+
+func (m *ErrMalformedEncoding) Error() string {
+	return "malformed or non canonical encoding"
+}
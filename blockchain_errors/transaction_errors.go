@@ -23,3 +23,123 @@ type ErrOutputValLGTInputVal struct{}
 func (m *ErrOutputValLGTInputVal) Error() string {
 	return "invalid transaction, total output value is larger than total input value"
 }
+
+type ErrTxNotFound struct{}
+
+func (m *ErrTxNotFound) Error() string {
+	return "transaction not found"
+}
+
+type ErrTxIndexDisabled struct{}
+
+func (m *ErrTxIndexDisabled) Error() string {
+	return "transaction index is disabled, run the node with -txindex"
+}
+
+type ErrDuplicateTx struct{}
+
+func (m *ErrDuplicateTx) Error() string {
+	return "invalid transaction, a transaction with the same hash already exists"
+}
+
+type ErrDuplicateInput struct{}
+
+func (m *ErrDuplicateInput) Error() string {
+	return "invalid transaction, an output is spent by more than one of its inputs"
+}
+
+type ErrAmountOutOfRange struct{}
+
+func (m *ErrAmountOutOfRange) Error() string {
+	return "invalid transaction, amount is negative or exceeds the maximum money supply"
+}
+
+type ErrUnexpectedCoinbase struct{}
+
+func (m *ErrUnexpectedCoinbase) Error() string {
+	return "invalid transaction, coinbase transactions are only valid as the first transaction of a block"
+}
+
+type ErrNonFinalTx struct{}
+
+func (m *ErrNonFinalTx) Error() string {
+	return "invalid transaction, transaction's lock time hasn't been reached"
+}
+
+type ErrSequenceLocked struct{}
+
+func (m *ErrSequenceLocked) Error() string {
+	return "invalid transaction, spent outputs don't have the confirmations required by the inputs' relative lock times"
+}
+
+type ErrWaitingPoolFull struct{}
+
+func (m *ErrWaitingPoolFull) Error() string {
+	return "too many transactions are waiting for their inputs' relative lock times"
+}
+
+type ErrNotMultisig struct{}
+
+func (m *ErrNotMultisig) Error() string {
+	return "invalid partially signed transaction, every input must spend a multisig output"
+}
+
+type ErrUnknownSigner struct{}
+
+func (m *ErrUnknownSigner) Error() string {
+	return "invalid signature, pubkey isn't one of the multisig output's pubkeys"
+}
+
+type ErrPartialTxMismatch struct{}
+
+func (m *ErrPartialTxMismatch) Error() string {
+	return "partially signed transactions don't spend and create the same outputs"
+}
+
+type ErrNotEnoughSignatures struct{}
+
+func (m *ErrNotEnoughSignatures) Error() string {
+	return "partially signed transaction doesn't have enough signatures yet"
+}
+
+type ErrRedeemScriptMismatch struct{}
+
+func (m *ErrRedeemScriptMismatch) Error() string {
+	return "redeem script doesn't match the script hash of the output being spent"
+}
+
+type ErrNotHTLC struct{}
+
+func (m *ErrNotHTLC) Error() string {
+	return "output isn't a hash time-locked contract"
+}
+
+type ErrNonZeroDataOutput struct{}
+
+func (m *ErrNonZeroDataOutput) Error() string {
+	return "data outputs can't be spent, so they must have zero value"
+}
+
+type ErrDataCarrierTooLarge struct{}
+
+func (m *ErrDataCarrierTooLarge) Error() string {
+	return "data output carries more data than this node relays"
+}
+
+type ErrInvalidSigHashType struct{}
+
+func (m *ErrInvalidSigHashType) Error() string {
+	return "invalid signature hash type for this input"
+}
+
+type ErrUnsupportedTxVersion struct{}
+
+func (m *ErrUnsupportedTxVersion) Error() string {
+	return "transaction version isn't active at this height"
+}
+
+type ErrInvalidLegacyTx struct{}
+
+func (m *ErrInvalidLegacyTx) Error() string {
+	return "invalid legacy transaction, it doesn't convert back to a transaction stored with gob"
+}
@@ -0,0 +1,86 @@
+package chain_params
+
+import (
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+// elliptic curve over which transaction signatures are made
+type SignatureCurve int
+
+const (
+	CurveP256      SignatureCurve = iota //raw X||Y pubkeys and r||s ECDSA signatures
+	CurveSecp256k1                       //compressed pubkeys, strict DER ECDSA signatures and BIP340 Schnorr signatures
+)
+
+// a change to the consensus rules, introducing new transaction and block versions once the chain reaches its
+// activation height. blocks and transactions with versions not yet active are rejected, so nodes can be upgraded
+// ahead of the change without splitting the chain
+type Deployment struct {
+	Name             string
+	TxVersion        int32 //highest transaction version valid once the deployment is active
+	BlockVersion     int32 //highest block version valid once the deployment is active
+	ActivationHeight int
+}
+
+// the rules every network starts with
+var genesisDeployment = Deployment{Name: "genesis", TxVersion: 1, BlockVersion: 1, ActivationHeight: 0}
+
+// consensus parameters which differ between networks, so nodes of different networks never accept each other's blocks
+type Params struct {
+	Name           string
+	SignatureCurve SignatureCurve
+	Deployments    []Deployment
+	LegacyTxs      bool //whether the history has legacy transactions, converted from the chain stored with gob
+}
+
+// highest transaction version valid in a block at the given height
+func (params *Params) MaxTxVersion(height int) int32 {
+	var maxVersion int32
+	for _, deployment := range params.Deployments {
+		if height >= deployment.ActivationHeight && deployment.TxVersion > maxVersion {
+			maxVersion = deployment.TxVersion
+		}
+	}
+	return maxVersion
+}
+
+// highest version of a block at the given height
+func (params *Params) MaxBlockVersion(height int) int32 {
+	var maxVersion int32
+	for _, deployment := range params.Deployments {
+		if height >= deployment.ActivationHeight && deployment.BlockVersion > maxVersion {
+			maxVersion = deployment.BlockVersion
+		}
+	}
+	return maxVersion
+}
+
+var MainNetParams = Params{
+	Name:           "mainnet",
+	SignatureCurve: CurveSecp256k1,
+	Deployments:    []Deployment{genesisDeployment},
+}
+
+var TestNetParams = Params{
+	Name:           "testnet",
+	SignatureCurve: CurveSecp256k1,
+	Deployments:    []Deployment{genesisDeployment},
+}
+
+// network of nodes still running on the original P256 signatures
+var LegacyNetParams = Params{
+	Name:           "legacy",
+	SignatureCurve: CurveP256,
+	Deployments:    []Deployment{genesisDeployment},
+	LegacyTxs:      true,
+}
+
+// parameters of the network with the given name, which are passed to everything validating blocks and transactions
+func NetworkParams(name string) (*Params, error) {
+	for _, params := range []*Params{&MainNetParams, &TestNetParams, &LegacyNetParams} {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+	return nil, &blockchain_errors.ErrUnknownNetwork{}
+}
@@ -0,0 +1,64 @@
+// Package transactions holds frozen copies of the transaction types stored with gob, before the binary encoding
+// existed. the ids of those transactions are hashes of their gob encoding, which includes the names of the types,
+// their fields and the package (in the names of slice types) and the ids gob assigns to the types in the order they
+// were first encoded. these types, and this package's name, must therefore never change
+package transactions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"io"
+	"log"
+)
+
+type Transaction struct {
+	Vin        []TXInput
+	Vout       []TXOutput
+	IsCoinbase bool
+}
+
+type TXInput struct {
+	Txid      []byte
+	OutIndex  int
+	Signature []byte //r||s ECDSA signature over the hash of the trimmed copy, made with a P256 key
+	PubKey    []byte //raw X||Y P256 public key, or random bytes in a coinbase
+}
+
+type TXOutput struct {
+	Value      int //in coins
+	PubKeyHash []byte
+}
+
+// nodes storing transactions with gob encoded a transaction before any other type, so these types got the first ids
+func init() {
+	if err := gob.NewEncoder(io.Discard).Encode(Transaction{}); err != nil {
+		log.Panic(err)
+	}
+}
+
+func (tx Transaction) Serialize() []byte {
+	var encoded bytes.Buffer
+	if err := gob.NewEncoder(&encoded).Encode(tx); err != nil {
+		log.Panic(err)
+	}
+	return encoded.Bytes()
+}
+
+func (tx Transaction) Hash() []byte {
+	hash := sha256.Sum256(tx.Serialize())
+	return hash[:]
+}
+
+// the transaction without the signatures and public keys, whose hash every input signs
+func (tx Transaction) TrimmedCopy() Transaction {
+	var inputs []TXInput
+	var outputs []TXOutput
+	for _, txIn := range tx.Vin {
+		inputs = append(inputs, TXInput{txIn.Txid, txIn.OutIndex, nil, nil})
+	}
+	for _, txOut := range tx.Vout {
+		outputs = append(outputs, TXOutput{txOut.Value, txOut.PubKeyHash})
+	}
+	return Transaction{inputs, outputs, tx.IsCoinbase}
+}
@@ -0,0 +1,21 @@
+package transactions
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// the hashes were computed by the code which stored transactions with gob, so they only match if neither these
+// types nor the order in which gob first saw them changed
+func TestLegacyHashes(t *testing.T) {
+	tx := Transaction{
+		Vin:  []TXInput{{Txid: []byte{1, 2, 3}, OutIndex: 1, Signature: []byte{4, 5}, PubKey: []byte{6, 7}}},
+		Vout: []TXOutput{{Value: 7, PubKeyHash: []byte{8, 9}}, {Value: 3, PubKeyHash: []byte{10}}},
+	}
+	if hash := hex.EncodeToString(tx.Hash()); hash != "2e125c9c3650ad4d183a41b18209307dea2f416be53e4e74b1969eb8c4122d59" {
+		t.Fatalf("Legacy transaction hash changed: %s", hash)
+	}
+	if hash := hex.EncodeToString(tx.TrimmedCopy().Hash()); hash != "296acc93ef6eb0908e4cd7929846aefbfe9863e6017dacb86122a885b405aa95" {
+		t.Fatalf("Legacy signature hash changed: %s", hash)
+	}
+}
@@ -0,0 +1,347 @@
+// Package light_client implements a node which only stores and validates block headers. It tracks the outputs
+// paying a set of watched scripts by checking the compact filter of every block and downloading only the blocks
+// whose filters match, so that it never stores the full blockchain or UTXO set.
+//
+// Headers are validated like full blocks' headers (they must connect, have an active version, meet the proof of work
+// target and have valid timestamps), but the transactions of the blocks they commit to aren't, so the client trusts
+// that the chain with the most headers only contains valid transactions. Filter headers are taken from the peer the
+// client syncs with, and each filter is checked against them.
+package light_client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+
+	"github.com/pedrogomes29/blockchain_node/block_filter"
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+const HEADER_PREFIX string = "header:"
+const HEIGHT_PREFIX string = "height:"
+const FILTER_HEADER_PREFIX string = "cfheader:"
+
+// hash of the last block of the best chain
+const TIP_KEY string = "l"
+
+// how many hashes are one block apart at the start of a locator, after which the gaps double
+const denseLocatorHashes = 10
+
+type LightClient struct {
+	HeadersDB *leveldb.DB
+	Params    *chain_params.Params //consensus parameters of the network the headers belong to
+	watched   map[string]bool      //filter elements of the watched scripts
+}
+
+func headerKey(blockHash []byte) []byte {
+	return append([]byte(HEADER_PREFIX), blockHash...)
+}
+
+func heightKey(height int) []byte {
+	return binary.BigEndian.AppendUint32([]byte(HEIGHT_PREFIX), uint32(height))
+}
+
+func filterHeaderKey(blockHash []byte) []byte {
+	return append([]byte(FILTER_HEADER_PREFIX), blockHash...)
+}
+
+// opens (or creates) the headers database of the network with the given params stored in dataDir, watching outputs
+// paying to the given scripts
+func OpenLightClient(dataDir string, watchedScripts [][]byte, params *chain_params.Params) *LightClient {
+	headersDB, err := leveldb.OpenFile(filepath.Join(dataDir, "headers"), nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	_, err = headersDB.Get([]byte(TIP_KEY), nil)
+	if err == leveldb.ErrNotFound {
+		fmt.Println("Headers not found. Creating...")
+		err = headersDB.Put([]byte(TIP_KEY), []byte{}, nil)
+		if err != nil {
+			log.Panic(err)
+		}
+	} else if err != nil {
+		log.Panic(err)
+	}
+
+	err = blockchain.VerifyNetwork(headersDB, params)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = headersDB.Put([]byte(blockchain.NETWORK_KEY), []byte(params.Name), nil)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	client := &LightClient{
+		HeadersDB: headersDB,
+		Params:    params,
+		watched:   make(map[string]bool),
+	}
+	for _, scriptPubKey := range watchedScripts {
+		client.watched[string(block_filter.ScriptElement(scriptPubKey))] = true
+	}
+
+	err = client.syncWatchedSet()
+	if err != nil {
+		log.Panic(err)
+	}
+	return client
+}
+
+func (client *LightClient) Close() error {
+	return client.HeadersDB.Close()
+}
+
+func (client *LightClient) TipHash() []byte {
+	tipHash, err := client.HeadersDB.Get([]byte(TIP_KEY), nil)
+	if err != nil {
+		log.Panic(err)
+	}
+	return tipHash
+}
+
+// height of the best chain, or -1 if no headers are known
+func (client *LightClient) Height() int {
+	tipHash := client.TipHash()
+	if len(tipHash) == 0 {
+		return -1
+	}
+	header, err := client.GetHeader(tipHash)
+	if err != nil {
+		log.Panic(err)
+	}
+	return header.Height
+}
+
+func (client *LightClient) GetHeader(blockHash []byte) (blockchain.BlockHeader, error) {
+	headerBytes, err := client.HeadersDB.Get(headerKey(blockHash), nil)
+	if err == leveldb.ErrNotFound {
+		return blockchain.BlockHeader{}, &blockchain_errors.ErrBlockNotFound{}
+	}
+	if err != nil {
+		return blockchain.BlockHeader{}, err
+	}
+	return blockchain.DecodeBlockHeader(headerBytes)
+}
+
+// hash of the block of the best chain at the given height
+func (client *LightClient) HashAtHeight(height int) ([]byte, error) {
+	blockHash, err := client.HeadersDB.Get(heightKey(height), nil)
+	if err == leveldb.ErrNotFound || (err == nil && height > client.Height()) {
+		return nil, &blockchain_errors.ErrBlockNotFound{}
+	}
+	return blockHash, err
+}
+
+// hashes of blocks of the best chain from the tip back to the genesis block, which the peer answers from the first
+// one it knows. they're one block apart near the tip and exponentially further apart afterwards
+func (client *LightClient) Locator() [][]byte {
+	var locator [][]byte
+	step := 1
+	for height := client.Height(); height >= 0; height -= step {
+		blockHash, err := client.HashAtHeight(height)
+		if err != nil {
+			log.Panic(err)
+		}
+		locator = append(locator, blockHash)
+		if len(locator) >= denseLocatorHashes {
+			step *= 2
+		}
+		if height > 0 && height-step < 0 {
+			step = height //always ends at the genesis block
+		}
+	}
+	return locator
+}
+
+// median timestamp of the last MedianTimeSpan headers ending at (and including) the given block
+func (client *LightClient) medianTimePast(blockHash []byte) (int64, error) {
+	var timestamps []int64
+	for i := 0; i < blockchain.MedianTimeSpan && len(blockHash) > 0; i++ {
+		header, err := client.GetHeader(blockHash)
+		if err != nil {
+			return 0, err
+		}
+		timestamps = append(timestamps, header.Timestamp)
+		blockHash = header.PrevBlockHeaderHash
+	}
+	return blockchain.MedianTimestamp(timestamps), nil
+}
+
+// checks a header whose parent is known (or which is a genesis block), like a full node checks a block's header
+func (client *LightClient) verifyHeader(header blockchain.BlockHeader) error {
+	prevHeight := -1
+	if len(header.PrevBlockHeaderHash) > 0 {
+		prevHeader, err := client.GetHeader(header.PrevBlockHeaderHash)
+		if err != nil {
+			return &blockchain_errors.ErrOrphanBlock{}
+		}
+		prevHeight = prevHeader.Height
+	}
+	if header.Height != prevHeight+1 {
+		return &blockchain_errors.ErrOrphanBlock{}
+	}
+	if err := header.VerifyVersion(client.Params); err != nil {
+		return err
+	}
+	if !header.ValidateNonce() {
+		return &blockchain_errors.ErrInvalidProofOfWork{}
+	}
+	medianTimePast, err := client.medianTimePast(header.PrevBlockHeaderHash)
+	if err != nil {
+		return err
+	}
+	return header.VerifyTimestamp(medianTimePast)
+}
+
+// validates and stores headers, each the child of the previous one, and switches to the chain they end if it's
+// longer than the best chain. headers before the first invalid one are kept
+func (client *LightClient) AddHeaders(headers []blockchain.BlockHeader) error {
+	var headersErr error
+	var lastHeader *blockchain.BlockHeader
+	for i, header := range headers {
+		if i > 0 && !bytes.Equal(header.PrevBlockHeaderHash, headers[i-1].Hash()) {
+			headersErr = &blockchain_errors.ErrOrphanBlock{}
+			break
+		}
+		blockHash := header.Hash()
+		if _, err := client.GetHeader(blockHash); err != nil {
+			if err := client.verifyHeader(header); err != nil {
+				headersErr = err
+				break
+			}
+			if err := client.HeadersDB.Put(headerKey(blockHash), header.Serialize(), nil); err != nil {
+				return err
+			}
+		}
+		lastHeader = &headers[i]
+	}
+
+	if lastHeader != nil && lastHeader.Height > client.Height() {
+		if err := client.setTip(*lastHeader); err != nil {
+			return err
+		}
+	}
+	return headersErr
+}
+
+// makes the chain ending at the given header the best chain, rescanning the watched outputs if blocks which were
+// already scanned are no longer part of it
+func (client *LightClient) setTip(tip blockchain.BlockHeader) error {
+	batch := new(leveldb.Batch)
+	height := client.Height()
+	header := tip
+	forkHeight := -1
+	for {
+		blockHash := header.Hash()
+		bestHash, err := client.HeadersDB.Get(heightKey(header.Height), nil)
+		if err == nil && bytes.Equal(bestHash, blockHash) && header.Height <= height {
+			forkHeight = header.Height
+			break
+		}
+		batch.Put(heightKey(header.Height), blockHash)
+		if len(header.PrevBlockHeaderHash) == 0 {
+			break
+		}
+		header, err = client.GetHeader(header.PrevBlockHeaderHash)
+		if err != nil {
+			return err
+		}
+	}
+	batch.Put([]byte(TIP_KEY), tip.Hash())
+
+	if client.ScannedHeight() > forkHeight {
+		client.resetWallet(batch)
+	}
+	return client.HeadersDB.Write(batch, nil)
+}
+
+func (client *LightClient) GetFilterHeader(blockHash []byte) ([]byte, error) {
+	filterHeader, err := client.HeadersDB.Get(filterHeaderKey(blockHash), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, &blockchain_errors.ErrBlockNotFound{}
+	}
+	return filterHeader, err
+}
+
+// filter header of the block before the one at the given height
+func (client *LightClient) prevFilterHeader(height int) ([]byte, error) {
+	if height == 0 {
+		return block_filter.GenesisPrevFilterHeader, nil
+	}
+	prevHash, err := client.HashAtHeight(height - 1)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetFilterHeader(prevHash)
+}
+
+// stores the filter headers of the blocks of the best chain ending at the block with hash stopHash, as answered by a
+// peer
+func (client *LightClient) AddFilterHeaders(stopHash []byte, filterHeaders [][]byte) error {
+	stopHeader, err := client.GetHeader(stopHash)
+	if err != nil {
+		return err
+	}
+	startHeight := stopHeader.Height - len(filterHeaders) + 1
+	if len(filterHeaders) == 0 || startHeight < 0 {
+		return &blockchain_errors.ErrInvalidFilterRange{}
+	}
+
+	batch := new(leveldb.Batch)
+	for i, filterHeader := range filterHeaders {
+		blockHash, err := client.HashAtHeight(startHeight + i)
+		if err != nil {
+			return err
+		}
+		batch.Put(filterHeaderKey(blockHash), filterHeader)
+	}
+	lastHash, err := client.HashAtHeight(stopHeader.Height)
+	if err != nil || !bytes.Equal(lastHash, stopHash) {
+		return &blockchain_errors.ErrInvalidFilterRange{}
+	}
+	return client.HeadersDB.Write(batch, nil)
+}
+
+// checks that a filter received for the block at the given height is the one committed to by its filter header
+func (client *LightClient) VerifyFilter(height int, filter *block_filter.Filter) error {
+	blockHash, err := client.HashAtHeight(height)
+	if err != nil {
+		return err
+	}
+	filterHeader, err := client.GetFilterHeader(blockHash)
+	if err != nil {
+		return err
+	}
+	prevFilterHeader, err := client.prevFilterHeader(height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(block_filter.FilterHeader(filter.Hash(), prevFilterHeader), filterHeader) {
+		return &blockchain_errors.ErrInvalidFilter{}
+	}
+	return nil
+}
+
+// identifies the watched set, so that the wallet is rescanned when it changes between runs
+func (client *LightClient) watchedSetHash() []byte {
+	elements := make([]string, 0, len(client.watched))
+	for element := range client.watched {
+		elements = append(elements, element)
+	}
+	slices.Sort(elements)
+	hasher := sha256.New()
+	for _, element := range elements {
+		hasher.Write(binary.AppendUvarint(nil, uint64(len(element))))
+		hasher.Write([]byte(element))
+	}
+	return hasher.Sum(nil)
+}
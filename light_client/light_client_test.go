@@ -0,0 +1,147 @@
+package light_client
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// pays to a script hash, whose outputs are watched by their whole script rather than by a pubkey hash
+var testAddress = transactions.ScriptHashAddress([]byte("redeem script"))
+
+// mines a chain of blocks with almost no work, each paying its coinbase to the test address
+func mineTestChain(t *testing.T, prevHeader *blockchain.BlockHeader, numBlocks int) []*blockchain.Block {
+	target := blockchain.Target
+	t.Cleanup(func() { blockchain.Target = target })
+	blockchain.Target = new(big.Int).Lsh(big.NewInt(1), 255)
+
+	var blocks []*blockchain.Block
+	prevHash, height, timestamp := []byte{}, 0, int64(1)
+	if prevHeader != nil {
+		prevHash, height, timestamp = prevHeader.Hash(), prevHeader.Height+1, prevHeader.Timestamp+1
+	}
+	for i := 0; i < numBlocks; i++ {
+		block := blockchain.NewBlock([]*transactions.Transaction{transactions.NewCoinbaseTX(testAddress, height)}, prevHash, height, &chain_params.MainNetParams)
+		block.Header.Timestamp = timestamp
+		if !block.POW(make(chan struct{})) {
+			t.Fatalf("Error mining block %d", height)
+		}
+		blocks = append(blocks, block)
+		prevHash, height, timestamp = block.GetBlockHeaderHash(), height+1, timestamp+1
+	}
+	return blocks
+}
+
+func headersOf(blocks []*blockchain.Block) []blockchain.BlockHeader {
+	headers := make([]blockchain.BlockHeader, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header
+	}
+	return headers
+}
+
+func TestAddHeaders(t *testing.T) {
+	client := OpenLightClient(t.TempDir(), nil, &chain_params.MainNetParams)
+	defer client.Close()
+
+	blocks := mineTestChain(t, nil, 20)
+	if err := client.AddHeaders(headersOf(blocks[5:])); !errors.Is(err, &blockchain_errors.ErrOrphanBlock{}) {
+		t.Fatalf("Headers not connecting to a known header were added: %v", err)
+	}
+	if err := client.AddHeaders(headersOf(blocks)); err != nil {
+		t.Fatalf("Error adding headers: %s", err)
+	}
+	if client.Height() != 19 || !bytes.Equal(client.TipHash(), blocks[19].GetBlockHeaderHash()) {
+		t.Fatalf("Tip is at height %d, expected the last header", client.Height())
+	}
+
+	//the locator starts at the tip, is dense near it and always ends at the genesis block
+	locator := client.Locator()
+	if !bytes.Equal(locator[0], blocks[19].GetBlockHeaderHash()) || !bytes.Equal(locator[len(locator)-1], blocks[0].GetBlockHeaderHash()) {
+		t.Fatalf("Locator doesn't go from the tip to the genesis block")
+	}
+	if len(locator) >= len(blocks) {
+		t.Fatalf("Locator has %d hashes, expected fewer than the %d blocks", len(locator), len(blocks))
+	}
+
+	invalid := mineTestChain(t, &blocks[19].Header, 2)
+	invalid[0].Header.Nonce++
+	for invalid[0].Header.ValidateNonce() {
+		invalid[0].Header.Nonce++
+	}
+	if err := client.AddHeaders(headersOf(invalid[:1])); !errors.Is(err, &blockchain_errors.ErrInvalidProofOfWork{}) {
+		t.Fatalf("Header without proof of work was added: %v", err)
+	}
+
+	tooOld := mineTestChain(t, &blocks[19].Header, 1)[0]
+	tooOld.Header.Timestamp = blocks[10].Header.Timestamp
+	for tooOld.Header.Nonce = 0; !tooOld.Header.ValidateNonce(); tooOld.Header.Nonce++ {
+	}
+	if err := client.AddHeaders(headersOf([]*blockchain.Block{tooOld})); !errors.Is(err, &blockchain_errors.ErrTimestampTooOld{}) {
+		t.Fatalf("Header older than the median time past was added: %v", err)
+	}
+	if client.Height() != 19 {
+		t.Fatalf("Tip moved to height %d after invalid headers", client.Height())
+	}
+}
+
+// a longer fork replaces the best chain, and blocks scanned on the old chain are scanned again
+func TestReorgHeaders(t *testing.T) {
+	client := OpenLightClient(t.TempDir(), nil, &chain_params.MainNetParams)
+	defer client.Close()
+
+	blocks := mineTestChain(t, nil, 5)
+	if err := client.AddHeaders(headersOf(blocks)); err != nil {
+		t.Fatalf("Error adding headers: %s", err)
+	}
+	for height := 0; height < 5; height++ {
+		if err := client.SkipBlock(height); err != nil {
+			t.Fatalf("Error scanning block %d: %s", height, err)
+		}
+	}
+
+	fork := mineTestChain(t, &blocks[1].Header, 4)
+	if err := client.AddHeaders(headersOf(fork)); err != nil {
+		t.Fatalf("Error adding fork headers: %s", err)
+	}
+	if client.Height() != 5 || !bytes.Equal(client.TipHash(), fork[3].GetBlockHeaderHash()) {
+		t.Fatalf("Longer fork didn't become the best chain")
+	}
+	forkHash, _ := client.HashAtHeight(2)
+	if !bytes.Equal(forkHash, fork[0].GetBlockHeaderHash()) {
+		t.Fatalf("Best chain at height 2 isn't the fork's block")
+	}
+	if client.ScannedHeight() != -1 {
+		t.Fatalf("Blocks scanned on the old chain weren't forgotten, scanned height is %d", client.ScannedHeight())
+	}
+}
+
+// outputs of a watched script are found by that script, and scripts which aren't watched can't be queried
+func TestFindUTXOs(t *testing.T) {
+	watchedScript, _ := transactions.AddressScript(testAddress)
+	client := OpenLightClient(t.TempDir(), [][]byte{watchedScript}, &chain_params.MainNetParams)
+	defer client.Close()
+
+	blocks := mineTestChain(t, nil, 1)
+	if err := client.AddHeaders(headersOf(blocks)); err != nil {
+		t.Fatalf("Error adding headers: %s", err)
+	}
+	if err := client.ProcessBlock(blocks[0]); err != nil {
+		t.Fatalf("Error scanning block: %s", err)
+	}
+	utxos, err := client.FindUTXOs(watchedScript)
+	if err != nil || len(utxos) != 1 {
+		t.Fatalf("Found %d outputs of the watched script: %v", len(utxos), err)
+	}
+
+	otherScript, _ := transactions.AddressScript(transactions.PubKeyHashAddress(make([]byte, 20)))
+	if _, err := client.FindUTXOs(otherScript); !errors.Is(err, &blockchain_errors.ErrAddressNotWatched{}) {
+		t.Fatalf("Outputs of a script which isn't watched were returned: %v", err)
+	}
+}
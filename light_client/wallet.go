@@ -0,0 +1,213 @@
+package light_client
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/pedrogomes29/blockchain_node/block_filter"
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// unspent outputs paying to the watched scripts, by txid, encoded like the full node's chainstate
+const UTXO_PREFIX string = "utxo:"
+
+// hash of the last block of the best chain whose filter was checked
+const SCANNED_KEY string = "scanned"
+
+// identifies the watched set the unspent outputs were found for
+const WATCHED_KEY string = "watched"
+
+func utxosKey(txHash []byte) []byte {
+	return append([]byte(UTXO_PREFIX), txHash...)
+}
+
+// height of the last block whose filter was checked, or -1 if no filter was
+func (client *LightClient) ScannedHeight() int {
+	scannedHash, err := client.HeadersDB.Get([]byte(SCANNED_KEY), nil)
+	if err == leveldb.ErrNotFound {
+		return -1
+	}
+	if err != nil {
+		log.Panic(err)
+	}
+	header, err := client.GetHeader(scannedHash)
+	if err != nil {
+		log.Panic(err)
+	}
+	return header.Height
+}
+
+// forgets the unspent outputs found so far, so that every block is scanned again
+func (client *LightClient) resetWallet(batch *leveldb.Batch) {
+	iter := client.HeadersDB.NewIterator(util.BytesPrefix([]byte(UTXO_PREFIX)), nil)
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		log.Panic(err)
+	}
+	batch.Delete([]byte(SCANNED_KEY))
+}
+
+// rescans every block if the watched set changed since the unspent outputs were found
+func (client *LightClient) syncWatchedSet() error {
+	watchedSetHash := client.watchedSetHash()
+	storedHash, err := client.HeadersDB.Get([]byte(WATCHED_KEY), nil)
+	if err == nil && bytes.Equal(storedHash, watchedSetHash) {
+		return nil
+	}
+	if err != nil && err != leveldb.ErrNotFound {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	client.resetWallet(batch)
+	batch.Put([]byte(WATCHED_KEY), watchedSetHash)
+	return client.HeadersDB.Write(batch, nil)
+}
+
+func (client *LightClient) isWatched(out transactions.TXOutput) bool {
+	return !out.IsData() && len(out.ScriptPubKey) > 0 && client.watched[string(block_filter.ScriptElement(out.ScriptPubKey))]
+}
+
+// filter elements of the watched scripts and of the outpoints of their unspent outputs, so that blocks paying to
+// or spending from them match
+func (client *LightClient) Elements() [][]byte {
+	var elements [][]byte
+	for element := range client.watched {
+		elements = append(elements, []byte(element))
+	}
+
+	iter := client.HeadersDB.NewIterator(util.BytesPrefix([]byte(UTXO_PREFIX)), nil)
+	for iter.Next() {
+		txHash := iter.Key()[len(UTXO_PREFIX):]
+		for outIndex := range transactions.DeserializeUTXOs(iter.Value()) {
+			elements = append(elements, block_filter.OutPointElement(txHash, outIndex))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		log.Panic(err)
+	}
+	return elements
+}
+
+// whether the filter of the block at the given height may match any watched script or unspent output
+func (client *LightClient) MatchFilter(height int, filter *block_filter.Filter) (bool, error) {
+	blockHash, err := client.HashAtHeight(height)
+	if err != nil {
+		return false, err
+	}
+	return filter.MatchAny(block_filter.FilterKey(blockHash), client.Elements())
+}
+
+// records that the block at the given height, whose filter matched nothing, was scanned
+func (client *LightClient) SkipBlock(height int) error {
+	if height != client.ScannedHeight()+1 {
+		return &blockchain_errors.ErrOrphanBlock{}
+	}
+	blockHash, err := client.HashAtHeight(height)
+	if err != nil {
+		return err
+	}
+	return client.HeadersDB.Put([]byte(SCANNED_KEY), blockHash, nil)
+}
+
+// updates the unspent outputs with the next block to be scanned, which must be the block of the best chain at that
+// height and contain the transactions its header commits to
+func (client *LightClient) ProcessBlock(block *blockchain.Block) error {
+	blockHash, err := client.HashAtHeight(client.ScannedHeight() + 1)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(block.GetBlockHeaderHash(), blockHash) {
+		return &blockchain_errors.ErrOrphanBlock{}
+	}
+	if err := block.VerifyTxCommitments(); err != nil {
+		return err
+	}
+
+	//outputs changed by this block, so that transactions spending outputs of earlier ones in the block are handled
+	changedUTXOs := make(map[string]transactions.UTXOs)
+	getUTXOs := func(txHash []byte) (transactions.UTXOs, error) {
+		if utxos, ok := changedUTXOs[string(txHash)]; ok {
+			return utxos, nil
+		}
+		utxoBytes, err := client.HeadersDB.Get(utxosKey(txHash), nil)
+		if err == leveldb.ErrNotFound {
+			return transactions.UTXOs{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return transactions.DecodeUTXOs(utxoBytes)
+	}
+
+	for _, tx := range block.Transactions {
+		if !tx.IsCoinbase {
+			for _, vin := range tx.Vin {
+				utxos, err := getUTXOs(vin.Txid)
+				if err != nil {
+					return err
+				}
+				if _, ok := utxos[vin.OutIndex]; ok {
+					delete(utxos, vin.OutIndex)
+					changedUTXOs[string(vin.Txid)] = utxos
+				}
+			}
+		}
+
+		txHash := tx.Hash()
+		for outIndex, out := range tx.Vout {
+			if !client.isWatched(out) {
+				continue
+			}
+			utxos, err := getUTXOs(txHash)
+			if err != nil {
+				return err
+			}
+			utxos[outIndex] = out
+			changedUTXOs[string(txHash)] = utxos
+		}
+	}
+
+	batch := new(leveldb.Batch)
+	for txHash, utxos := range changedUTXOs {
+		if len(utxos) == 0 {
+			batch.Delete(utxosKey([]byte(txHash)))
+		} else {
+			batch.Put(utxosKey([]byte(txHash)), utxos.Serialize())
+		}
+	}
+	batch.Put([]byte(SCANNED_KEY), blockHash)
+	return client.HeadersDB.Write(batch, nil)
+}
+
+// unspent outputs locked with the given script, which must be watched since others aren't tracked
+func (client *LightClient) FindUTXOs(scriptPubKey []byte) ([]transactions.TXOutput, error) {
+	if !client.watched[string(block_filter.ScriptElement(scriptPubKey))] {
+		return nil, &blockchain_errors.ErrAddressNotWatched{}
+	}
+
+	var UTXOs []transactions.TXOutput
+	iter := client.HeadersDB.NewIterator(util.BytesPrefix([]byte(UTXO_PREFIX)), nil)
+	for iter.Next() {
+		txUTXOs, err := transactions.DecodeUTXOs(iter.Value())
+		if err != nil {
+			iter.Release()
+			return nil, err
+		}
+		for _, UTXO := range txUTXOs {
+			if bytes.Equal(UTXO.ScriptPubKey, scriptPubKey) {
+				UTXOs = append(UTXOs, UTXO)
+			}
+		}
+	}
+	iter.Release()
+	return UTXOs, iter.Error()
+}
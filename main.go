@@ -7,29 +7,39 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/light_client"
 	"github.com/pedrogomes29/blockchain_node/server"
+	"github.com/pedrogomes29/blockchain_node/transactions"
 )
 
 func main() {
 	minerAddr := flag.String("miner", "", "Miner's wallet address")
 	seeds := flag.String("seeds", "", "Comma-separated list of seed addresses")
+	txIndex := flag.Bool("txindex", false, "Maintain an index of all confirmed transactions by hash")
+	network := flag.String("network", chain_params.MainNetParams.Name, "Network to run on (mainnet, testnet or legacy)")
+	dataCarrierSize := flag.Int("datacarriersize", transactions.DefaultMaxDataCarrierSize, "Maximum number of bytes carried by data outputs accepted into the memory pool")
+	sigCacheSize := flag.Int("sigcachesize", transactions.DefaultSigCacheSize, "Number of verified signatures remembered, so that they aren't verified again when included in a block")
+	light := flag.Bool("light", false, "Only sync block headers, tracking the watched addresses with block filters")
+	watch := flag.String("watch", "", "Comma-separated list of addresses tracked in light mode")
 	flag.Parse()
 
 	// Check if minerAddr is set
-	if *minerAddr == "" {
+	if *minerAddr == "" && !*light {
 		fmt.Println("Miner's wallet address is required.")
 		fmt.Println("Usage:")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	if *minerAddr == "" {
-		fmt.Println("Miner's wallet address is required.")
-		fmt.Println("Usage:")
-		flag.PrintDefaults()
+	params, err := chain_params.NetworkParams(*network)
+	if err != nil {
+		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
+	transactions.SignatureCache = transactions.NewSigCache(*sigCacheSize)
+
 	var seedAddresses []string
 	if *seeds != "" {
 		seedAddresses = strings.Split(*seeds, ",")
@@ -46,6 +56,23 @@ func main() {
 		}
 	}
 
-	server := server.NewServer(*minerAddr, seedAddresses)
+	if *light {
+		var watchedScripts [][]byte
+		if *watch != "" {
+			for _, address := range strings.Split(*watch, ",") {
+				scriptPubKey, err := transactions.AddressScript(address)
+				if err != nil {
+					fmt.Printf("Invalid watched address: %s\n", address)
+					os.Exit(1)
+				}
+				watchedScripts = append(watchedScripts, scriptPubKey)
+			}
+		}
+		lightServer := server.NewLightServer(light_client.OpenLightClient(".", watchedScripts, params), seedAddresses)
+		lightServer.Run()
+		return
+	}
+
+	server := server.NewServer(*minerAddr, seedAddresses, *txIndex, *dataCarrierSize, params)
 	server.Run()
 }
@@ -9,12 +9,16 @@ import (
 	"github.com/pedrogomes29/blockchain_node/transactions"
 )
 
+// transactions waiting for their inputs' relative lock times are re-verified on every block, so only this many are kept
+const MaxWaitingTxs = 1000
+
 type inputUTXOs map[int]string //maps utxo to transaction spending that utxo
 
 type MemoryPool struct {
 	txQueue    *list.List
 	txIndex    map[string]*list.Element
-	spentUTXOs map[string]inputUTXOs //maps transaction id to that transaction's UTXOs being spent
+	spentUTXOs map[string]inputUTXOs                //maps transaction id to that transaction's UTXOs being spent
+	waitingTxs map[string]*transactions.Transaction //transactions waiting for their inputs' relative lock times
 	mux        *sync.RWMutex
 }
 
@@ -23,6 +27,7 @@ func NewMemoryPool() *MemoryPool {
 		txQueue:    list.New(),
 		txIndex:    make(map[string]*list.Element),
 		spentUTXOs: make(map[string]inputUTXOs),
+		waitingTxs: make(map[string]*transactions.Transaction),
 		mux:        &sync.RWMutex{},
 	}
 }
@@ -178,3 +183,64 @@ func (mp *MemoryPool) GetTxWithLock(txHash []byte) *transactions.Transaction {
 	defer mp.mux.RUnlock()
 	return mp.getTx(txHash)
 }
+
+func (mp *MemoryPool) AddWaitingTxWithLock(tx *transactions.Transaction) error {
+	mp.mux.Lock()
+	defer mp.mux.Unlock()
+
+	txHashString := hex.EncodeToString(tx.Hash())
+	if _, exists := mp.waitingTxs[txHashString]; !exists && len(mp.waitingTxs) >= MaxWaitingTxs {
+		return &blockchain_errors.ErrWaitingPoolFull{}
+	}
+	mp.waitingTxs[txHashString] = tx
+	return nil
+}
+
+func (mp *MemoryPool) GetWaitingTxWithLock(txHash []byte) *transactions.Transaction {
+	mp.mux.RLock()
+	defer mp.mux.RUnlock()
+	return mp.waitingTxs[hex.EncodeToString(txHash)]
+}
+
+// removes and returns every transaction waiting for its inputs' relative lock times
+func (mp *MemoryPool) TakeWaitingTxsWithLock() []*transactions.Transaction {
+	mp.mux.Lock()
+	defer mp.mux.Unlock()
+
+	waitingTxs := make([]*transactions.Transaction, 0, len(mp.waitingTxs))
+	for _, tx := range mp.waitingTxs {
+		waitingTxs = append(waitingTxs, tx)
+	}
+	mp.waitingTxs = make(map[string]*transactions.Transaction)
+	return waitingTxs
+}
+
+// returns the queued transactions which have inputs with relative lock times
+func (mp *MemoryPool) GetTxsWithRelativeLockTimesWithLock() []*transactions.Transaction {
+	mp.mux.RLock()
+	defer mp.mux.RUnlock()
+
+	var txs []*transactions.Transaction
+	for txQueueElement := mp.txQueue.Front(); txQueueElement != nil; txQueueElement = txQueueElement.Next() {
+		tx := txQueueElement.Value.(*transactions.Transaction)
+		if tx.HasRelativeLockTimes() {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+// returns every transaction in the memory pool, queued or waiting for its inputs' relative lock times
+func (mp *MemoryPool) GetAllTxsWithLock() []*transactions.Transaction {
+	mp.mux.RLock()
+	defer mp.mux.RUnlock()
+
+	txs := make([]*transactions.Transaction, 0, mp.txQueue.Len()+len(mp.waitingTxs))
+	for txQueueElement := mp.txQueue.Front(); txQueueElement != nil; txQueueElement = txQueueElement.Next() {
+		txs = append(txs, txQueueElement.Value.(*transactions.Transaction))
+	}
+	for _, tx := range mp.waitingTxs {
+		txs = append(txs, tx)
+	}
+	return txs
+}
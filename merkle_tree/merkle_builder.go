@@ -0,0 +1,90 @@
+package merkle_tree
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// computes a merkle root from leaf hashes appended one at a time, keeping only the root of each complete subtree,
+// so that appending is O(log n) and no nodes are allocated. it computes the same root as NewMerkleTree over the data
+// whose hashes are appended
+type MerkleBuilder struct {
+	subtreeRoots [][sha256.Size]byte //subtreeRoots[level] is pending if bit level of numLeaves is set
+	numLeaves    int
+	mutated      bool
+	pair         [2 * sha256.Size]byte //buffer for the concatenation of two hashes
+}
+
+func NewMerkleBuilder() *MerkleBuilder {
+	return &MerkleBuilder{}
+}
+
+func (mb *MerkleBuilder) hashPair(left, right *[sha256.Size]byte) [sha256.Size]byte {
+	if *left == *right {
+		mb.mutated = true
+	}
+	copy(mb.pair[:sha256.Size], left[:])
+	copy(mb.pair[sha256.Size:], right[:])
+	return sha256.Sum256(mb.pair[:])
+}
+
+func (mb *MerkleBuilder) Append(leafHash []byte) {
+	var hash [sha256.Size]byte
+	copy(hash[:], leafHash)
+
+	//merges the complete subtrees of the same size as the one ending in the new leaf
+	level := 0
+	for ; mb.numLeaves>>level&1 == 1; level++ {
+		hash = mb.hashPair(&mb.subtreeRoots[level], &hash)
+	}
+	if level == len(mb.subtreeRoots) {
+		mb.subtreeRoots = append(mb.subtreeRoots, hash)
+	} else {
+		mb.subtreeRoots[level] = hash
+	}
+	mb.numLeaves++
+}
+
+func (mb *MerkleBuilder) NumLeaves() int {
+	return mb.numLeaves
+}
+
+// root of the leaves appended so far (nil if there are none) and whether the tree has identical siblings, like
+// MerkleTree.Mutated. leaves can still be appended afterwards
+func (mb *MerkleBuilder) Root() ([]byte, bool) {
+	if mb.numLeaves == 0 {
+		return nil, false
+	}
+
+	mutated := mb.mutated
+	//the smallest pending subtree is the last node of its level, so it's paired with itself. going up, the node
+	//is paired with the pending subtree to its left, if any, or else with itself again
+	lowest := 0
+	for mb.numLeaves>>lowest&1 == 0 {
+		lowest++
+	}
+	hash := mb.subtreeRoots[lowest]
+	for level := lowest; level < treeHeight(mb.numLeaves); level++ {
+		if level > lowest && mb.numLeaves>>level&1 == 1 {
+			if mb.subtreeRoots[level] == hash {
+				mutated = true
+			}
+			copy(mb.pair[:sha256.Size], mb.subtreeRoots[level][:])
+			copy(mb.pair[sha256.Size:], hash[:])
+		} else {
+			copy(mb.pair[:sha256.Size], hash[:])
+			copy(mb.pair[sha256.Size:], hash[:])
+		}
+		hash = sha256.Sum256(mb.pair[:])
+	}
+	return bytes.Clone(hash[:]), mutated
+}
+
+// merkle root of the given leaf hashes, e.g. a block's txids, without building the tree
+func MerkleRoot(leafHashes [][]byte) ([]byte, bool) {
+	mb := MerkleBuilder{subtreeRoots: make([][sha256.Size]byte, 0, treeHeight(len(leafHashes))+1)}
+	for _, leafHash := range leafHashes {
+		mb.Append(leafHash)
+	}
+	return mb.Root()
+}
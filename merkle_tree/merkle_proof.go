@@ -0,0 +1,56 @@
+package merkle_tree
+
+import (
+	"bytes"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+// authentication path of a leaf, i.e. the sibling of each node from the leaf up to (but excluding) the root
+type MerkleProof struct {
+	LeafIndex int
+	NumLeaves int
+	Siblings  [][]byte
+}
+
+// proof of the leaf at the given index of the tree with the given leaf hashes
+func NewMerkleProof(leafHashes [][]byte, leafIndex int) (*MerkleProof, error) {
+	if leafIndex < 0 || leafIndex >= len(leafHashes) {
+		return nil, &blockchain_errors.ErrLeafIndexOutOfRange{}
+	}
+
+	height := treeHeight(len(leafHashes))
+	siblings := make([][]byte, height)
+	for level := 0; level < height; level++ {
+		siblingPosition := leafIndex>>level ^ 1
+		if siblingPosition >= treeWidth(len(leafHashes), level) {
+			//the last node of an odd level is paired with itself
+			siblingPosition = leafIndex >> level
+		}
+		siblings[level] = nodeHash(level, siblingPosition, leafHashes)
+	}
+
+	return &MerkleProof{LeafIndex: leafIndex, NumLeaves: len(leafHashes), Siblings: siblings}, nil
+}
+
+// whether the proof shows that the leaf with the given hash is in the tree with the given root
+func VerifyMerkleProof(root, leafHash []byte, proof *MerkleProof) bool {
+	if proof.LeafIndex < 0 || proof.LeafIndex >= proof.NumLeaves || len(proof.Siblings) != treeHeight(proof.NumLeaves) {
+		return false
+	}
+
+	hash := leafHash
+	for level, sibling := range proof.Siblings {
+		position := proof.LeafIndex >> level
+		if position%2 == 0 {
+			//the last node of an odd level is paired with itself
+			if position == treeWidth(proof.NumLeaves, level)-1 && !bytes.Equal(sibling, hash) {
+				return false
+			}
+			hash = hashPair(hash, sibling)
+		} else {
+			hash = hashPair(sibling, hash)
+		}
+	}
+	return bytes.Equal(hash, root)
+}
@@ -1,11 +1,16 @@
 package merkle_tree
 
 import (
+	"bytes"
 	"crypto/sha256"
 )
 
 type MerkleTree struct {
-	RootNode *MerkleNode
+	RootNode  *MerkleNode
+	NumLeaves int
+	//whether a level had two identical siblings. since the last node of an odd level is paired with itself, the same
+	//root is obtained if the last leaves are duplicated, so such a tree might be a mutation of another one
+	Mutated bool
 }
 
 type MerkleNode struct {
@@ -38,7 +43,13 @@ func NewMerkleTree(data [][]byte) *MerkleTree {
 		currentLevelNodes = append(currentLevelNodes, node)
 	}
 
+	mutated := false
 	for len(currentLevelNodes) > 1 { //if the current level has only one node, it is the merkle tree root
+		for nodeIdx := 0; nodeIdx+1 < len(currentLevelNodes); nodeIdx += 2 {
+			if bytes.Equal(currentLevelNodes[nodeIdx].Data, currentLevelNodes[nodeIdx+1].Data) {
+				mutated = true
+			}
+		}
 		currentLevelNodes = ensureEven(currentLevelNodes)
 		for currentLevelNodeIdx := 0; currentLevelNodeIdx < len(currentLevelNodes); currentLevelNodeIdx += 2 {
 			leftChild, rightChild := currentLevelNodes[currentLevelNodeIdx], currentLevelNodes[currentLevelNodeIdx+1]
@@ -48,7 +59,7 @@ func NewMerkleTree(data [][]byte) *MerkleTree {
 		currentLevelNodes = currentLevelNodes[:len(currentLevelNodes)/2]
 	}
 
-	return &MerkleTree{currentLevelNodes[0]}
+	return &MerkleTree{RootNode: currentLevelNodes[0], NumLeaves: len(data), Mutated: mutated}
 }
 
 func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
@@ -58,9 +69,7 @@ func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
 		newNodeData := sha256.Sum256(data)
 		newNode.Data = newNodeData[:]
 	} else { //at least one child, node is not a leaf
-		prevHashes := append(left.Data, right.Data...)
-		newNodeData := sha256.Sum256(prevHashes)
-		newNode.Data = newNodeData[:]
+		newNode.Data = hashPair(left.Data, right.Data)
 	}
 
 	newNode.Left = left
@@ -68,3 +77,23 @@ func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
 
 	return &newNode
 }
+
+func hashPair(left, right []byte) []byte {
+	hash := sha256.Sum256(append(append([]byte{}, left...), right...))
+	return hash[:]
+}
+
+// number of levels above the leaves in a tree with numLeaves leaves
+func treeHeight(numLeaves int) int {
+	height := 0
+	for treeWidth(numLeaves, height) > 1 {
+		height++
+	}
+	return height
+}
+
+// number of nodes at the given height (0 being the leaves) of a tree with numLeaves leaves, before the last node of
+// an odd level is duplicated
+func treeWidth(numLeaves, height int) int {
+	return (numLeaves + (1 << height) - 1) >> height
+}
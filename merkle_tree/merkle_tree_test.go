@@ -1,8 +1,11 @@
 package merkle_tree
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -69,3 +72,145 @@ func TestNewMerkleTree(t *testing.T) {
 		t.Fatalf("Root hash is incorrect")
 	}
 }
+
+func testLeaves(numLeaves int) ([][]byte, [][]byte) {
+	var data, leafHashes [][]byte
+	for i := 0; i < numLeaves; i++ {
+		datum := []byte(fmt.Sprintf("node%d", i))
+		hash := sha256.Sum256(datum)
+		data = append(data, datum)
+		leafHashes = append(leafHashes, hash[:])
+	}
+	return data, leafHashes
+}
+
+func TestMerkleProof(t *testing.T) {
+	for numLeaves := 1; numLeaves <= 9; numLeaves++ {
+		data, leafHashes := testLeaves(numLeaves)
+		mTree := NewMerkleTree(data)
+		for leafIndex := range data {
+			proof, err := NewMerkleProof(leafHashes, leafIndex)
+			if err != nil {
+				t.Fatalf("Error building proof of leaf %d of %d: %s", leafIndex, numLeaves, err)
+			}
+			if !VerifyMerkleProof(mTree.RootNode.Data, leafHashes[leafIndex], proof) {
+				t.Fatalf("Proof of leaf %d of %d is invalid", leafIndex, numLeaves)
+			}
+			otherLeaf := leafHashes[(leafIndex+1)%numLeaves]
+			if numLeaves > 1 && VerifyMerkleProof(mTree.RootNode.Data, otherLeaf, proof) {
+				t.Fatalf("Proof of leaf %d of %d verifies another leaf", leafIndex, numLeaves)
+			}
+		}
+		if _, err := NewMerkleProof(leafHashes, numLeaves); err == nil {
+			t.Fatalf("Proof of a leaf out of range was built")
+		}
+	}
+}
+
+func TestPartialMerkleTree(t *testing.T) {
+	for numLeaves := 1; numLeaves <= 9; numLeaves++ {
+		data, leafHashes := testLeaves(numLeaves)
+		root := NewMerkleTree(data).RootNode.Data
+
+		for mask := 0; mask < 1<<numLeaves; mask += 1 + mask/3 {
+			matches := make([]bool, numLeaves)
+			var wantHashes [][]byte
+			var wantIndexes []int
+			for i := range matches {
+				if mask&(1<<i) != 0 {
+					matches[i] = true
+					wantHashes = append(wantHashes, leafHashes[i])
+					wantIndexes = append(wantIndexes, i)
+				}
+			}
+
+			pmt, err := DecodePartialMerkleTree(NewPartialMerkleTree(leafHashes, matches).Serialize())
+			if err != nil {
+				t.Fatalf("Error decoding partial merkle tree: %s", err)
+			}
+			gotRoot, gotHashes, gotIndexes, err := pmt.Extract()
+			if err != nil {
+				t.Fatalf("Error extracting partial merkle tree of %d leaves: %s", numLeaves, err)
+			}
+			if !bytes.Equal(gotRoot, root) || !reflect.DeepEqual(gotHashes, wantHashes) || !reflect.DeepEqual(gotIndexes, wantIndexes) {
+				t.Fatalf("Partial merkle tree of %d leaves with matches %b doesn't match the tree", numLeaves, mask)
+			}
+		}
+	}
+
+	_, leafHashes := testLeaves(5)
+	pmt := NewPartialMerkleTree(leafHashes, []bool{false, true, false, false, false})
+	pmt.Hashes = append(pmt.Hashes, leafHashes[0])
+	if _, _, _, err := pmt.Extract(); err == nil {
+		t.Fatalf("Partial merkle tree with unused hashes was extracted")
+	}
+}
+
+// duplicating the last leaves of a level with an odd number of nodes keeps the root, which must be detected
+func TestMutatedMerkleTree(t *testing.T) {
+	data, _ := testLeaves(3)
+	mTree := NewMerkleTree(data)
+	mutatedTree := NewMerkleTree(append(data, data[2]))
+	if mTree.Mutated || !mutatedTree.Mutated {
+		t.Fatalf("Mutation wasn't detected correctly: original %v, duplicated %v", mTree.Mutated, mutatedTree.Mutated)
+	}
+	if !bytes.Equal(mTree.RootNode.Data, mutatedTree.RootNode.Data) {
+		t.Fatalf("Duplicating the last leaf changed the root")
+	}
+
+	//the duplicated pair can also be higher up the tree
+	data, _ = testLeaves(6)
+	mTree = NewMerkleTree(data)
+	mutatedTree = NewMerkleTree(append(data, data[4:6]...))
+	if mTree.Mutated || !mutatedTree.Mutated || !bytes.Equal(mTree.RootNode.Data, mutatedTree.RootNode.Data) {
+		t.Fatalf("Duplicated pair of leaves wasn't detected")
+	}
+}
+
+func TestMerkleBuilder(t *testing.T) {
+	for numLeaves := 1; numLeaves <= 40; numLeaves++ {
+		data, leafHashes := testLeaves(numLeaves)
+		//duplicating the last leaves mutates some of the trees
+		for _, numDuplicated := range []int{0, 1, 2} {
+			if numDuplicated > numLeaves {
+				continue
+			}
+			data := append(append([][]byte{}, data...), data[numLeaves-numDuplicated:]...)
+			leafHashes := append(append([][]byte{}, leafHashes...), leafHashes[numLeaves-numDuplicated:]...)
+
+			mTree := NewMerkleTree(data)
+			root, mutated := MerkleRoot(leafHashes)
+			if !bytes.Equal(root, mTree.RootNode.Data) || mutated != mTree.Mutated {
+				t.Fatalf("Streaming root of %d leaves doesn't match the tree", len(data))
+			}
+		}
+	}
+
+	//the root can be taken after every append
+	_, leafHashes := testLeaves(20)
+	mb := NewMerkleBuilder()
+	for i, leafHash := range leafHashes {
+		mb.Append(leafHash)
+		root, _ := mb.Root()
+		wantRoot, _ := MerkleRoot(leafHashes[:i+1])
+		if !bytes.Equal(root, wantRoot) {
+			t.Fatalf("Incremental root of %d leaves is incorrect", i+1)
+		}
+	}
+}
+
+func BenchmarkMerkleRoot(b *testing.B) {
+	data, leafHashes := testLeaves(4000)
+	b.Run("tree", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewMerkleTree(data)
+		}
+	})
+	b.Run("streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			MerkleRoot(leafHashes)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package merkle_tree
+
+import (
+	"bytes"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+)
+
+// proves that several leaves are in a tree while sending each hash at most once, like bitcoin's merkleblock.
+// the tree is traversed depth first: each visited node has a flag saying whether a matched leaf is below it.
+// the hash of nodes without matches (and of matched leaves) is included, while nodes with matches are descended into
+type PartialMerkleTree struct {
+	NumLeaves int
+	Hashes    [][]byte
+	Flags     []bool
+}
+
+// builds the partial tree over the given leaf hashes proving the leaves whose matches entry is true
+func NewPartialMerkleTree(leafHashes [][]byte, matches []bool) *PartialMerkleTree {
+	pmt := &PartialMerkleTree{NumLeaves: len(leafHashes)}
+	pmt.build(treeHeight(len(leafHashes)), 0, leafHashes, matches)
+	return pmt
+}
+
+func nodeHash(height, position int, leafHashes [][]byte) []byte {
+	if height == 0 {
+		return leafHashes[position]
+	}
+	left := nodeHash(height-1, position*2, leafHashes)
+	right := left
+	if position*2+1 < treeWidth(len(leafHashes), height-1) {
+		right = nodeHash(height-1, position*2+1, leafHashes)
+	}
+	return hashPair(left, right)
+}
+
+func (pmt *PartialMerkleTree) build(height, position int, leafHashes [][]byte, matches []bool) {
+	hasMatch := false
+	for leaf := position << height; leaf < (position+1)<<height && leaf < len(leafHashes); leaf++ {
+		hasMatch = hasMatch || matches[leaf]
+	}
+	pmt.Flags = append(pmt.Flags, hasMatch)
+
+	if height == 0 || !hasMatch {
+		pmt.Hashes = append(pmt.Hashes, nodeHash(height, position, leafHashes))
+		return
+	}
+	pmt.build(height-1, position*2, leafHashes, matches)
+	if position*2+1 < treeWidth(pmt.NumLeaves, height-1) {
+		pmt.build(height-1, position*2+1, leafHashes, matches)
+	}
+}
+
+// computes the root of the partial tree, returning the matched leaves' hashes and indexes.
+// the caller must compare the root with the one in the block header
+func (pmt *PartialMerkleTree) Extract() (root []byte, matchedHashes [][]byte, matchedIndexes []int, err error) {
+	if pmt.NumLeaves == 0 || len(pmt.Hashes) > pmt.NumLeaves || len(pmt.Flags) < len(pmt.Hashes) {
+		return nil, nil, nil, &blockchain_errors.ErrInvalidPartialMerkleTree{}
+	}
+
+	var flagsUsed, hashesUsed int
+	var extract func(height, position int) []byte
+	extract = func(height, position int) []byte {
+		if flagsUsed >= len(pmt.Flags) {
+			err = &blockchain_errors.ErrInvalidPartialMerkleTree{}
+			return nil
+		}
+		hasMatch := pmt.Flags[flagsUsed]
+		flagsUsed++
+
+		if height == 0 || !hasMatch {
+			if hashesUsed >= len(pmt.Hashes) {
+				err = &blockchain_errors.ErrInvalidPartialMerkleTree{}
+				return nil
+			}
+			hash := pmt.Hashes[hashesUsed]
+			hashesUsed++
+			if height == 0 && hasMatch {
+				matchedHashes = append(matchedHashes, hash)
+				matchedIndexes = append(matchedIndexes, position)
+			}
+			return hash
+		}
+
+		left := extract(height-1, position*2)
+		right := left
+		if position*2+1 < treeWidth(pmt.NumLeaves, height-1) {
+			right = extract(height-1, position*2+1)
+			//identical siblings would let the same root prove a different set of leaves
+			if err == nil && bytes.Equal(left, right) {
+				err = &blockchain_errors.ErrInvalidPartialMerkleTree{}
+			}
+		}
+		if err != nil {
+			return nil
+		}
+		return hashPair(left, right)
+	}
+
+	root = extract(treeHeight(pmt.NumLeaves), 0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	//every hash and every flag (except the padding of the last byte) must have been used
+	if hashesUsed != len(pmt.Hashes) || (flagsUsed+7)/8 != (len(pmt.Flags)+7)/8 {
+		return nil, nil, nil, &blockchain_errors.ErrInvalidPartialMerkleTree{}
+	}
+	return root, matchedHashes, matchedIndexes, nil
+}
+
+// encodes the partial tree as: version byte | NumLeaves uint32 | varint #hashes | hashes | flags packed into bytes,
+// least significant bit first, prefixed with their length
+func (pmt *PartialMerkleTree) Serialize() []byte {
+	w := serialization.NewVersionedWriter()
+	w.WriteUint32(uint32(pmt.NumLeaves))
+	w.WriteVarInt(uint64(len(pmt.Hashes)))
+	for _, hash := range pmt.Hashes {
+		w.WriteVarBytes(hash)
+	}
+	flagBytes := make([]byte, (len(pmt.Flags)+7)/8)
+	for i, flag := range pmt.Flags {
+		if flag {
+			flagBytes[i/8] |= 1 << (i % 8)
+		}
+	}
+	w.WriteVarBytes(flagBytes)
+	return w.Bytes()
+}
+
+// decodes a partial tree. the flags keep the padding of the last byte, which Extract ignores
+func DecodePartialMerkleTree(data []byte) (*PartialMerkleTree, error) {
+	r := serialization.NewVersionedReader(data)
+	pmt := &PartialMerkleTree{NumLeaves: int(r.ReadUint32())}
+	pmt.Hashes = make([][]byte, r.ReadCount(1))
+	for i := range pmt.Hashes {
+		pmt.Hashes[i] = r.ReadVarBytes()
+	}
+	flagBytes := r.ReadVarBytes()
+	if err := r.Finish(); err != nil {
+		return nil, err
+	}
+	for _, flagByte := range flagBytes {
+		for bit := 0; bit < 8; bit++ {
+			pmt.Flags = append(pmt.Flags, flagByte&(1<<bit) != 0)
+		}
+	}
+	return pmt, nil
+}
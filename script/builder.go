@@ -0,0 +1,74 @@
+package script
+
+import (
+	"encoding/binary"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+// builds scripts using the smallest push opcodes, so that they pass the interpreter's minimal push checks
+type ScriptBuilder struct {
+	script []byte
+	err    error
+}
+
+func NewScriptBuilder() *ScriptBuilder {
+	return &ScriptBuilder{}
+}
+
+func (b *ScriptBuilder) AddOp(opcode byte) *ScriptBuilder {
+	b.script = append(b.script, opcode)
+	return b
+}
+
+func (b *ScriptBuilder) AddData(data []byte) *ScriptBuilder {
+	if len(data) > MaxScriptElementSize {
+		b.err = &blockchain_errors.ErrElementTooLarge{}
+		return b
+	}
+
+	dataLen := len(data)
+	switch {
+	case dataLen == 0:
+		b.script = append(b.script, OP_0)
+		return b
+	case dataLen == 1 && data[0] >= 1 && data[0] <= 16:
+		b.script = append(b.script, OP_1+data[0]-1)
+		return b
+	case dataLen == 1 && data[0] == 0x81:
+		b.script = append(b.script, OP_1NEGATE)
+		return b
+	case dataLen <= int(OP_DATA_75):
+		b.script = append(b.script, byte(dataLen))
+	case dataLen <= 0xff:
+		b.script = append(b.script, OP_PUSHDATA1, byte(dataLen))
+	default:
+		b.script = append(b.script, OP_PUSHDATA2)
+		b.script = binary.LittleEndian.AppendUint16(b.script, uint16(dataLen))
+	}
+	b.script = append(b.script, data...)
+	return b
+}
+
+func (b *ScriptBuilder) AddInt64(n int64) *ScriptBuilder {
+	if n == 0 {
+		return b.AddOp(OP_0)
+	}
+	if n == -1 {
+		return b.AddOp(OP_1NEGATE)
+	}
+	if n >= 1 && n <= 16 {
+		return b.AddOp(OP_1 + byte(n-1))
+	}
+	return b.AddData(scriptNum(n).Bytes())
+}
+
+func (b *ScriptBuilder) Script() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.script) > MaxScriptSize {
+		return nil, &blockchain_errors.ErrScriptTooLarge{}
+	}
+	return b.script, nil
+}
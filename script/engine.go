@@ -0,0 +1,503 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/utils"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const MaxScriptSize = 10000
+const MaxScriptElementSize = 520
+const MaxOpsPerScript = 201 //push opcodes don't count towards this limit
+const MaxStackSize = 1000   //includes the alt stack
+const MaxPubKeysPerMultisig = 20
+
+// checks signatures and lock times against the transaction (and input) being verified, so that the interpreter
+// doesn't need to know how transactions are structured
+type SignatureChecker interface {
+	CheckSig(signature []byte, pubKey []byte) bool
+	CheckLockTime(lockTime int64) bool //whether the transaction can only be included after the given lock time
+}
+
+// lock times can be up to 5 bytes long, since they're unsigned 32 bit values
+const lockTimeNumberSize = 5
+
+type engine struct {
+	stack     stack
+	altStack  stack
+	condStack []bool //whether each of the nested conditionals being executed is in a taken branch
+	numOps    int
+	checker   SignatureChecker
+}
+
+// runs the unlocking script followed by the locking script of the output it spends, succeeding if the stack ends with a
+// true value on top. For pay to script hash outputs, the redeem script (the last element pushed by the unlocking
+// script) is then run against the rest of the elements pushed by the unlocking script
+func Verify(scriptSig []byte, scriptPubKey []byte, checker SignatureChecker) error {
+	if !IsPushOnly(scriptSig) {
+		return &blockchain_errors.ErrScriptSigNotPushOnly{}
+	}
+
+	e := &engine{checker: checker}
+	if err := e.executeScript(scriptSig); err != nil {
+		return err
+	}
+
+	isScriptHash := GetScriptClass(scriptPubKey) == ScriptHashTy
+	scriptSigStack := append(stack{}, e.stack...)
+
+	if err := e.executeScript(scriptPubKey); err != nil {
+		return err
+	}
+	if err := e.checkTopIsTrue(); err != nil {
+		return err
+	}
+
+	if !isScriptHash {
+		return nil
+	}
+
+	redeemScript, err := scriptSigStack.pop()
+	if err != nil {
+		return err
+	}
+	e.stack = scriptSigStack
+	e.altStack = nil
+	if err := e.executeScript(redeemScript); err != nil {
+		return err
+	}
+	return e.checkTopIsTrue()
+}
+
+func (e *engine) checkTopIsTrue() error {
+	top, err := e.stack.peek(0)
+	if err != nil || !asBool(top) {
+		return &blockchain_errors.ErrEvalFalse{}
+	}
+	return nil
+}
+
+func (e *engine) isExecuting() bool {
+	for _, taken := range e.condStack {
+		if !taken {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *engine) executeScript(script []byte) error {
+	if len(script) > MaxScriptSize {
+		return &blockchain_errors.ErrScriptTooLarge{}
+	}
+
+	ops, err := parseScript(script)
+	if err != nil {
+		return err
+	}
+
+	e.numOps = 0
+	e.condStack = nil
+	for _, op := range ops {
+		if len(op.data) > MaxScriptElementSize {
+			return &blockchain_errors.ErrElementTooLarge{}
+		}
+		if op.opcode > OP_16 {
+			e.numOps++
+			if e.numOps > MaxOpsPerScript {
+				return &blockchain_errors.ErrTooManyOps{}
+			}
+		}
+
+		if !e.isExecuting() && !isConditionalOpcode(op.opcode) {
+			continue
+		}
+
+		if err := e.executeOpcode(op); err != nil {
+			return err
+		}
+
+		if len(e.stack)+len(e.altStack) > MaxStackSize {
+			return &blockchain_errors.ErrStackOverflow{}
+		}
+	}
+
+	if len(e.condStack) != 0 {
+		return &blockchain_errors.ErrUnbalancedConditional{}
+	}
+	return nil
+}
+
+func (e *engine) executeOpcode(op parsedOpcode) error {
+	switch {
+	case op.opcode <= OP_PUSHDATA4:
+		if err := checkMinimalDataPush(op); err != nil {
+			return err
+		}
+		e.stack.push(op.data)
+		return nil
+	case op.opcode == OP_1NEGATE:
+		e.stack.pushNum(-1)
+		return nil
+	case op.opcode >= OP_1 && op.opcode <= OP_16:
+		e.stack.pushNum(scriptNum(op.opcode - OP_1 + 1))
+		return nil
+	}
+
+	switch op.opcode {
+	case OP_NOP:
+		return nil
+	case OP_IF, OP_NOTIF:
+		taken := false
+		if e.isExecuting() {
+			cond, err := e.stack.popBool()
+			if err != nil {
+				return err
+			}
+			taken = cond == (op.opcode == OP_IF)
+		}
+		e.condStack = append(e.condStack, taken)
+		return nil
+	case OP_ELSE:
+		if len(e.condStack) == 0 {
+			return &blockchain_errors.ErrUnbalancedConditional{}
+		}
+		e.condStack[len(e.condStack)-1] = !e.condStack[len(e.condStack)-1]
+		return nil
+	case OP_ENDIF:
+		if len(e.condStack) == 0 {
+			return &blockchain_errors.ErrUnbalancedConditional{}
+		}
+		e.condStack = e.condStack[:len(e.condStack)-1]
+		return nil
+	case OP_VERIFY:
+		return e.verify()
+	case OP_RETURN:
+		return &blockchain_errors.ErrEarlyReturn{}
+	case OP_CHECKLOCKTIMEVERIFY: //fails unless the transaction's lock time is at least the one on top of the stack
+		v, err := e.stack.peek(0)
+		if err != nil {
+			return err
+		}
+		lockTime, err := makeScriptNum(v, lockTimeNumberSize)
+		if err != nil {
+			return err
+		}
+		if lockTime < 0 || !e.checker.CheckLockTime(int64(lockTime)) {
+			return &blockchain_errors.ErrUnsatisfiedLockTime{}
+		}
+		return nil
+	}
+
+	if executed, err := e.executeStackOpcode(op.opcode); executed {
+		return err
+	}
+	if executed, err := e.executeNumericOpcode(op.opcode); executed {
+		return err
+	}
+	if executed, err := e.executeCryptoOpcode(op.opcode); executed {
+		return err
+	}
+	return &blockchain_errors.ErrInvalidOpcode{}
+}
+
+func (e *engine) verify() error {
+	ok, err := e.stack.popBool()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &blockchain_errors.ErrVerifyFailed{}
+	}
+	return nil
+}
+
+// executes opcodes which only move elements around the stack, returning whether the opcode is one of them
+func (e *engine) executeStackOpcode(opcode byte) (bool, error) {
+	switch opcode {
+	case OP_TOALTSTACK:
+		v, err := e.stack.pop()
+		if err != nil {
+			return true, err
+		}
+		e.altStack.push(v)
+	case OP_FROMALTSTACK:
+		v, err := e.altStack.pop()
+		if err != nil {
+			return true, err
+		}
+		e.stack.push(v)
+	case OP_2DROP:
+		if len(e.stack) < 2 {
+			return true, &blockchain_errors.ErrStackUnderflow{}
+		}
+		e.stack = e.stack[:len(e.stack)-2]
+	case OP_2DUP:
+		a, err := e.stack.peek(1)
+		if err != nil {
+			return true, err
+		}
+		b, _ := e.stack.peek(0)
+		e.stack.push(a)
+		e.stack.push(b)
+	case OP_DEPTH:
+		e.stack.pushNum(scriptNum(len(e.stack)))
+	case OP_DROP:
+		_, err := e.stack.pop()
+		return true, err
+	case OP_DUP:
+		v, err := e.stack.peek(0)
+		if err != nil {
+			return true, err
+		}
+		e.stack.push(v)
+	case OP_NIP:
+		top, err := e.stack.pop()
+		if err != nil {
+			return true, err
+		}
+		if _, err = e.stack.pop(); err != nil {
+			return true, err
+		}
+		e.stack.push(top)
+	case OP_OVER:
+		v, err := e.stack.peek(1)
+		if err != nil {
+			return true, err
+		}
+		e.stack.push(v)
+	case OP_SWAP:
+		if len(e.stack) < 2 {
+			return true, &blockchain_errors.ErrStackUnderflow{}
+		}
+		n := len(e.stack)
+		e.stack[n-1], e.stack[n-2] = e.stack[n-2], e.stack[n-1]
+	case OP_SIZE:
+		v, err := e.stack.peek(0)
+		if err != nil {
+			return true, err
+		}
+		e.stack.pushNum(scriptNum(len(v)))
+	case OP_EQUAL, OP_EQUALVERIFY:
+		a, err := e.stack.pop()
+		if err != nil {
+			return true, err
+		}
+		b, err := e.stack.pop()
+		if err != nil {
+			return true, err
+		}
+		e.stack.pushBool(bytes.Equal(a, b))
+		if opcode == OP_EQUALVERIFY {
+			return true, e.verify()
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// executes arithmetic and numeric comparison opcodes, returning whether the opcode is one of them
+func (e *engine) executeNumericOpcode(opcode byte) (bool, error) {
+	switch opcode {
+	case OP_1ADD, OP_1SUB, OP_NEGATE, OP_ABS, OP_NOT, OP_0NOTEQUAL:
+		n, err := e.stack.popNum()
+		if err != nil {
+			return true, err
+		}
+		switch opcode {
+		case OP_1ADD:
+			n++
+		case OP_1SUB:
+			n--
+		case OP_NEGATE:
+			n = -n
+		case OP_ABS:
+			if n < 0 {
+				n = -n
+			}
+		case OP_NOT:
+			n = boolToNum(n == 0)
+		case OP_0NOTEQUAL:
+			n = boolToNum(n != 0)
+		}
+		e.stack.pushNum(n)
+	case OP_ADD, OP_SUB, OP_BOOLAND, OP_BOOLOR, OP_NUMEQUAL, OP_NUMEQUALVERIFY, OP_NUMNOTEQUAL, OP_LESSTHAN,
+		OP_GREATERTHAN, OP_LESSTHANOREQUAL, OP_GREATERTHANOREQUAL, OP_MIN, OP_MAX:
+		b, err := e.stack.popNum()
+		if err != nil {
+			return true, err
+		}
+		a, err := e.stack.popNum()
+		if err != nil {
+			return true, err
+		}
+
+		var result scriptNum
+		switch opcode {
+		case OP_ADD:
+			result = a + b
+		case OP_SUB:
+			result = a - b
+		case OP_BOOLAND:
+			result = boolToNum(a != 0 && b != 0)
+		case OP_BOOLOR:
+			result = boolToNum(a != 0 || b != 0)
+		case OP_NUMEQUAL, OP_NUMEQUALVERIFY:
+			result = boolToNum(a == b)
+		case OP_NUMNOTEQUAL:
+			result = boolToNum(a != b)
+		case OP_LESSTHAN:
+			result = boolToNum(a < b)
+		case OP_GREATERTHAN:
+			result = boolToNum(a > b)
+		case OP_LESSTHANOREQUAL:
+			result = boolToNum(a <= b)
+		case OP_GREATERTHANOREQUAL:
+			result = boolToNum(a >= b)
+		case OP_MIN:
+			result = min(a, b)
+		case OP_MAX:
+			result = max(a, b)
+		}
+		e.stack.pushNum(result)
+
+		if opcode == OP_NUMEQUALVERIFY {
+			return true, e.verify()
+		}
+	case OP_WITHIN: //x min max -> min <= x < max
+		maxVal, err := e.stack.popNum()
+		if err != nil {
+			return true, err
+		}
+		minVal, err := e.stack.popNum()
+		if err != nil {
+			return true, err
+		}
+		x, err := e.stack.popNum()
+		if err != nil {
+			return true, err
+		}
+		e.stack.pushBool(minVal <= x && x < maxVal)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func boolToNum(v bool) scriptNum {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// executes hashing and signature checking opcodes, returning whether the opcode is one of them
+func (e *engine) executeCryptoOpcode(opcode byte) (bool, error) {
+	switch opcode {
+	case OP_RIPEMD160, OP_SHA256, OP_HASH160, OP_HASH256:
+		v, err := e.stack.pop()
+		if err != nil {
+			return true, err
+		}
+
+		switch opcode {
+		case OP_RIPEMD160:
+			hasher := ripemd160.New()
+			hasher.Write(v)
+			e.stack.push(hasher.Sum(nil))
+		case OP_SHA256:
+			hash := sha256.Sum256(v)
+			e.stack.push(hash[:])
+		case OP_HASH160:
+			e.stack.push(utils.HashPublicKey(v))
+		case OP_HASH256:
+			hash := sha256.Sum256(v)
+			hash = sha256.Sum256(hash[:])
+			e.stack.push(hash[:])
+		}
+	case OP_CHECKSIG, OP_CHECKSIGVERIFY:
+		pubKey, err := e.stack.pop()
+		if err != nil {
+			return true, err
+		}
+		signature, err := e.stack.pop()
+		if err != nil {
+			return true, err
+		}
+
+		e.stack.pushBool(len(signature) > 0 && e.checker.CheckSig(signature, pubKey))
+		if opcode == OP_CHECKSIGVERIFY {
+			return true, e.verify()
+		}
+	case OP_CHECKMULTISIG, OP_CHECKMULTISIGVERIFY:
+		valid, err := e.checkMultisig()
+		if err != nil {
+			return true, err
+		}
+		e.stack.pushBool(valid)
+		if opcode == OP_CHECKMULTISIGVERIFY {
+			return true, e.verify()
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// <sig1> ... <sigM> M <pubKey1> ... <pubKeyN> N OP_CHECKMULTISIG succeeds if the M signatures are valid for M of the
+// N pubkeys, with the signatures in the same order as their pubkeys
+func (e *engine) checkMultisig() (bool, error) {
+	numPubKeys, err := e.stack.popNum()
+	if err != nil {
+		return false, err
+	}
+	if numPubKeys < 0 || numPubKeys > MaxPubKeysPerMultisig {
+		return false, &blockchain_errors.ErrInvalidPubKeyCount{}
+	}
+	e.numOps += int(numPubKeys)
+	if e.numOps > MaxOpsPerScript {
+		return false, &blockchain_errors.ErrTooManyOps{}
+	}
+
+	pubKeys := make([][]byte, numPubKeys)
+	for i := len(pubKeys) - 1; i >= 0; i-- {
+		if pubKeys[i], err = e.stack.pop(); err != nil {
+			return false, err
+		}
+	}
+
+	numSignatures, err := e.stack.popNum()
+	if err != nil {
+		return false, err
+	}
+	if numSignatures < 0 || numSignatures > numPubKeys {
+		return false, &blockchain_errors.ErrInvalidSignatureCount{}
+	}
+
+	signatures := make([][]byte, numSignatures)
+	for i := len(signatures) - 1; i >= 0; i-- {
+		if signatures[i], err = e.stack.pop(); err != nil {
+			return false, err
+		}
+	}
+
+	pubKeyIdx := 0
+	for sigIdx, signature := range signatures {
+		//each signature is matched against the remaining pubkeys, in order
+		for {
+			if len(pubKeys)-pubKeyIdx < len(signatures)-sigIdx { //not enough pubkeys left for the remaining signatures
+				return false, nil
+			}
+			pubKey := pubKeys[pubKeyIdx]
+			pubKeyIdx++
+			if len(signature) > 0 && e.checker.CheckSig(signature, pubKey) {
+				break
+			}
+		}
+	}
+	return true, nil
+}
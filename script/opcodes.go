@@ -0,0 +1,138 @@
+package script
+
+// opcode values follow bitcoin's script so that scripts are familiar to read
+const (
+	OP_0         byte = 0x00 //pushes an empty byte array
+	OP_DATA_1    byte = 0x01 //0x01 to 0x4b push the next n bytes
+	OP_DATA_75   byte = 0x4b
+	OP_PUSHDATA1 byte = 0x4c //the next byte is the number of bytes to push
+	OP_PUSHDATA2 byte = 0x4d //the next 2 bytes (little endian) are the number of bytes to push
+	OP_PUSHDATA4 byte = 0x4e //the next 4 bytes (little endian) are the number of bytes to push
+	OP_1NEGATE   byte = 0x4f
+	OP_1         byte = 0x51 //0x51 to 0x60 push the numbers 1 to 16
+	OP_2         byte = 0x52
+	OP_3         byte = 0x53
+	OP_16        byte = 0x60
+
+	OP_NOP    byte = 0x61
+	OP_IF     byte = 0x63
+	OP_NOTIF  byte = 0x64
+	OP_ELSE   byte = 0x67
+	OP_ENDIF  byte = 0x68
+	OP_VERIFY byte = 0x69
+	OP_RETURN byte = 0x6a
+
+	OP_TOALTSTACK   byte = 0x6b
+	OP_FROMALTSTACK byte = 0x6c
+	OP_2DROP        byte = 0x6d
+	OP_2DUP         byte = 0x6e
+	OP_DEPTH        byte = 0x74
+	OP_DROP         byte = 0x75
+	OP_DUP          byte = 0x76
+	OP_NIP          byte = 0x77
+	OP_OVER         byte = 0x78
+	OP_SWAP         byte = 0x7c
+	OP_SIZE         byte = 0x82
+
+	OP_EQUAL       byte = 0x87
+	OP_EQUALVERIFY byte = 0x88
+
+	OP_1ADD               byte = 0x8b
+	OP_1SUB               byte = 0x8c
+	OP_NEGATE             byte = 0x8f
+	OP_ABS                byte = 0x90
+	OP_NOT                byte = 0x91
+	OP_0NOTEQUAL          byte = 0x92
+	OP_ADD                byte = 0x93
+	OP_SUB                byte = 0x94
+	OP_BOOLAND            byte = 0x9a
+	OP_BOOLOR             byte = 0x9b
+	OP_NUMEQUAL           byte = 0x9c
+	OP_NUMEQUALVERIFY     byte = 0x9d
+	OP_NUMNOTEQUAL        byte = 0x9e
+	OP_LESSTHAN           byte = 0x9f
+	OP_GREATERTHAN        byte = 0xa0
+	OP_LESSTHANOREQUAL    byte = 0xa1
+	OP_GREATERTHANOREQUAL byte = 0xa2
+	OP_MIN                byte = 0xa3
+	OP_MAX                byte = 0xa4
+	OP_WITHIN             byte = 0xa5
+
+	OP_RIPEMD160      byte = 0xa6
+	OP_SHA256         byte = 0xa8
+	OP_HASH160        byte = 0xa9
+	OP_HASH256        byte = 0xaa
+	OP_CHECKSIG       byte = 0xac
+	OP_CHECKSIGVERIFY byte = 0xad
+
+	OP_CHECKMULTISIG       byte = 0xae
+	OP_CHECKMULTISIGVERIFY byte = 0xaf
+
+	OP_CHECKLOCKTIMEVERIFY byte = 0xb1
+)
+
+var opcodeNames = map[byte]string{
+	OP_0:                   "OP_0",
+	OP_PUSHDATA1:           "OP_PUSHDATA1",
+	OP_PUSHDATA2:           "OP_PUSHDATA2",
+	OP_PUSHDATA4:           "OP_PUSHDATA4",
+	OP_1NEGATE:             "OP_1NEGATE",
+	OP_NOP:                 "OP_NOP",
+	OP_IF:                  "OP_IF",
+	OP_NOTIF:               "OP_NOTIF",
+	OP_ELSE:                "OP_ELSE",
+	OP_ENDIF:               "OP_ENDIF",
+	OP_VERIFY:              "OP_VERIFY",
+	OP_RETURN:              "OP_RETURN",
+	OP_TOALTSTACK:          "OP_TOALTSTACK",
+	OP_FROMALTSTACK:        "OP_FROMALTSTACK",
+	OP_2DROP:               "OP_2DROP",
+	OP_2DUP:                "OP_2DUP",
+	OP_DEPTH:               "OP_DEPTH",
+	OP_DROP:                "OP_DROP",
+	OP_DUP:                 "OP_DUP",
+	OP_NIP:                 "OP_NIP",
+	OP_OVER:                "OP_OVER",
+	OP_SWAP:                "OP_SWAP",
+	OP_SIZE:                "OP_SIZE",
+	OP_EQUAL:               "OP_EQUAL",
+	OP_EQUALVERIFY:         "OP_EQUALVERIFY",
+	OP_1ADD:                "OP_1ADD",
+	OP_1SUB:                "OP_1SUB",
+	OP_NEGATE:              "OP_NEGATE",
+	OP_ABS:                 "OP_ABS",
+	OP_NOT:                 "OP_NOT",
+	OP_0NOTEQUAL:           "OP_0NOTEQUAL",
+	OP_ADD:                 "OP_ADD",
+	OP_SUB:                 "OP_SUB",
+	OP_BOOLAND:             "OP_BOOLAND",
+	OP_BOOLOR:              "OP_BOOLOR",
+	OP_NUMEQUAL:            "OP_NUMEQUAL",
+	OP_NUMEQUALVERIFY:      "OP_NUMEQUALVERIFY",
+	OP_NUMNOTEQUAL:         "OP_NUMNOTEQUAL",
+	OP_LESSTHAN:            "OP_LESSTHAN",
+	OP_GREATERTHAN:         "OP_GREATERTHAN",
+	OP_LESSTHANOREQUAL:     "OP_LESSTHANOREQUAL",
+	OP_GREATERTHANOREQUAL:  "OP_GREATERTHANOREQUAL",
+	OP_MIN:                 "OP_MIN",
+	OP_MAX:                 "OP_MAX",
+	OP_WITHIN:              "OP_WITHIN",
+	OP_RIPEMD160:           "OP_RIPEMD160",
+	OP_SHA256:              "OP_SHA256",
+	OP_HASH160:             "OP_HASH160",
+	OP_HASH256:             "OP_HASH256",
+	OP_CHECKSIG:            "OP_CHECKSIG",
+	OP_CHECKSIGVERIFY:      "OP_CHECKSIGVERIFY",
+	OP_CHECKMULTISIG:       "OP_CHECKMULTISIG",
+	OP_CHECKMULTISIGVERIFY: "OP_CHECKMULTISIGVERIFY",
+	OP_CHECKLOCKTIMEVERIFY: "OP_CHECKLOCKTIMEVERIFY",
+}
+
+// opcodes which push data or a small number onto the stack
+func isPushOpcode(opcode byte) bool {
+	return opcode <= OP_16 && opcode != 0x50 //0x50 is reserved
+}
+
+func isConditionalOpcode(opcode byte) bool {
+	return opcode == OP_IF || opcode == OP_NOTIF || opcode == OP_ELSE || opcode == OP_ENDIF
+}
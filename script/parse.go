@@ -0,0 +1,145 @@
+package script
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+type parsedOpcode struct {
+	opcode byte
+	data   []byte //data pushed by the opcode, if it's a data push
+}
+
+func parseScript(script []byte) ([]parsedOpcode, error) {
+	var ops []parsedOpcode
+	for i := 0; i < len(script); {
+		opcode := script[i]
+		i++
+
+		var dataLen int
+		switch {
+		case opcode >= OP_DATA_1 && opcode <= OP_DATA_75:
+			dataLen = int(opcode)
+		case opcode == OP_PUSHDATA1:
+			if i+1 > len(script) {
+				return nil, &blockchain_errors.ErrMalformedPush{}
+			}
+			dataLen = int(script[i])
+			i++
+		case opcode == OP_PUSHDATA2:
+			if i+2 > len(script) {
+				return nil, &blockchain_errors.ErrMalformedPush{}
+			}
+			dataLen = int(binary.LittleEndian.Uint16(script[i : i+2]))
+			i += 2
+		case opcode == OP_PUSHDATA4:
+			if i+4 > len(script) {
+				return nil, &blockchain_errors.ErrMalformedPush{}
+			}
+			dataLen = int(binary.LittleEndian.Uint32(script[i : i+4]))
+			i += 4
+		default:
+			ops = append(ops, parsedOpcode{opcode: opcode})
+			continue
+		}
+
+		if dataLen < 0 || dataLen > len(script)-i {
+			return nil, &blockchain_errors.ErrMalformedPush{}
+		}
+		ops = append(ops, parsedOpcode{opcode: opcode, data: script[i : i+dataLen]})
+		i += dataLen
+	}
+	return ops, nil
+}
+
+// data pushes must use the smallest opcode able to push the data
+func checkMinimalDataPush(op parsedOpcode) error {
+	dataLen := len(op.data)
+	var minimalOpcode byte
+	switch {
+	case dataLen == 0:
+		minimalOpcode = OP_0
+	case dataLen == 1 && op.data[0] >= 1 && op.data[0] <= 16:
+		minimalOpcode = OP_1 + op.data[0] - 1
+	case dataLen == 1 && op.data[0] == 0x81:
+		minimalOpcode = OP_1NEGATE
+	case dataLen <= int(OP_DATA_75):
+		minimalOpcode = byte(dataLen)
+	case dataLen <= 0xff:
+		minimalOpcode = OP_PUSHDATA1
+	case dataLen <= 0xffff:
+		minimalOpcode = OP_PUSHDATA2
+	default:
+		minimalOpcode = OP_PUSHDATA4
+	}
+
+	if op.opcode != minimalOpcode {
+		return &blockchain_errors.ErrNonMinimalPush{}
+	}
+	return nil
+}
+
+func IsPushOnly(script []byte) bool {
+	ops, err := parseScript(script)
+	if err != nil {
+		return false
+	}
+	for _, op := range ops {
+		if !isPushOpcode(op.opcode) {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the data pushed by a push only script
+func PushedData(script []byte) ([][]byte, error) {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	var pushes [][]byte
+	for _, op := range ops {
+		switch {
+		case op.opcode <= OP_PUSHDATA4:
+			pushes = append(pushes, op.data)
+		case op.opcode == OP_1NEGATE:
+			pushes = append(pushes, scriptNum(-1).Bytes())
+		case op.opcode >= OP_1 && op.opcode <= OP_16:
+			pushes = append(pushes, scriptNum(op.opcode-OP_1+1).Bytes())
+		default:
+			return nil, &blockchain_errors.ErrScriptSigNotPushOnly{}
+		}
+	}
+	return pushes, nil
+}
+
+// human readable representation of a script, with data pushes in hex
+func Disassemble(script []byte) string {
+	ops, err := parseScript(script)
+	if err != nil {
+		return "[error]"
+	}
+
+	var parts []string
+	for _, op := range ops {
+		switch {
+		case op.opcode > OP_0 && op.opcode <= OP_PUSHDATA4:
+			parts = append(parts, hex.EncodeToString(op.data))
+		case op.opcode >= OP_1 && op.opcode <= OP_16:
+			parts = append(parts, fmt.Sprintf("OP_%d", op.opcode-OP_1+1))
+		default:
+			name, known := opcodeNames[op.opcode]
+			if !known {
+				name = fmt.Sprintf("OP_UNKNOWN%d", op.opcode)
+			}
+			parts = append(parts, name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,292 @@
+package script
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+// accepts a signature if it's equal to the pubkey it's checked against, and lock times up to its own
+type fakeChecker struct {
+	lockTime int64
+}
+
+func (checker fakeChecker) CheckSig(signature []byte, pubKey []byte) bool {
+	return bytes.Equal(signature, pubKey)
+}
+
+func (checker fakeChecker) CheckLockTime(lockTime int64) bool {
+	return lockTime <= checker.lockTime
+}
+
+func mustScript(t *testing.T, b *ScriptBuilder) []byte {
+	script, err := b.Script()
+	if err != nil {
+		t.Fatalf("Error building script: %s", err)
+	}
+	return script
+}
+
+func TestScriptNum(t *testing.T) {
+	for _, n := range []int64{0, 1, -1, 127, 128, -128, 255, 256, -255, 32767, 32768, -2147483647, 2147483647} {
+		decoded, err := makeScriptNum(scriptNum(n).Bytes(), maxNumberSize)
+		if err != nil {
+			t.Fatalf("Error decoding %d: %s", n, err)
+		}
+		if int64(decoded) != n {
+			t.Fatalf("Decoded %d instead of %d", decoded, n)
+		}
+	}
+
+	if _, err := makeScriptNum([]byte{0x01, 0x00}, maxNumberSize); !errors.Is(err, &blockchain_errors.ErrNonMinimalNumber{}) {
+		t.Fatalf("Non minimal number was accepted")
+	}
+	if _, err := makeScriptNum([]byte{1, 2, 3, 4, 5}, maxNumberSize); !errors.Is(err, &blockchain_errors.ErrNumberTooLarge{}) {
+		t.Fatalf("Number larger than 4 bytes was accepted")
+	}
+}
+
+func TestPayToPubKeyHash(t *testing.T) {
+	pubKey := []byte("public key")
+	scriptPubKey, err := PayToPubKeyHashScript(utils.HashPublicKey(pubKey))
+	if err != nil {
+		t.Fatalf("Error building script: %s", err)
+	}
+	if GetScriptClass(scriptPubKey) != PubKeyHashTy {
+		t.Fatalf("Script isn't recognized as pay to pubkey hash")
+	}
+	if !bytes.Equal(ExtractPubKeyHash(scriptPubKey), utils.HashPublicKey(pubKey)) {
+		t.Fatalf("Extracted pubkey hash is incorrect")
+	}
+
+	scriptSig, _ := PubKeyHashScriptSig(pubKey, pubKey)
+	if err := Verify(scriptSig, scriptPubKey, fakeChecker{}); err != nil {
+		t.Fatalf("Valid spend was rejected: %s", err)
+	}
+
+	scriptSig, _ = PubKeyHashScriptSig([]byte("wrong signature"), pubKey)
+	if err := Verify(scriptSig, scriptPubKey, fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrEvalFalse{}) {
+		t.Fatalf("Invalid signature was accepted")
+	}
+
+	otherPubKey := []byte("other public key")
+	scriptSig, _ = PubKeyHashScriptSig(otherPubKey, otherPubKey)
+	if err := Verify(scriptSig, scriptPubKey, fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrVerifyFailed{}) {
+		t.Fatalf("Spend with the wrong pubkey was accepted")
+	}
+}
+
+func TestConditionalsAndArithmetic(t *testing.T) {
+	//adds the two pushed numbers if the branch flag is true, subtracts them otherwise, and checks the result is within [5, 10)
+	scriptPubKey := mustScript(t, NewScriptBuilder().
+		AddOp(OP_IF).AddOp(OP_ADD).AddOp(OP_ELSE).AddOp(OP_SUB).AddOp(OP_ENDIF).
+		AddInt64(5).AddInt64(10).AddOp(OP_WITHIN))
+
+	tests := []struct {
+		a, b   int64
+		branch bool
+		valid  bool
+	}{
+		{3, 4, true, true},
+		{3, 7, true, false},
+		{20, 13, false, true},
+		{3, 4, false, false},
+	}
+	for _, test := range tests {
+		builder := NewScriptBuilder().AddInt64(test.a).AddInt64(test.b)
+		if test.branch {
+			builder.AddInt64(1)
+		} else {
+			builder.AddInt64(0)
+		}
+		err := Verify(mustScript(t, builder), scriptPubKey, fakeChecker{})
+		if (err == nil) != test.valid {
+			t.Fatalf("Unexpected result for %d, %d, %t: %v", test.a, test.b, test.branch, err)
+		}
+	}
+}
+
+func TestHashLock(t *testing.T) {
+	preimage := []byte("secret")
+	hash := sha256.Sum256(preimage)
+
+	scriptPubKey := mustScript(t, NewScriptBuilder().AddOp(OP_SHA256).AddData(hash[:]).AddOp(OP_EQUAL))
+	if err := Verify(mustScript(t, NewScriptBuilder().AddData(preimage)), scriptPubKey, fakeChecker{}); err != nil {
+		t.Fatalf("Correct preimage was rejected: %s", err)
+	}
+	if err := Verify(mustScript(t, NewScriptBuilder().AddData([]byte("guess"))), scriptPubKey, fakeChecker{}); err == nil {
+		t.Fatalf("Wrong preimage was accepted")
+	}
+}
+
+func TestScriptLimits(t *testing.T) {
+	unlock := mustScript(t, NewScriptBuilder().AddInt64(1))
+
+	tooManyOps := NewScriptBuilder()
+	for i := 0; i <= MaxOpsPerScript; i++ {
+		tooManyOps.AddOp(OP_NOP)
+	}
+	if err := Verify(unlock, mustScript(t, tooManyOps), fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrTooManyOps{}) {
+		t.Fatalf("Script with too many operations was accepted")
+	}
+
+	if err := Verify(unlock, []byte{OP_IF}, fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrUnbalancedConditional{}) {
+		t.Fatalf("Unbalanced conditional was accepted")
+	}
+
+	if err := Verify(unlock, []byte{OP_RETURN}, fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrEarlyReturn{}) {
+		t.Fatalf("OP_RETURN didn't fail the script")
+	}
+
+	if err := Verify([]byte{OP_1, OP_DROP}, []byte{OP_1}, fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrScriptSigNotPushOnly{}) {
+		t.Fatalf("Unlocking script with non push opcodes was accepted")
+	}
+
+	if err := Verify([]byte{OP_PUSHDATA1, 1, 7}, []byte{OP_DROP, OP_1}, fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrNonMinimalPush{}) {
+		t.Fatalf("Non minimal push was accepted")
+	}
+
+	if _, err := NewScriptBuilder().AddData(make([]byte, MaxScriptElementSize+1)).Script(); err == nil {
+		t.Fatalf("Element larger than the maximum element size was accepted")
+	}
+}
+
+func TestMultisig(t *testing.T) {
+	pubKeys := [][]byte{[]byte("key 1"), []byte("key 2"), []byte("key 3")}
+	scriptPubKey, err := MultisigScript(2, pubKeys)
+	if err != nil {
+		t.Fatalf("Error building script: %s", err)
+	}
+	if GetScriptClass(scriptPubKey) != MultiSigTy {
+		t.Fatalf("Script isn't recognized as multisig")
+	}
+	if numSignatures, extractedPubKeys, ok := ExtractMultisig(scriptPubKey); !ok || numSignatures != 2 || len(extractedPubKeys) != 3 {
+		t.Fatalf("Extracted multisig parameters are incorrect")
+	}
+
+	tests := []struct {
+		signatures [][]byte
+		valid      bool
+	}{
+		{[][]byte{pubKeys[0], pubKeys[1]}, true},
+		{[][]byte{pubKeys[0], pubKeys[2]}, true},
+		{[][]byte{pubKeys[1], pubKeys[2]}, true},
+		{[][]byte{pubKeys[2], pubKeys[0]}, false}, //signatures out of key order
+		{[][]byte{pubKeys[0], pubKeys[0]}, false}, //same key signing twice
+		{[][]byte{pubKeys[0], []byte("forged")}, false},
+	}
+	for i, test := range tests {
+		scriptSig, _ := MultisigScriptSig(test.signatures)
+		err := Verify(scriptSig, scriptPubKey, fakeChecker{})
+		if (err == nil) != test.valid {
+			t.Fatalf("Unexpected result for multisig test %d: %v", i, err)
+		}
+	}
+}
+
+func TestPayToScriptHash(t *testing.T) {
+	pubKeys := [][]byte{[]byte("key 1"), []byte("key 2")}
+	redeemScript, _ := MultisigScript(2, pubKeys)
+	scriptPubKey, err := PayToScriptHashScript(utils.HashPublicKey(redeemScript))
+	if err != nil {
+		t.Fatalf("Error building script: %s", err)
+	}
+	if GetScriptClass(scriptPubKey) != ScriptHashTy {
+		t.Fatalf("Script isn't recognized as pay to script hash")
+	}
+
+	redeemScriptSig, _ := MultisigScriptSig(pubKeys)
+	scriptSig, _ := ScriptHashScriptSig(redeemScriptSig, redeemScript)
+	if err := Verify(scriptSig, scriptPubKey, fakeChecker{}); err != nil {
+		t.Fatalf("Valid spend was rejected: %s", err)
+	}
+
+	//the redeem script hash matches, but the redeem script itself isn't satisfied
+	redeemScriptSig, _ = MultisigScriptSig([][]byte{pubKeys[0], []byte("forged")})
+	scriptSig, _ = ScriptHashScriptSig(redeemScriptSig, redeemScript)
+	if err := Verify(scriptSig, scriptPubKey, fakeChecker{}); err == nil {
+		t.Fatalf("Spend not satisfying the redeem script was accepted")
+	}
+
+	otherRedeemScript, _ := MultisigScript(1, pubKeys)
+	redeemScriptSig, _ = MultisigScriptSig(pubKeys[:1])
+	scriptSig, _ = ScriptHashScriptSig(redeemScriptSig, otherRedeemScript)
+	if err := Verify(scriptSig, scriptPubKey, fakeChecker{}); err == nil {
+		t.Fatalf("Spend with a different redeem script was accepted")
+	}
+}
+
+func TestHTLC(t *testing.T) {
+	preimage := []byte("swap secret")
+	hash := sha256.Sum256(preimage)
+	recipientPubKey, senderPubKey := []byte("recipient key"), []byte("sender key")
+	params := HTLCParams{
+		Hash:                hash[:],
+		RecipientPubKeyHash: utils.HashPublicKey(recipientPubKey),
+		SenderPubKeyHash:    utils.HashPublicKey(senderPubKey),
+		LockTime:            100,
+	}
+	scriptPubKey, err := HTLCScript(params)
+	if err != nil {
+		t.Fatalf("Error building script: %s", err)
+	}
+	if GetScriptClass(scriptPubKey) != HTLCTy {
+		t.Fatalf("Script isn't recognized as an HTLC")
+	}
+	if extracted, ok := ExtractHTLC(scriptPubKey); !ok || extracted.LockTime != params.LockTime ||
+		!bytes.Equal(extracted.Hash, params.Hash) || !bytes.Equal(extracted.SenderPubKeyHash, params.SenderPubKeyHash) {
+		t.Fatalf("Extracted HTLC parameters are incorrect")
+	}
+
+	claim, _ := HTLCClaimScriptSig(recipientPubKey, recipientPubKey, preimage)
+	if err := Verify(claim, scriptPubKey, fakeChecker{}); err != nil {
+		t.Fatalf("Valid claim was rejected: %s", err)
+	}
+	if revealed, ok := ExtractHTLCPreimage(claim); !ok || !bytes.Equal(revealed, preimage) {
+		t.Fatalf("Preimage couldn't be extracted from the claim")
+	}
+
+	wrongPreimage, _ := HTLCClaimScriptSig(recipientPubKey, recipientPubKey, []byte("guess"))
+	if err := Verify(wrongPreimage, scriptPubKey, fakeChecker{}); err == nil {
+		t.Fatalf("Claim with the wrong preimage was accepted")
+	}
+
+	senderClaim, _ := HTLCClaimScriptSig(senderPubKey, senderPubKey, preimage)
+	if err := Verify(senderClaim, scriptPubKey, fakeChecker{}); err == nil {
+		t.Fatalf("Claim by the sender was accepted")
+	}
+
+	refund, _ := HTLCRefundScriptSig(senderPubKey, senderPubKey)
+	if err := Verify(refund, scriptPubKey, fakeChecker{lockTime: 99}); !errors.Is(err, &blockchain_errors.ErrUnsatisfiedLockTime{}) {
+		t.Fatalf("Refund before the lock time was accepted")
+	}
+	if err := Verify(refund, scriptPubKey, fakeChecker{lockTime: 100}); err != nil {
+		t.Fatalf("Refund after the lock time was rejected: %s", err)
+	}
+
+	if _, err := HTLCScript(HTLCParams{Hash: []byte("short"), LockTime: 1}); !errors.Is(err, &blockchain_errors.ErrInvalidHTLC{}) {
+		t.Fatalf("HTLC with invalid parameters was built")
+	}
+}
+
+func TestNullData(t *testing.T) {
+	for _, data := range [][]byte{{}, {7}, {0x81}, []byte("document hash")} {
+		scriptPubKey, err := NullDataScript(data)
+		if err != nil {
+			t.Fatalf("Error building script: %s", err)
+		}
+		if GetScriptClass(scriptPubKey) != NullDataTy {
+			t.Fatalf("Script isn't recognized as null data")
+		}
+		if extracted, ok := ExtractNullData(scriptPubKey); !ok || !bytes.Equal(extracted, data) {
+			t.Fatalf("Extracted %x instead of %x", extracted, data)
+		}
+		if err := Verify([]byte{OP_1}, scriptPubKey, fakeChecker{}); !errors.Is(err, &blockchain_errors.ErrEarlyReturn{}) {
+			t.Fatalf("Null data output could be spent")
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package script
+
+import (
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+// numeric operands can be at most 4 bytes long, although results of arithmetic can overflow that
+const maxNumberSize = 4
+
+// numbers are encoded as little endian sign-magnitude byte arrays, with the sign in the most significant bit
+type scriptNum int64
+
+func makeScriptNum(v []byte, maxLen int) (scriptNum, error) {
+	if len(v) > maxLen {
+		return 0, &blockchain_errors.ErrNumberTooLarge{}
+	}
+	if len(v) == 0 {
+		return 0, nil
+	}
+
+	//the most significant byte can only be 0x00 or 0x80 if the next byte needs its most significant bit
+	if v[len(v)-1]&0x7f == 0 && (len(v) == 1 || v[len(v)-2]&0x80 == 0) {
+		return 0, &blockchain_errors.ErrNonMinimalNumber{}
+	}
+
+	var result int64
+	for i, b := range v {
+		result |= int64(b) << uint(8*i)
+	}
+
+	if v[len(v)-1]&0x80 != 0 {
+		result &= ^(int64(0x80) << uint(8*(len(v)-1)))
+		return scriptNum(-result), nil
+	}
+	return scriptNum(result), nil
+}
+
+func (n scriptNum) Bytes() []byte {
+	if n == 0 {
+		return []byte{}
+	}
+
+	isNegative := n < 0
+	if isNegative {
+		n = -n
+	}
+
+	var result []byte
+	for n > 0 {
+		result = append(result, byte(n&0xff))
+		n >>= 8
+	}
+
+	//if the most significant bit is used by the magnitude, an extra byte is needed for the sign
+	if result[len(result)-1]&0x80 != 0 {
+		extraByte := byte(0x00)
+		if isNegative {
+			extraByte = 0x80
+		}
+		result = append(result, extraByte)
+	} else if isNegative {
+		result[len(result)-1] |= 0x80
+	}
+
+	return result
+}
+
+// any non zero value is true, except negative zero
+func asBool(v []byte) bool {
+	for i, b := range v {
+		if b != 0 {
+			if i == len(v)-1 && b == 0x80 {
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func fromBool(v bool) []byte {
+	if v {
+		return []byte{1}
+	}
+	return []byte{}
+}
+
+type stack [][]byte
+
+func (s *stack) push(v []byte) {
+	*s = append(*s, v)
+}
+
+func (s *stack) pop() ([]byte, error) {
+	if len(*s) == 0 {
+		return nil, &blockchain_errors.ErrStackUnderflow{}
+	}
+	v := (*s)[len(*s)-1]
+	*s = (*s)[:len(*s)-1]
+	return v, nil
+}
+
+// returns the element n positions below the top of the stack without removing it
+func (s *stack) peek(n int) ([]byte, error) {
+	if n < 0 || n >= len(*s) {
+		return nil, &blockchain_errors.ErrStackUnderflow{}
+	}
+	return (*s)[len(*s)-1-n], nil
+}
+
+func (s *stack) pushNum(n scriptNum) {
+	s.push(n.Bytes())
+}
+
+func (s *stack) popNum() (scriptNum, error) {
+	v, err := s.pop()
+	if err != nil {
+		return 0, err
+	}
+	return makeScriptNum(v, maxNumberSize)
+}
+
+func (s *stack) pushBool(v bool) {
+	s.push(fromBool(v))
+}
+
+func (s *stack) popBool() (bool, error) {
+	v, err := s.pop()
+	if err != nil {
+		return false, err
+	}
+	return asBool(v), nil
+}
@@ -0,0 +1,404 @@
+package script
+
+import (
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+type ScriptClass int
+
+const (
+	NonStandardTy ScriptClass = iota
+	PubKeyHashTy              //OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY OP_CHECKSIG
+	PubKeyTy                  //<pubKey> OP_CHECKSIG
+	MultiSigTy                //M <pubKey1> ... <pubKeyN> N OP_CHECKMULTISIG
+	ScriptHashTy              //OP_HASH160 <scriptHash> OP_EQUAL
+	HTLCTy                    //hash time-locked contract, see HTLCScript
+	NullDataTy                //OP_RETURN <data>
+)
+
+const pubKeyHashLen = 20
+const scriptHashLen = 20
+const htlcHashLen = 32
+
+func (class ScriptClass) String() string {
+	switch class {
+	case PubKeyHashTy:
+		return "pubkeyhash"
+	case PubKeyTy:
+		return "pubkey"
+	case MultiSigTy:
+		return "multisig"
+	case ScriptHashTy:
+		return "scripthash"
+	case HTLCTy:
+		return "htlc"
+	case NullDataTy:
+		return "nulldata"
+	default:
+		return "nonstandard"
+	}
+}
+
+func GetScriptClass(script []byte) ScriptClass {
+	ops, err := parseScript(script)
+	if err != nil {
+		return NonStandardTy
+	}
+
+	switch {
+	case isPubKeyHash(ops):
+		return PubKeyHashTy
+	case isPubKey(ops):
+		return PubKeyTy
+	case isMultisig(ops):
+		return MultiSigTy
+	case isScriptHash(ops):
+		return ScriptHashTy
+	case isHTLC(ops):
+		return HTLCTy
+	case isNullData(ops):
+		return NullDataTy
+	default:
+		return NonStandardTy
+	}
+}
+
+func isPubKeyHash(ops []parsedOpcode) bool {
+	return len(ops) == 5 &&
+		ops[0].opcode == OP_DUP &&
+		ops[1].opcode == OP_HASH160 &&
+		ops[2].opcode == pubKeyHashLen &&
+		ops[3].opcode == OP_EQUALVERIFY &&
+		ops[4].opcode == OP_CHECKSIG
+}
+
+func isPubKey(ops []parsedOpcode) bool {
+	return len(ops) == 2 &&
+		ops[0].opcode >= OP_DATA_1 && ops[0].opcode <= OP_DATA_75 &&
+		ops[1].opcode == OP_CHECKSIG
+}
+
+func isScriptHash(ops []parsedOpcode) bool {
+	return len(ops) == 3 &&
+		ops[0].opcode == OP_HASH160 &&
+		ops[1].opcode == scriptHashLen &&
+		ops[2].opcode == OP_EQUAL
+}
+
+// OP_RETURN fails the script as soon as it runs, so outputs locked with it can never be spent
+func isNullData(ops []parsedOpcode) bool {
+	if len(ops) == 0 || ops[0].opcode != OP_RETURN {
+		return false
+	}
+	return len(ops) == 1 || (len(ops) == 2 && isPushOpcode(ops[1].opcode))
+}
+
+func isSmallInt(opcode byte) bool {
+	return opcode == OP_0 || (opcode >= OP_1 && opcode <= OP_16)
+}
+
+func smallIntValue(opcode byte) int {
+	if opcode == OP_0 {
+		return 0
+	}
+	return int(opcode-OP_1) + 1
+}
+
+func isMultisig(ops []parsedOpcode) bool {
+	if len(ops) < 4 || ops[len(ops)-1].opcode != OP_CHECKMULTISIG {
+		return false
+	}
+	if !isSmallInt(ops[0].opcode) || !isSmallInt(ops[len(ops)-2].opcode) {
+		return false
+	}
+
+	numSignatures := smallIntValue(ops[0].opcode)
+	numPubKeys := smallIntValue(ops[len(ops)-2].opcode)
+	if numSignatures < 1 || numSignatures > numPubKeys || numPubKeys != len(ops)-3 {
+		return false
+	}
+
+	for _, op := range ops[1 : len(ops)-2] {
+		if op.opcode < OP_DATA_1 || op.opcode > OP_DATA_75 {
+			return false
+		}
+	}
+	return true
+}
+
+func PayToPubKeyHashScript(pubKeyHash []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddOp(OP_DUP).
+		AddOp(OP_HASH160).
+		AddData(pubKeyHash).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+func PayToPubKeyScript(pubKey []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddData(pubKey).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// provably unspendable locking script carrying arbitrary data
+func NullDataScript(data []byte) ([]byte, error) {
+	builder := NewScriptBuilder().AddOp(OP_RETURN)
+	if len(data) > 0 {
+		builder.AddData(data)
+	}
+	return builder.Script()
+}
+
+// returns the data carried by a null data script
+func ExtractNullData(script []byte) ([]byte, bool) {
+	ops, err := parseScript(script)
+	if err != nil || !isNullData(ops) {
+		return nil, false
+	}
+	if len(ops) == 1 {
+		return []byte{}, true
+	}
+
+	//single bytes which are small numbers are pushed with their own opcode
+	switch opcode := ops[1].opcode; {
+	case opcode == OP_1NEGATE:
+		return scriptNum(-1).Bytes(), true
+	case opcode >= OP_1 && opcode <= OP_16:
+		return scriptNum(smallIntValue(opcode)).Bytes(), true
+	default:
+		return ops[1].data, true
+	}
+}
+
+// locking script which is satisfied by revealing a redeem script with the given hash and satisfying it
+func PayToScriptHashScript(scriptHash []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddOp(OP_HASH160).
+		AddData(scriptHash).
+		AddOp(OP_EQUAL).
+		Script()
+}
+
+// returns the hash of the redeem script a pay to script hash script is locked to, or nil for other scripts
+func ExtractScriptHash(script []byte) []byte {
+	ops, err := parseScript(script)
+	if err != nil || !isScriptHash(ops) {
+		return nil
+	}
+	return ops[1].data
+}
+
+// appends the push of the redeem script to the unlocking script satisfying the redeem script
+func ScriptHashScriptSig(redeemScriptSig []byte, redeemScript []byte) ([]byte, error) {
+	pushedRedeemScript, err := NewScriptBuilder().AddData(redeemScript).Script()
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, redeemScriptSig...), pushedRedeemScript...), nil
+}
+
+// locking script requiring signatures for numSignatures of the pubkeys, which must be given in the same order as the keys
+func MultisigScript(numSignatures int, pubKeys [][]byte) ([]byte, error) {
+	if len(pubKeys) < 1 || len(pubKeys) > 16 {
+		return nil, &blockchain_errors.ErrInvalidPubKeyCount{}
+	}
+	if numSignatures < 1 || numSignatures > len(pubKeys) {
+		return nil, &blockchain_errors.ErrInvalidSignatureCount{}
+	}
+
+	builder := NewScriptBuilder().AddInt64(int64(numSignatures))
+	for _, pubKey := range pubKeys {
+		builder.AddData(pubKey)
+	}
+	return builder.AddInt64(int64(len(pubKeys))).AddOp(OP_CHECKMULTISIG).Script()
+}
+
+// returns the number of required signatures and the pubkeys of a multisig script
+func ExtractMultisig(script []byte) (int, [][]byte, bool) {
+	ops, err := parseScript(script)
+	if err != nil || !isMultisig(ops) {
+		return 0, nil, false
+	}
+
+	var pubKeys [][]byte
+	for _, op := range ops[1 : len(ops)-2] {
+		pubKeys = append(pubKeys, op.data)
+	}
+	return smallIntValue(ops[0].opcode), pubKeys, true
+}
+
+// unlocking script for multisig outputs, with the signatures in the same order as their pubkeys in the locking script
+func MultisigScriptSig(signatures [][]byte) ([]byte, error) {
+	builder := NewScriptBuilder()
+	for _, signature := range signatures {
+		builder.AddData(signature)
+	}
+	return builder.Script()
+}
+
+// unlocking script for pay to pubkey hash outputs
+func PubKeyHashScriptSig(signature []byte, pubKey []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddData(signature).
+		AddData(pubKey).
+		Script()
+}
+
+// unlocking script for pay to pubkey outputs
+func PubKeyScriptSig(signature []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddData(signature).
+		Script()
+}
+
+// returns the pubkey hash a pay to pubkey hash or pay to pubkey script is locked to, or nil for other scripts
+func ExtractPubKeyHash(script []byte) []byte {
+	ops, err := parseScript(script)
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case isPubKeyHash(ops):
+		return ops[2].data
+	case isPubKey(ops):
+		return utils.HashPublicKey(ops[0].data)
+	default:
+		return nil
+	}
+}
+
+// the parameters of a hash time-locked contract: the recipient can spend it by revealing the preimage of the hash,
+// and the sender can take it back once the lock time is reached
+type HTLCParams struct {
+	Hash                []byte //sha256 of the preimage
+	RecipientPubKeyHash []byte
+	SenderPubKeyHash    []byte
+	LockTime            int64
+}
+
+func (params HTLCParams) validate() error {
+	if len(params.Hash) != htlcHashLen {
+		return &blockchain_errors.ErrInvalidHTLC{}
+	}
+	if len(params.RecipientPubKeyHash) != pubKeyHashLen || len(params.SenderPubKeyHash) != pubKeyHashLen {
+		return &blockchain_errors.ErrInvalidHTLC{}
+	}
+	if params.LockTime <= 0 || params.LockTime > 0xffffffff {
+		return &blockchain_errors.ErrInvalidHTLC{}
+	}
+	return nil
+}
+
+// OP_IF
+//
+//	OP_SHA256 <hash> OP_EQUALVERIFY OP_DUP OP_HASH160 <recipientPubKeyHash>
+//
+// OP_ELSE
+//
+//	<lockTime> OP_CHECKLOCKTIMEVERIFY OP_DROP OP_DUP OP_HASH160 <senderPubKeyHash>
+//
+// OP_ENDIF
+// OP_EQUALVERIFY OP_CHECKSIG
+func HTLCScript(params HTLCParams) ([]byte, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return NewScriptBuilder().
+		AddOp(OP_IF).
+		AddOp(OP_SHA256).AddData(params.Hash).AddOp(OP_EQUALVERIFY).
+		AddOp(OP_DUP).AddOp(OP_HASH160).AddData(params.RecipientPubKeyHash).
+		AddOp(OP_ELSE).
+		AddInt64(params.LockTime).AddOp(OP_CHECKLOCKTIMEVERIFY).AddOp(OP_DROP).
+		AddOp(OP_DUP).AddOp(OP_HASH160).AddData(params.SenderPubKeyHash).
+		AddOp(OP_ENDIF).
+		AddOp(OP_EQUALVERIFY).
+		AddOp(OP_CHECKSIG).
+		Script()
+}
+
+// the lock time pushed by an HTLC script, which may be a small int opcode or a number push
+func htlcLockTime(op parsedOpcode) (int64, bool) {
+	if op.opcode >= OP_1 && op.opcode <= OP_16 {
+		return int64(smallIntValue(op.opcode)), true
+	}
+	if op.opcode < OP_DATA_1 || op.opcode > OP_DATA_75 {
+		return 0, false
+	}
+	lockTime, err := makeScriptNum(op.data, lockTimeNumberSize)
+	if err != nil || lockTime <= 0 {
+		return 0, false
+	}
+	return int64(lockTime), true
+}
+
+func isHTLC(ops []parsedOpcode) bool {
+	if len(ops) != 17 {
+		return false
+	}
+	_, validLockTime := htlcLockTime(ops[8])
+	return ops[0].opcode == OP_IF &&
+		ops[1].opcode == OP_SHA256 &&
+		ops[2].opcode == htlcHashLen &&
+		ops[3].opcode == OP_EQUALVERIFY &&
+		ops[4].opcode == OP_DUP &&
+		ops[5].opcode == OP_HASH160 &&
+		ops[6].opcode == pubKeyHashLen &&
+		ops[7].opcode == OP_ELSE &&
+		validLockTime &&
+		ops[9].opcode == OP_CHECKLOCKTIMEVERIFY &&
+		ops[10].opcode == OP_DROP &&
+		ops[11].opcode == OP_DUP &&
+		ops[12].opcode == OP_HASH160 &&
+		ops[13].opcode == pubKeyHashLen &&
+		ops[14].opcode == OP_ENDIF &&
+		ops[15].opcode == OP_EQUALVERIFY &&
+		ops[16].opcode == OP_CHECKSIG
+}
+
+// returns the parameters of an HTLC script
+func ExtractHTLC(script []byte) (*HTLCParams, bool) {
+	ops, err := parseScript(script)
+	if err != nil || !isHTLC(ops) {
+		return nil, false
+	}
+	lockTime, _ := htlcLockTime(ops[8])
+	return &HTLCParams{
+		Hash:                ops[2].data,
+		RecipientPubKeyHash: ops[6].data,
+		SenderPubKeyHash:    ops[13].data,
+		LockTime:            lockTime,
+	}, true
+}
+
+// unlocking script through which the recipient of an HTLC claims it, revealing the preimage
+func HTLCClaimScriptSig(signature []byte, pubKey []byte, preimage []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddData(signature).
+		AddData(pubKey).
+		AddData(preimage).
+		AddInt64(1).
+		Script()
+}
+
+// unlocking script through which the sender of an HTLC takes it back after its lock time
+func HTLCRefundScriptSig(signature []byte, pubKey []byte) ([]byte, error) {
+	return NewScriptBuilder().
+		AddData(signature).
+		AddData(pubKey).
+		AddInt64(0).
+		Script()
+}
+
+// returns the preimage revealed by an HTLC claim, so that the counterparty of a swap can learn it from the chain
+func ExtractHTLCPreimage(scriptSig []byte) ([]byte, bool) {
+	ops, err := parseScript(scriptSig)
+	if err != nil || len(ops) != 4 || ops[3].opcode != OP_1 {
+		return nil, false
+	}
+	return ops[2].data, true
+}
@@ -0,0 +1,238 @@
+// Package serialization implements the binary encoding used to hash, store and relay transactions and blocks.
+//
+// Every encoded object starts with a one byte encoding version (currently 1), which decoders require to match.
+// Changing the layout below or how ids are computed requires a new version.
+// Integers are little endian and fixed size, except counts and lengths, which are encoded as varints: values below
+// 0xfd take one byte, and larger values are written as 0xfd followed by a uint16, 0xfe followed by a uint32 or 0xff
+// followed by a uint64, always using the shortest form. Byte arrays are prefixed with their length.
+//
+//	transaction:  version byte | Version int32 | flags byte (1 if coinbase) | varint #inputs | inputs |
+//	              varint #outputs | outputs | LockTime uint32
+//	input:        Txid bytes | OutIndex int32 | ScriptSig bytes | Sequence uint32
+//	output:       Value int64 | ScriptPubKey bytes
+//	block header: version byte | Version int32 | PrevBlockHeaderHash bytes | MerkleRootHash bytes |
+//	              WitnessRootHash bytes | Timestamp int64 | Nonce uint32 | Height uint32
+//	block:        block header | varint #transactions | transactions, each prefixed with its length
+//	compact block: block header | Nonce uint64 | varint #short ids | short ids, 6 bytes each | varint #prefilled |
+//	              (varint index | transaction prefixed with its length), by increasing index
+//	UTXOs:        version byte | varint #outputs | (output index uint32 | output), by increasing output index
+//
+// A transaction's id is the sha256 of its encoding with the input scripts left empty (except for coinbases), so that
+// re-encoding its signatures doesn't change it, while its witness hash covers the whole encoding.
+// Transactions of version 0 are legacy transactions, converted from the chain stored with gob before this encoding
+// existed: their id is still the sha256 of their gob encoding, which covers their signatures.
+//
+// Decoding is strict: non minimal varints, unknown flags, lengths past the end of the data and trailing bytes are
+// rejected, so every object has exactly one encoding.
+package serialization
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+const EncodingVersion byte = 1
+
+type Writer struct {
+	buf bytes.Buffer
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// starts an encoded object with the encoding version
+func NewVersionedWriter() *Writer {
+	w := NewWriter()
+	w.WriteUint8(EncodingVersion)
+	return w
+}
+
+func (w *Writer) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+func (w *Writer) WriteUint8(v uint8) {
+	w.buf.WriteByte(v)
+}
+
+func (w *Writer) WriteUint32(v uint32) {
+	w.buf.Write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (w *Writer) WriteInt32(v int32) {
+	w.WriteUint32(uint32(v))
+}
+
+func (w *Writer) WriteUint64(v uint64) {
+	w.buf.Write(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+func (w *Writer) WriteInt64(v int64) {
+	w.WriteUint64(uint64(v))
+}
+
+func (w *Writer) WriteVarInt(v uint64) {
+	switch {
+	case v < 0xfd:
+		w.WriteUint8(uint8(v))
+	case v <= 0xffff:
+		w.WriteUint8(0xfd)
+		w.buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(v)))
+	case v <= 0xffffffff:
+		w.WriteUint8(0xfe)
+		w.WriteUint32(uint32(v))
+	default:
+		w.WriteUint8(0xff)
+		w.WriteUint64(v)
+	}
+}
+
+// number of bytes taken by the varint encoding of v
+func VarIntSize(v uint64) int {
+	switch {
+	case v < 0xfd:
+		return 1
+	case v <= 0xffff:
+		return 3
+	case v <= 0xffffffff:
+		return 5
+	default:
+		return 9
+	}
+}
+
+func (w *Writer) WriteVarBytes(data []byte) {
+	w.WriteVarInt(uint64(len(data)))
+	w.buf.Write(data)
+}
+
+// reads an encoded object, remembering the first error so that decoders only need to check it once at the end
+type Reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func NewReader(data []byte) *Reader {
+	return &Reader{data: data}
+}
+
+// starts reading an encoded object, checking its encoding version
+func NewVersionedReader(data []byte) *Reader {
+	r := NewReader(data)
+	if version := r.ReadUint8(); r.err == nil && version != EncodingVersion {
+		r.fail()
+	}
+	return r
+}
+
+func (r *Reader) fail() {
+	if r.err == nil {
+		r.err = &blockchain_errors.ErrMalformedEncoding{}
+	}
+}
+
+func (r *Reader) Remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *Reader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > r.Remaining() {
+		r.fail()
+		return nil
+	}
+	data := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return data
+}
+
+func (r *Reader) ReadUint8() uint8 {
+	data := r.next(1)
+	if data == nil {
+		return 0
+	}
+	return data[0]
+}
+
+func (r *Reader) ReadUint32() uint32 {
+	data := r.next(4)
+	if data == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(data)
+}
+
+func (r *Reader) ReadInt32() int32 {
+	return int32(r.ReadUint32())
+}
+
+func (r *Reader) ReadUint64() uint64 {
+	data := r.next(8)
+	if data == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(data)
+}
+
+func (r *Reader) ReadInt64() int64 {
+	return int64(r.ReadUint64())
+}
+
+// reads a varint, rejecting it unless it's in its shortest form
+func (r *Reader) ReadVarInt() uint64 {
+	var v, min uint64
+	switch prefix := r.ReadUint8(); prefix {
+	case 0xfd:
+		data := r.next(2)
+		if data == nil {
+			return 0
+		}
+		v, min = uint64(binary.LittleEndian.Uint16(data)), 0xfd
+	case 0xfe:
+		v, min = uint64(r.ReadUint32()), 0x10000
+	case 0xff:
+		v, min = r.ReadUint64(), 0x100000000
+	default:
+		return uint64(prefix)
+	}
+	if v < min {
+		r.fail()
+		return 0
+	}
+	return v
+}
+
+// reads the number of elements of a list, each taking at least minElementSize bytes, so that a corrupted count
+// can't make the decoder allocate more elements than the data could hold
+func (r *Reader) ReadCount(minElementSize int) int {
+	count := r.ReadVarInt()
+	if count > uint64(r.Remaining()/minElementSize) {
+		r.fail()
+		return 0
+	}
+	return int(count)
+}
+
+func (r *Reader) ReadVarBytes() []byte {
+	length := r.ReadVarInt()
+	if length > uint64(r.Remaining()) {
+		r.fail()
+		return nil
+	}
+	return append([]byte{}, r.next(int(length))...)
+}
+
+// returns the first error found while reading, or an error if not all of the data was read
+func (r *Reader) Finish() error {
+	if r.err == nil && r.Remaining() != 0 {
+		r.fail()
+	}
+	return r.err
+}
+
+func (r *Reader) Err() error {
+	return r.err
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+func (server *Server) blockFromParam(c *gin.Context) *blockchain.Block {
+	blockHash, err := hex.DecodeString(c.Param("hash"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid block hash format"})
+		return nil
+	}
+	block := server.bc.GetBlock(blockHash)
+	if block == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Block not found"})
+		return nil
+	}
+	return block
+}
+
+func (server *Server) MerkleProofHandler(c *gin.Context) {
+	block := server.blockFromParam(c)
+	if block == nil {
+		return
+	}
+	txHash, err := hex.DecodeString(c.Param("txid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction id format"})
+		return
+	}
+
+	proof, err := block.MerkleProof(txHash)
+	if errors.Is(err, &blockchain_errors.ErrTxNotInBlock{}) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building merkle proof"})
+		return
+	}
+
+	siblings := make([]string, len(proof.Siblings))
+	for i, sibling := range proof.Siblings {
+		siblings[i] = hex.EncodeToString(sibling)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"txid":       hex.EncodeToString(txHash),
+		"merkleRoot": hex.EncodeToString(block.Header.MerkleRootHash),
+		"leafIndex":  proof.LeafIndex,
+		"numLeaves":  proof.NumLeaves,
+		"siblings":   siblings,
+	})
+}
+
+// proves several transactions at once, given as a comma separated list of txids
+func (server *Server) PartialMerkleTreeHandler(c *gin.Context) {
+	block := server.blockFromParam(c)
+	if block == nil {
+		return
+	}
+	var txHashes [][]byte
+	for _, txid := range strings.Split(c.Query("txids"), ",") {
+		txHash, err := hex.DecodeString(txid)
+		if err != nil || len(txHash) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction id format"})
+			return
+		}
+		txHashes = append(txHashes, txHash)
+	}
+
+	pmt, err := block.PartialMerkleTree(txHashes)
+	if errors.Is(err, &blockchain_errors.ErrTxNotInBlock{}) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building partial merkle tree"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"merkleRoot":        hex.EncodeToString(block.Header.MerkleRootHash),
+		"partialMerkleTree": hex.EncodeToString(pmt.Serialize()),
+	})
+}
+
+func (server *Server) BlockFilterHandler(c *gin.Context) {
+	block := server.blockFromParam(c)
+	if block == nil {
+		return
+	}
+	blockHash := block.GetBlockHeaderHash()
+	filter, err := server.bc.GetBlockFilter(blockHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading block filter"})
+		return
+	}
+	filterHeader, err := server.bc.GetFilterHeader(blockHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading filter header"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"blockHash":    hex.EncodeToString(blockHash),
+		"filter":       hex.EncodeToString(filter.Serialize()),
+		"filterHeader": hex.EncodeToString(filterHeader),
+	})
+}
+
+// filter headers from the start height up to the block with the given hash
+func (server *Server) FilterHeadersHandler(c *gin.Context) {
+	stopHash, err := hex.DecodeString(c.Param("hash"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid block hash format"})
+		return
+	}
+	startHeight, err := strconv.Atoi(c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start height"})
+		return
+	}
+
+	filterHeaders, err := server.bc.FilterHeaders(startHeight, stopHash)
+	if errors.Is(err, &blockchain_errors.ErrBlockNotFound{}) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, &blockchain_errors.ErrInvalidFilterRange{}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading filter headers"})
+		return
+	}
+
+	encoded := make([]string, len(filterHeaders))
+	for i, filterHeader := range filterHeaders {
+		encoded[i] = hex.EncodeToString(filterHeader)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"startHeight":   startHeight,
+		"stopHash":      hex.EncodeToString(stopHash),
+		"filterHeaders": encoded,
+	})
+}
+
+func (server *Server) AddBlockRoutes(r *gin.Engine) {
+	blockRoutes := r.Group("/block")
+	{
+		blockRoutes.GET("/:hash/proof/:txid", server.MerkleProofHandler)
+		blockRoutes.GET("/:hash/partialmerkletree", server.PartialMerkleTreeHandler)
+		blockRoutes.GET("/:hash/filter", server.BlockFilterHandler)
+		blockRoutes.GET("/:hash/filterheaders", server.FilterHeadersHandler)
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"encoding/hex"
 	"log"
 	"strconv"
+
+	"github.com/pedrogomes29/blockchain_node/block_filter"
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
 )
 
 type commandID int
@@ -17,6 +21,15 @@ const (
 	INV
 	GET_DATA
 	DATA
+	GET_CFILTERS
+	GET_CFHEADERS
+	CFILTERS
+	CFHEADERS
+	GET_HEADERS
+	HEADERS
+	CMPCTBLOCK
+	GET_BLOCKTXN
+	BLOCKTXN
 )
 
 type objectType int
@@ -76,8 +89,11 @@ type objectEntries struct {
 func ParseObjects(args []string) objectEntries {
 	var blockEntries [][]byte
 	var txEntries [][]byte
-	for i := 0; i < len(args); i += 2 {
+	for i := 0; i+1 < len(args); i += 2 {
 		entryTypeString := args[i]
+		if len(args[i+1]) > MAX_ENTRY_SIZE { //rejects oversized entries before decoding them
+			continue
+		}
 		entry, _ := hex.DecodeString(args[i+1]) //TODO: Error handling
 
 		switch entryTypeString {
@@ -92,3 +108,148 @@ func ParseObjects(args []string) objectEntries {
 		txEntries:    txEntries,
 	}
 }
+
+type getCFHeadersPayload struct {
+	startHeight int
+	stopHash    []byte
+}
+
+func ParseGetCFHeadersPayload(args []string) (getCFHeadersPayload, error) {
+	if len(args) != 2 {
+		return getCFHeadersPayload{}, &blockchain_errors.ErrInvalidFilterRange{}
+	}
+	startHeight, err := strconv.Atoi(args[0])
+	if err != nil {
+		return getCFHeadersPayload{}, err
+	}
+	stopHash, err := hex.DecodeString(args[1])
+	if err != nil {
+		return getCFHeadersPayload{}, err
+	}
+	return getCFHeadersPayload{startHeight: startHeight, stopHash: stopHash}, nil
+}
+
+type cfilterEntry struct {
+	blockHash []byte
+	filter    *block_filter.Filter
+}
+
+func ParseCFiltersPayload(args []string) ([]cfilterEntry, error) {
+	if len(args)%2 != 0 {
+		return nil, &blockchain_errors.ErrMalformedEncoding{}
+	}
+	entries := make([]cfilterEntry, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		blockHash, err := hex.DecodeString(args[i])
+		if err != nil {
+			return nil, err
+		}
+		filterBytes, err := hex.DecodeString(args[i+1])
+		if err != nil {
+			return nil, err
+		}
+		filter, err := block_filter.DecodeFilter(filterBytes)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, cfilterEntry{blockHash: blockHash, filter: filter})
+	}
+	return entries, nil
+}
+
+type cfHeadersPayload struct {
+	stopHash      []byte
+	filterHeaders [][]byte
+}
+
+func ParseCFHeadersPayload(args []string) (cfHeadersPayload, error) {
+	if len(args) == 0 {
+		return cfHeadersPayload{}, &blockchain_errors.ErrInvalidFilterRange{}
+	}
+	stopHash, err := hex.DecodeString(args[0])
+	if err != nil {
+		return cfHeadersPayload{}, err
+	}
+	filterHeaders := make([][]byte, len(args)-1)
+	for i, arg := range args[1:] {
+		filterHeaders[i], err = hex.DecodeString(arg)
+		if err != nil {
+			return cfHeadersPayload{}, err
+		}
+	}
+	return cfHeadersPayload{stopHash: stopHash, filterHeaders: filterHeaders}, nil
+}
+
+func ParseHeadersPayload(args []string) ([]blockchain.BlockHeader, error) {
+	headers := make([]blockchain.BlockHeader, len(args))
+	for i, arg := range args {
+		headerBytes, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, err
+		}
+		headers[i], err = blockchain.DecodeBlockHeader(headerBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return headers, nil
+}
+
+func ParseCompactBlockPayload(args []string) ([]byte, error) {
+	if len(args) != 1 || len(args[0]) > MAX_ENTRY_SIZE {
+		return nil, &blockchain_errors.ErrMalformedEncoding{}
+	}
+	return hex.DecodeString(args[0])
+}
+
+type getBlockTxnPayload struct {
+	blockHash []byte
+	indexes   []int //positions of the requested transactions in the block
+}
+
+func ParseGetBlockTxnPayload(args []string) (getBlockTxnPayload, error) {
+	if len(args) == 0 {
+		return getBlockTxnPayload{}, &blockchain_errors.ErrMalformedEncoding{}
+	}
+	blockHash, err := hex.DecodeString(args[0])
+	if err != nil {
+		return getBlockTxnPayload{}, err
+	}
+	if len(args)-1 > MAX_BLOCKTXN_INDEXES {
+		return getBlockTxnPayload{}, &blockchain_errors.ErrMalformedEncoding{}
+	}
+	indexes := make([]int, len(args)-1)
+	for i, arg := range args[1:] {
+		indexes[i], err = strconv.Atoi(arg)
+		if err != nil {
+			return getBlockTxnPayload{}, err
+		}
+	}
+	return getBlockTxnPayload{blockHash: blockHash, indexes: indexes}, nil
+}
+
+type blockTxnPayload struct {
+	blockHash []byte
+	txEntries [][]byte
+}
+
+func ParseBlockTxnPayload(args []string) (blockTxnPayload, error) {
+	if len(args) == 0 {
+		return blockTxnPayload{}, &blockchain_errors.ErrMalformedEncoding{}
+	}
+	blockHash, err := hex.DecodeString(args[0])
+	if err != nil {
+		return blockTxnPayload{}, err
+	}
+	txEntries := make([][]byte, len(args)-1)
+	for i, arg := range args[1:] {
+		if len(arg) > MAX_ENTRY_SIZE {
+			return blockTxnPayload{}, &blockchain_errors.ErrMalformedEncoding{}
+		}
+		txEntries[i], err = hex.DecodeString(arg)
+		if err != nil {
+			return blockTxnPayload{}, err
+		}
+	}
+	return blockTxnPayload{blockHash: blockHash, txEntries: txEntries}, nil
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// connects two full nodes over a loopback connection, the second one syncing from the first
+func connectTestNodes(t *testing.T, node *Server, newPeer *Server) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go node.NewPeer(conn).ReadInput()
+	}()
+
+	for _, server := range []*Server{node, newPeer} {
+		go server.HandleTcpCommands()
+		go func(miningChan chan struct{}) { //no block is being mined when blocks are received
+			for range miningChan {
+			}
+		}(server.miningChan)
+	}
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting to node: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	newPeer.connect(conn)
+}
+
+func waitUntil(t *testing.T, condition func() bool, description string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting until %s", description)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// a mined block is relayed as a compact block, which the peer rebuilds from its memory pool and the one transaction
+// it didn't have
+func TestCompactBlockRelay(t *testing.T) {
+	alice, bob := newTestWallet(t), newTestWallet(t)
+	miner := newTestNode(t, alice)
+	miner.mineTestBlocks(2)
+	blocks := miner.bc.GetBlocksStartingAtHash([]byte{})
+
+	missingTx := spendTx(t, miner, alice, blocks[0].Transactions[0].Hash())
+	if err := miner.AddTxToMemPool(*missingTx); err != nil {
+		t.Fatalf("Error adding transaction: %s", err)
+	}
+
+	peer := newTestNode(t, bob)
+	connectTestNodes(t, miner, peer)
+	waitUntil(t, func() bool { return peer.bc.Height() == miner.bc.Height() }, "the peer syncs the blockchain")
+
+	relayedTx := spendTx(t, miner, alice, blocks[1].Transactions[0].Hash())
+	if err := miner.AddTxToMemPool(*relayedTx); err != nil {
+		t.Fatalf("Error adding transaction: %s", err)
+	}
+	waitUntil(t, func() bool { return peer.memoryPool.GetTxWithLock(relayedTx.Hash()) != nil }, "the peer receives the transaction")
+
+	miner.mineTestBlocks(1)
+	waitUntil(t, func() bool { return peer.bc.Height() == miner.bc.Height() }, "the peer adds the mined block")
+	if !bytes.Equal(peer.bc.LastBlockHash(), miner.bc.LastBlockHash()) {
+		t.Fatalf("Peer's last block isn't the mined block")
+	}
+	if len(miner.bc.GetBlock(miner.bc.LastBlockHash()).Transactions) != 3 {
+		t.Fatalf("Mined block doesn't include both transactions")
+	}
+
+	stats := peer.CompactBlockStats()
+	if stats.Blocks != 1 || stats.MissingTxs != 1 {
+		t.Fatalf("Peer reconstructed %d blocks requesting %d transactions, expected 1 block and 1 transaction", stats.Blocks, stats.MissingTxs)
+	}
+	if stats.SavedBytes <= 0 || stats.SavedBytes != stats.BlockBytes-stats.ReceivedBytes {
+		t.Fatalf("Compact block saved %d bytes, received %d bytes for a %d byte block", stats.SavedBytes, stats.ReceivedBytes, stats.BlockBytes)
+	}
+}
+
+// repeated or decreasing indexes are refused, so that a request can't make the answer larger than the block
+func TestGetBlockTxnIndexes(t *testing.T) {
+	alice := newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+	blockHash := node.bc.LastBlockHash()
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	go func() {
+		requestPeer := &peer{conn: local}
+		node.ReceiveGetBlockTxn(requestPeer, getBlockTxnPayload{blockHash: blockHash, indexes: []int{0, 0}})
+		node.ReceiveGetBlockTxn(requestPeer, getBlockTxnPayload{blockHash: blockHash, indexes: []int{0}})
+	}()
+
+	msg, _, err := readMessage(bufio.NewReader(remote))
+	if err != nil {
+		t.Fatalf("Error reading answer: %s", err)
+	}
+	payload, err := ParseBlockTxnPayload(strings.Split(msg, " ")[1:])
+	if err != nil || len(payload.txEntries) != 1 {
+		t.Fatalf("Request repeating an index was answered, or the valid request wasn't: %q", msg)
+	}
+}
+
+// compact blocks waiting for transactions are bounded, the oldest being dropped, and are only completed by the peer
+// which sent them
+func TestPendingCompactBlocks(t *testing.T) {
+	alice := newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+	coinbaseTxid := node.bc.GetBlock(node.bc.LastBlockHash()).Transactions[0].Hash()
+	missingTx := spendTx(t, node, alice, coinbaseTxid)
+
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	go io.Copy(io.Discard, remote) //GET_BLOCKTXN requests
+	sender, otherPeer := &peer{conn: local}, &peer{conn: local}
+
+	var blockHashes []string
+	subsidy := transactions.BlockSubsidy(node.bc.Height() + 1)
+	for i := 0; i <= MAX_PENDING_COMPACT_BLOCKS; i++ {
+		block := mineCoinbaseBlock(t, node, alice, subsidy-transactions.Amount(i), missingTx)
+		blockHashes = append(blockHashes, hex.EncodeToString(block.GetBlockHeaderHash()))
+		node.ReceiveCompactBlock(sender, blockchain.NewCompactBlock(block).Serialize())
+	}
+	if len(node.pendingCompactBlocks) != MAX_PENDING_COMPACT_BLOCKS || node.pendingCompactBlocks[blockHashes[0]] != nil {
+		t.Fatalf("%d compact blocks are waiting, the oldest one included: %t", len(node.pendingCompactBlocks),
+			node.pendingCompactBlocks[blockHashes[0]] != nil)
+	}
+
+	lastHash, _ := hex.DecodeString(blockHashes[MAX_PENDING_COMPACT_BLOCKS])
+	node.ReceiveBlockTxn(otherPeer, blockTxnPayload{blockHash: lastHash, txEntries: [][]byte{missingTx.Serialize()}})
+	if node.pendingCompactBlocks[blockHashes[MAX_PENDING_COMPACT_BLOCKS]] == nil {
+		t.Fatalf("Compact block was completed by another peer")
+	}
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// a compact block waiting for the transactions which weren't in the memory pool
+type pendingCompactBlock struct {
+	partialBlock  *blockchain.PartialBlock
+	receivedBytes int
+	peer          *peer //only the peer which sent the compact block can complete it
+	receivedAt    time.Time
+}
+
+type CompactBlockStats struct {
+	Blocks        int `json:"blocks"`        //blocks reconstructed from compact blocks
+	MissingTxs    int `json:"missingTxs"`    //transactions requested because they weren't in the memory pool
+	ReceivedBytes int `json:"receivedBytes"` //encoded size of the compact blocks and of the requested transactions
+	BlockBytes    int `json:"blockBytes"`    //encoded size of the reconstructed blocks, had they been sent whole
+	SavedBytes    int `json:"savedBytes"`
+}
+
+func (server *Server) CompactBlockStats() CompactBlockStats {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	stats := server.compactBlockStats
+	stats.SavedBytes = stats.BlockBytes - stats.ReceivedBytes
+	return stats
+}
+
+// announces a single new block as a compact block, since it's most likely a newly mined block whose transactions
+// peers already have, while several blocks are a chain being synced, which peers fetch whole
+func (server *Server) AnnounceBlocks(blockHashes [][]byte) {
+	if len(blockHashes) == 1 {
+		if block := server.bc.GetBlock(blockHashes[0]); block != nil {
+			compactBlock := hex.EncodeToString(blockchain.NewCompactBlock(block).Serialize())
+			for _, peer := range server.peers {
+				peer.sendString("CMPCTBLOCK " + compactBlock)
+			}
+			return
+		}
+	}
+	server.BroadcastObjects(INV, objectEntries{
+		blockEntries: blockHashes,
+	})
+}
+
+// rebuilds a block extending the tip from the memory pool, requesting the transactions which aren't in it with
+// "GET_BLOCKTXN <block hash> <index> ...". other blocks are requested whole, so that forks are handled as usual
+func (server *Server) ReceiveCompactBlock(requestPeer *peer, compactBlockBytes []byte) {
+	compactBlock, err := blockchain.DecodeCompactBlock(compactBlockBytes)
+	if err != nil {
+		return
+	}
+	blockHash := compactBlock.Header.Hash()
+	blockHashStr := hex.EncodeToString(blockHash)
+	if server.bc.GetBlock(blockHash) != nil || server.pendingCompactBlocks[blockHashStr] != nil {
+		return
+	}
+	if !compactBlock.Header.ValidateNonce() {
+		return
+	}
+	lastBlockHash := server.bc.LastBlockHash()
+	if !bytes.Equal(compactBlock.Header.PrevBlockHeaderHash, lastBlockHash) {
+		requestPeer.SendObjects(GET_DATA, objectEntries{
+			blockEntries: [][]byte{blockHash},
+		})
+		return
+	}
+
+	//blocks still waiting for transactions no longer extend the tip
+	for pendingHash, pending := range server.pendingCompactBlocks {
+		if !bytes.Equal(pending.partialBlock.Header.PrevBlockHeaderHash, lastBlockHash) {
+			delete(server.pendingCompactBlocks, pendingHash)
+		}
+	}
+
+	partialBlock := compactBlock.Reconstruct(server.memoryPool.GetAllTxsWithLock())
+	missingIndexes := partialBlock.MissingIndexes()
+	if len(missingIndexes) == 0 {
+		server.addCompactBlock(requestPeer, partialBlock, len(compactBlockBytes), 0)
+		return
+	}
+
+	if len(server.pendingCompactBlocks) >= MAX_PENDING_COMPACT_BLOCKS {
+		server.evictOldestPendingCompactBlock()
+	}
+	server.pendingCompactBlocks[blockHashStr] = &pendingCompactBlock{
+		partialBlock:  partialBlock,
+		receivedBytes: len(compactBlockBytes),
+		peer:          requestPeer,
+		receivedAt:    time.Now(),
+	}
+	var sb strings.Builder
+	sb.WriteString("GET_BLOCKTXN " + blockHashStr)
+	for _, index := range missingIndexes {
+		sb.WriteString(" " + strconv.Itoa(index))
+	}
+	requestPeer.sendString(sb.String())
+}
+
+func (server *Server) evictOldestPendingCompactBlock() {
+	var oldestHash string
+	var oldest *pendingCompactBlock
+	for pendingHash, pending := range server.pendingCompactBlocks {
+		if oldest == nil || pending.receivedAt.Before(oldest.receivedAt) {
+			oldestHash, oldest = pendingHash, pending
+		}
+	}
+	delete(server.pendingCompactBlocks, oldestHash)
+}
+
+// answers with "BLOCKTXN <block hash> <tx> ..." with the requested transactions of a known block. the indexes must
+// be strictly increasing, so that each transaction is sent at most once. if the transactions don't fit in a message,
+// the block is sent whole instead
+func (server *Server) ReceiveGetBlockTxn(requestPeer *peer, payload getBlockTxnPayload) {
+	block := server.bc.GetBlock(payload.blockHash)
+	if block == nil {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("BLOCKTXN " + hex.EncodeToString(payload.blockHash))
+	for i, index := range payload.indexes {
+		if index < 0 || index >= len(block.Transactions) || (i > 0 && index <= payload.indexes[i-1]) {
+			return
+		}
+		txEntry := " " + hex.EncodeToString(block.Transactions[index].Serialize())
+		if sb.Len()+len(txEntry)+len("\n") > MAX_MESSAGE_SIZE {
+			requestPeer.SendObjects(DATA, objectEntries{
+				blockEntries: [][]byte{block.Serialize()},
+			})
+			return
+		}
+		sb.WriteString(txEntry)
+	}
+	requestPeer.sendString(sb.String())
+}
+
+// completes a compact block with the transactions it was waiting for, requesting the block whole if they don't
+// match its header
+func (server *Server) ReceiveBlockTxn(requestPeer *peer, payload blockTxnPayload) {
+	blockHashStr := hex.EncodeToString(payload.blockHash)
+	pending := server.pendingCompactBlocks[blockHashStr]
+	if pending == nil || pending.peer != requestPeer {
+		return
+	}
+	delete(server.pendingCompactBlocks, blockHashStr)
+
+	receivedBytes := pending.receivedBytes
+	txs := make([]*transactions.Transaction, len(payload.txEntries))
+	for i, txBytes := range payload.txEntries {
+		tx, err := transactions.DecodeTransaction(txBytes)
+		if err != nil {
+			return
+		}
+		txs[i] = tx
+		receivedBytes += len(txBytes)
+	}
+
+	if err := pending.partialBlock.FillMissing(txs); err != nil {
+		fmt.Println("Error filling compact block")
+		fmt.Println(err.Error())
+		return
+	}
+	server.addCompactBlock(requestPeer, pending.partialBlock, receivedBytes, len(txs))
+}
+
+func (server *Server) addCompactBlock(requestPeer *peer, partialBlock *blockchain.PartialBlock, receivedBytes int, missingTxs int) {
+	block, err := partialBlock.Block()
+	if err != nil {
+		//a short id matched the wrong transaction, or the peer sent the wrong transactions
+		requestPeer.SendObjects(GET_DATA, objectEntries{
+			blockEntries: [][]byte{partialBlock.Header.Hash()},
+		})
+		return
+	}
+
+	blockBytes := block.Serialize()
+	newBlocksHashes := server.ReceiveBlocks(requestPeer, [][]byte{blockBytes})
+	if len(newBlocksHashes) == 0 {
+		return
+	}
+
+	server.mu.Lock()
+	server.compactBlockStats.Blocks++
+	server.compactBlockStats.MissingTxs += missingTxs
+	server.compactBlockStats.ReceivedBytes += receivedBytes
+	server.compactBlockStats.BlockBytes += len(blockBytes)
+	server.mu.Unlock()
+	fmt.Printf("Reconstructed block %d from a compact block, requesting %d of its %d transactions: received %d bytes instead of %d\n",
+		block.Header.Height, missingTxs, len(block.Transactions), receivedBytes, len(blockBytes))
+
+	server.AnnounceBlocks(newBlocksHashes)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestDataOutputs(t *testing.T) {
+	wallet := newTestWallet(t)
+	node := newTestNode(t, wallet)
+	node.mineTestBlocks(1)
+
+	tooLarge, _ := transactions.NewDataOutput(make([]byte, transactions.DefaultMaxDataCarrierSize+1))
+	if err := node.AddTxToMemPool(*spendTx(t, node, wallet, nil, *tooLarge)); !errors.Is(err, &blockchain_errors.ErrDataCarrierTooLarge{}) {
+		t.Fatalf("Data output larger than the maximum size was accepted: %v", err)
+	}
+
+	withValue, _ := transactions.NewDataOutput([]byte("document hash"))
+	withValue.Value = 1
+	if err := node.AddTxToMemPool(*spendTx(t, node, wallet, nil, *withValue)); !errors.Is(err, &blockchain_errors.ErrNonZeroDataOutput{}) {
+		t.Fatalf("Data output with value was accepted: %v", err)
+	}
+
+	dataOutput, _ := transactions.NewDataOutput([]byte("document hash"))
+	tx := spendTx(t, node, wallet, nil, *dataOutput)
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("Data output was rejected: %s", err)
+	}
+	node.mineTestBlocks(1)
+
+	if _, err := transactions.GetUTXO(node.bc.ChainstateDB, tx.Hash(), 1); err == nil {
+		t.Fatalf("Data output was stored in the UTXO set")
+	}
+	if _, err := transactions.GetUTXO(node.bc.ChainstateDB, tx.Hash(), 0); err != nil {
+		t.Fatalf("Spendable output wasn't stored in the UTXO set: %s", err)
+	}
+
+	confirmedTx, _, _, err := node.FindTransaction(tx.Hash())
+	if err != nil {
+		t.Fatalf("Transaction wasn't found: %s", err)
+	}
+	if data, isData := confirmedTx.Vout[1].Data(); !isData || !bytes.Equal(data, []byte("document hash")) {
+		t.Fatalf("Data output wasn't found in the confirmed transaction")
+	}
+}
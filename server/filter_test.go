@@ -0,0 +1,48 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/block_filter"
+)
+
+// a wallet finds the blocks paying to it from their filters, which are committed to by the filter headers
+func TestBlockFilters(t *testing.T) {
+	alice, bob := newTestWallet(t), newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(3)
+
+	prevFilterHeader := block_filter.GenesisPrevFilterHeader
+	blocks := node.bc.GetBlocksStartingAtHash([]byte{})
+	for _, block := range blocks {
+		blockHash := block.GetBlockHeaderHash()
+		filter, err := node.bc.GetBlockFilter(blockHash)
+		if err != nil {
+			t.Fatalf("Error reading filter of block %d: %s", block.Header.Height, err)
+		}
+		key := block_filter.FilterKey(blockHash)
+		if match, _ := filter.Match(key, alice.pubKeyHash); !match {
+			t.Fatalf("Filter of block %d doesn't match the miner's pubkey hash", block.Header.Height)
+		}
+		if match, _ := filter.Match(key, bob.pubKeyHash); match {
+			t.Fatalf("Filter of block %d matches an unrelated pubkey hash", block.Header.Height)
+		}
+
+		filterHeader, _ := node.bc.GetFilterHeader(blockHash)
+		if !bytes.Equal(filterHeader, block_filter.FilterHeader(filter.Hash(), prevFilterHeader)) {
+			t.Fatalf("Filter header of block %d doesn't chain to the previous one", block.Header.Height)
+		}
+		prevFilterHeader = filterHeader
+	}
+
+	lastBlockHash := node.bc.LastBlockHash()
+	filterHeaders, err := node.bc.FilterHeaders(1, lastBlockHash)
+	if err != nil || len(filterHeaders) != len(blocks)-1 || !bytes.Equal(filterHeaders[len(filterHeaders)-1], prevFilterHeader) {
+		t.Fatalf("Filter headers from height 1 don't match the blocks' filter headers: %v", err)
+	}
+	if _, err := node.bc.FilterHeaders(len(blocks), lastBlockHash); err == nil {
+		t.Fatalf("Filter headers starting after the stop block were returned")
+	}
+
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+type htlcScriptRequest struct {
+	Hash                []byte `json:"hash"`
+	RecipientPubKeyHash []byte `json:"recipientPubKeyHash"`
+	SenderPubKeyHash    []byte `json:"senderPubKeyHash"`
+	LockTime            int64  `json:"lockTime"`
+}
+
+type htlcSpendRequest struct {
+	Txid      []byte              `json:"txid"`
+	OutIndex  int                 `json:"outIndex"`
+	Address   string              `json:"address"` //receives the HTLC's value minus the fee
+	Fee       transactions.Amount `json:"fee"`
+	PubKey    []byte              `json:"pubKey"`
+	Signature []byte              `json:"signature"` //ends with its hash type, if empty the unsigned transaction is returned
+	Preimage  []byte              `json:"preimage"`  //only for claims
+}
+
+// builds an unsigned transaction spending an HTLC output
+func (server *Server) BuildHTLCSpend(request htlcSpendRequest, refund bool) (*transactions.Transaction, error) {
+	htlc, err := transactions.GetUTXO(server.bc.ChainstateDB, request.Txid, request.OutIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	value := htlc.Value - request.Fee
+	if request.Fee < 0 || value <= 0 {
+		return nil, &blockchain_errors.ErrAmountOutOfRange{}
+	}
+	destination, err := transactions.NewTXOutput(value, request.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions.NewHTLCSpendTx(request.Txid, request.OutIndex, htlc, *destination, refund)
+}
+
+func (server *Server) HTLCScriptHandler(c *gin.Context) {
+	var request htlcScriptRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid HTLC format"})
+		return
+	}
+
+	scriptPubKey, err := script.HTLCScript(script.HTLCParams{
+		Hash:                request.Hash,
+		RecipientPubKeyHash: request.RecipientPubKeyHash,
+		SenderPubKeyHash:    request.SenderPubKeyHash,
+		LockTime:            request.LockTime,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scriptPubKey": scriptPubKey,
+		"asm":          script.Disassemble(scriptPubKey),
+	})
+}
+
+func (server *Server) HTLCClaimHandler(c *gin.Context) {
+	server.htlcSpendHandler(c, false)
+}
+
+func (server *Server) HTLCRefundHandler(c *gin.Context) {
+	server.htlcSpendHandler(c, true)
+}
+
+func (server *Server) htlcSpendHandler(c *gin.Context, refund bool) {
+	var request htlcSpendRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid HTLC spend format"})
+		return
+	}
+
+	tx, err := server.BuildHTLCSpend(request, refund)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(request.Signature) == 0 {
+		sigHash, _, err := server.SignatureHash(*tx, 0, transactions.SigHashAll)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"transaction": tx, "sigHash": sigHash})
+		return
+	}
+
+	var scriptSig []byte
+	if refund {
+		scriptSig, err = script.HTLCRefundScriptSig(request.Signature, request.PubKey)
+	} else {
+		scriptSig, err = script.HTLCClaimScriptSig(request.Signature, request.PubKey, request.Preimage)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tx.Vin[0].ScriptSig = scriptSig
+
+	if err := server.AddTxToMemPool(*tx); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"transaction": tx})
+}
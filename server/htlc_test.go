@@ -0,0 +1,145 @@
+package server
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// funds an HTLC from the wallet's coins and mines it
+func fundHTLC(t *testing.T, server *Server, funder *testWallet, params script.HTLCParams) *transactions.Transaction {
+	tx := spendTx(t, server, funder, nil)
+	htlc, err := transactions.NewHTLCOutput(tx.Vout[0].Value, params)
+	if err != nil {
+		t.Fatalf("Error building HTLC output: %s", err)
+	}
+	tx.Vout = []transactions.TXOutput{*htlc}
+	funder.signInputs(t, server, tx)
+	if err := server.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("HTLC funding was rejected: %s", err)
+	}
+	server.mineTestBlocks(1)
+	return tx
+}
+
+// builds, signs and submits a claim (or refund) of an HTLC output
+func spendHTLC(t *testing.T, server *Server, spender *testWallet, htlcTx *transactions.Transaction, preimage []byte, refund bool) (*transactions.Transaction, error) {
+	tx, err := server.BuildHTLCSpend(htlcSpendRequest{Txid: htlcTx.Hash(), OutIndex: 0, Address: spender.address, Fee: 1}, refund)
+	if err != nil {
+		t.Fatalf("Error building HTLC spend: %s", err)
+	}
+
+	signature := spender.sign(t, server, tx, 0, transactions.SigHashAll)
+	if refund {
+		tx.Vin[0].ScriptSig, _ = script.HTLCRefundScriptSig(signature, spender.pubKey)
+	} else {
+		tx.Vin[0].ScriptSig, _ = script.HTLCClaimScriptSig(signature, spender.pubKey, preimage)
+	}
+	return tx, server.AddTxToMemPool(*tx)
+}
+
+func balance(t *testing.T, server *Server, wallet *testWallet) transactions.Amount {
+	utxos, err := server.FindUTXOs(wallet.pubKeyHash)
+	if err != nil {
+		t.Fatalf("Error finding UTXOs: %s", err)
+	}
+	var total transactions.Amount
+	for _, utxo := range utxos {
+		total += utxo.Value
+	}
+	return total
+}
+
+// alice swaps her coins on node A for bob's coins on node B
+func TestHTLCAtomicSwap(t *testing.T) {
+	alice, bob := newTestWallet(t), newTestWallet(t)
+	nodeA, nodeB := newTestNode(t, alice), newTestNode(t, bob)
+	nodeA.mineTestBlocks(1)
+	nodeB.mineTestBlocks(1)
+
+	preimage := []byte("alice's swap secret")
+	hash := sha256.Sum256(preimage)
+
+	//alice's refund must only be possible after bob's, so that she can't claim bob's coins and then take hers back
+	aliceHTLC := fundHTLC(t, nodeA, alice, script.HTLCParams{
+		Hash:                hash[:],
+		RecipientPubKeyHash: bob.pubKeyHash,
+		SenderPubKeyHash:    alice.pubKeyHash,
+		LockTime:            int64(nodeA.bc.Height() + 20),
+	})
+	bobHTLC := fundHTLC(t, nodeB, bob, script.HTLCParams{
+		Hash:                hash[:],
+		RecipientPubKeyHash: alice.pubKeyHash,
+		SenderPubKeyHash:    bob.pubKeyHash,
+		LockTime:            int64(nodeB.bc.Height() + 10),
+	})
+
+	if _, err := spendHTLC(t, nodeA, alice, aliceHTLC, nil, true); !errors.Is(err, &blockchain_errors.ErrNonFinalTx{}) {
+		t.Fatalf("Refund before the lock time was accepted: %v", err)
+	}
+	if _, err := spendHTLC(t, nodeB, alice, bobHTLC, []byte("guess"), false); err == nil {
+		t.Fatalf("Claim with the wrong preimage was accepted")
+	}
+
+	//alice claims bob's coins, revealing the preimage on node B's chain
+	aliceClaim, err := spendHTLC(t, nodeB, alice, bobHTLC, preimage, false)
+	if err != nil {
+		t.Fatalf("Alice's claim was rejected: %s", err)
+	}
+	nodeB.mineTestBlocks(1)
+
+	confirmedClaim, _, err := nodeB.bc.FindTransaction(aliceClaim.Hash())
+	if err != nil {
+		t.Fatalf("Alice's claim wasn't mined: %s", err)
+	}
+	revealed, ok := script.ExtractHTLCPreimage(confirmedClaim.Vin[0].ScriptSig)
+	if !ok {
+		t.Fatalf("Preimage couldn't be extracted from alice's claim")
+	}
+
+	//bob uses the revealed preimage to claim alice's coins
+	if _, err := spendHTLC(t, nodeA, bob, aliceHTLC, revealed, false); err != nil {
+		t.Fatalf("Bob's claim was rejected: %s", err)
+	}
+	nodeA.mineTestBlocks(1)
+
+	if balance(t, nodeA, bob) == 0 {
+		t.Fatalf("Bob didn't receive alice's coins")
+	}
+	if balance(t, nodeB, alice) == 0 {
+		t.Fatalf("Alice didn't receive bob's coins")
+	}
+}
+
+func TestHTLCRefund(t *testing.T) {
+	alice, bob := newTestWallet(t), newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+
+	hash := sha256.Sum256([]byte("never revealed"))
+	lockTime := node.bc.Height() + 3
+	htlcTx := fundHTLC(t, node, alice, script.HTLCParams{
+		Hash:                hash[:],
+		RecipientPubKeyHash: bob.pubKeyHash,
+		SenderPubKeyHash:    alice.pubKeyHash,
+		LockTime:            int64(lockTime),
+	})
+
+	for node.bc.Height()+1 <= lockTime {
+		if _, err := spendHTLC(t, node, alice, htlcTx, nil, true); err == nil {
+			t.Fatalf("Refund at height %d was accepted before the lock time", node.bc.Height()+1)
+		}
+		node.mineTestBlocks(1)
+	}
+
+	if _, err := spendHTLC(t, node, bob, htlcTx, nil, true); err == nil {
+		t.Fatalf("Refund by the recipient was accepted")
+	}
+	if _, err := spendHTLC(t, node, alice, htlcTx, nil, true); err != nil {
+		t.Fatalf("Refund after the lock time was rejected: %s", err)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// a tampered copy of a block doesn't stop the genuine block with the same hash from being accepted, while a block
+// failing validation is remembered as invalid
+func TestReceiveMutatedBlock(t *testing.T) {
+	alice := newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+	go func() { //no block is being mined when blocks are received
+		for range node.miningChan {
+		}
+	}()
+
+	subsidy := transactions.BlockSubsidy(node.bc.Height() + 1)
+	genuine := mineCoinbaseBlock(t, node, alice, subsidy)
+	mutated := *genuine
+	tamperedCoinbase := *genuine.Transactions[0]
+	tamperedCoinbase.Vout = append([]transactions.TXOutput{}, genuine.Transactions[0].Vout...)
+	tamperedCoinbase.Vout[0].Value--
+	mutated.Transactions = []*transactions.Transaction{&tamperedCoinbase}
+	if !bytes.Equal(mutated.GetBlockHeaderHash(), genuine.GetBlockHeaderHash()) {
+		t.Fatalf("Tampered block doesn't have the genuine block's hash")
+	}
+
+	if added := node.ReceiveBlocks(nil, [][]byte{mutated.Serialize()}); added != nil {
+		t.Fatalf("Tampered block was added")
+	}
+	if len(node.invalidBlocks) != 0 {
+		t.Fatalf("Tampered block was remembered as invalid")
+	}
+	added := node.ReceiveBlocks(nil, [][]byte{genuine.Serialize()})
+	if len(added) != 1 || !bytes.Equal(node.bc.LastBlockHash(), genuine.GetBlockHeaderHash()) {
+		t.Fatalf("Genuine block wasn't added after a tampered copy was received")
+	}
+
+	invalid := mineCoinbaseBlock(t, node, alice, transactions.BlockSubsidy(node.bc.Height()+1)+1)
+	if added := node.ReceiveBlocks(nil, [][]byte{invalid.Serialize()}); added != nil {
+		t.Fatalf("Block with a coinbase paying more than the subsidy was added")
+	}
+	if !node.invalidBlocks[hex.EncodeToString(invalid.GetBlockHeaderHash())] {
+		t.Fatalf("Block failing validation wasn't remembered as invalid")
+	}
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/light_client"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// a node which syncs headers from full nodes and downloads only the blocks whose filters match its watched scripts
+type LightServer struct {
+	client   *light_client.LightClient
+	peers    map[string]*peer
+	commands chan command
+	scanning bool //whether filter headers, filters or a block were requested and haven't arrived yet
+}
+
+func NewLightServer(client *light_client.LightClient, seedAddrs []string) *LightServer {
+	server := &LightServer{
+		client:   client,
+		peers:    make(map[string]*peer),
+		commands: make(chan command),
+	}
+
+	for _, seedAddress := range seedAddrs {
+		server.ConnectToAddress(seedAddress)
+	}
+	return server
+}
+
+func (server *LightServer) ConnectToAddress(address string) {
+	if _, ok := server.peers[address]; ok { //if address is already known
+		return
+	}
+
+	conn, err := net.Dial("tcp", address+":"+BLOCKCHAIN_PORT)
+	if err != nil {
+		log.Panic("Error establishing connection: ", err)
+		return
+	}
+	server.connect(conn)
+}
+
+func (server *LightServer) connect(conn net.Conn) {
+	newPeer := &peer{
+		conn:     conn,
+		commands: server.commands,
+	}
+	newPeer.sendString("VERSION" + " " + strconv.Itoa(server.client.Height()))
+	go newPeer.ReadInput()
+}
+
+func (server *LightServer) ReceiveVersion(requestPeer *peer, payload versionPayload) {
+	if !payload.ACK {
+		requestPeer.sendString("VERSION" + " " + strconv.Itoa(server.client.Height()) + " " + "ACK")
+		return
+	}
+	requestPeer.sendString("VERSION_ACK")
+	server.ReceiveVersionAck(requestPeer)
+}
+
+func (server *LightServer) ReceiveVersionAck(requestPeer *peer) {
+	fmt.Printf("Connected to peer:%s\n", requestPeer.GetAddress())
+	server.peers[requestPeer.GetAddress()] = requestPeer
+	server.SendGetHeaders(requestPeer)
+}
+
+func (server *LightServer) SendGetHeaders(requestPeer *peer) {
+	var sb strings.Builder
+	sb.WriteString("GET_HEADERS")
+	for _, blockHash := range server.client.Locator() {
+		sb.WriteString(" " + hex.EncodeToString(blockHash))
+	}
+	requestPeer.sendString(sb.String())
+}
+
+func (server *LightServer) ReceiveHeaders(requestPeer *peer, headers []blockchain.BlockHeader) {
+	if err := server.client.AddHeaders(headers); err != nil {
+		fmt.Println("Error adding headers")
+		fmt.Println(err.Error())
+	}
+	if len(headers) == MAX_HEADERS { //the peer may have more headers
+		server.SendGetHeaders(requestPeer)
+	}
+
+	//a request made before a reorg may never be answered, so scanning restarts
+	server.scanning = false
+	server.continueScan(requestPeer)
+}
+
+// announced blocks, whole or compact, are only downloaded if their filters match, after their headers
+func (server *LightServer) ReceiveInv(requestPeer *peer, payload objectEntries) {
+	if len(payload.blockEntries) > 0 {
+		server.SendGetHeaders(requestPeer)
+	}
+}
+
+// requests what's needed to scan the next blocks: their filter headers if they aren't known, otherwise their filters
+func (server *LightServer) continueScan(requestPeer *peer) {
+	if server.scanning {
+		return
+	}
+	nextHeight := server.client.ScannedHeight() + 1
+	height := server.client.Height()
+	if nextHeight > height {
+		return
+	}
+
+	stopHeight := min(nextHeight+MAX_CFILTERS-1, height)
+	nextHash, err := server.client.HashAtHeight(nextHeight)
+	if err != nil {
+		log.Panic(err)
+	}
+	if _, err = server.client.GetFilterHeader(nextHash); err != nil {
+		stopHash, err := server.client.HashAtHeight(stopHeight)
+		if err != nil {
+			log.Panic(err)
+		}
+		requestPeer.sendString("GET_CFHEADERS " + strconv.Itoa(nextHeight) + " " + hex.EncodeToString(stopHash))
+		server.scanning = true
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("GET_CFILTERS")
+	for height := nextHeight; height <= stopHeight; height++ {
+		blockHash, err := server.client.HashAtHeight(height)
+		if err != nil {
+			log.Panic(err)
+		}
+		if _, err = server.client.GetFilterHeader(blockHash); err != nil {
+			break
+		}
+		sb.WriteString(" " + hex.EncodeToString(blockHash))
+	}
+	requestPeer.sendString(sb.String())
+	server.scanning = true
+}
+
+func (server *LightServer) ReceiveCFHeaders(requestPeer *peer, payload cfHeadersPayload) {
+	server.scanning = false
+	if err := server.client.AddFilterHeaders(payload.stopHash, payload.filterHeaders); err != nil {
+		fmt.Println("Error adding filter headers")
+		fmt.Println(err.Error())
+		return
+	}
+	server.continueScan(requestPeer)
+}
+
+// checks the filters in order, stopping at the first one which matches to download its block
+func (server *LightServer) ReceiveCFilters(requestPeer *peer, entries []cfilterEntry) {
+	server.scanning = false
+	for _, entry := range entries {
+		nextHeight := server.client.ScannedHeight() + 1
+		nextHash, err := server.client.HashAtHeight(nextHeight)
+		if err != nil || !bytes.Equal(entry.blockHash, nextHash) { //answers a request made before a reorg
+			continue
+		}
+		if err = server.client.VerifyFilter(nextHeight, entry.filter); err != nil {
+			fmt.Println("Error verifying block filter")
+			fmt.Println(err.Error())
+			return
+		}
+		matched, err := server.client.MatchFilter(nextHeight, entry.filter)
+		if err != nil {
+			fmt.Println("Error matching block filter")
+			fmt.Println(err.Error())
+			return
+		}
+		if matched {
+			requestPeer.SendObjects(GET_DATA, objectEntries{
+				blockEntries: [][]byte{nextHash},
+			})
+			server.scanning = true
+			return
+		}
+		if err = server.client.SkipBlock(nextHeight); err != nil {
+			log.Panic(err)
+		}
+	}
+	server.continueScan(requestPeer)
+}
+
+func (server *LightServer) ReceiveData(requestPeer *peer, payload objectEntries) {
+	if len(payload.blockEntries) == 0 {
+		return
+	}
+	server.scanning = false
+	for _, blockBytes := range payload.blockEntries {
+		block, err := blockchain.DecodeBlock(blockBytes)
+		if err != nil {
+			continue
+		}
+		if err = server.client.ProcessBlock(block); err != nil {
+			fmt.Println("Error processing block")
+			fmt.Println(err.Error())
+		}
+	}
+	server.continueScan(requestPeer)
+}
+
+func (server *LightServer) HandleTcpCommands() {
+	for cmd := range server.commands {
+		switch cmd.id {
+		case VERSION:
+			server.ReceiveVersion(cmd.peer, ParseVersionPayload(cmd.args))
+		case VERSION_ACK:
+			server.ReceiveVersionAck(cmd.peer)
+		case HEADERS:
+			headers, err := ParseHeadersPayload(cmd.args)
+			if err == nil {
+				server.ReceiveHeaders(cmd.peer, headers)
+			}
+		case INV:
+			server.ReceiveInv(cmd.peer, ParseObjects(cmd.args))
+		case CMPCTBLOCK:
+			server.SendGetHeaders(cmd.peer)
+		case CFHEADERS:
+			payload, err := ParseCFHeadersPayload(cmd.args)
+			if err == nil {
+				server.ReceiveCFHeaders(cmd.peer, payload)
+			}
+		case CFILTERS:
+			entries, err := ParseCFiltersPayload(cmd.args)
+			if err == nil {
+				server.ReceiveCFilters(cmd.peer, entries)
+			}
+		case DATA:
+			server.ReceiveData(cmd.peer, ParseObjects(cmd.args))
+		}
+	}
+}
+
+func (server *LightServer) FindUTXOs(pubKeyHash []byte) ([]transactions.TXOutput, error) {
+	scriptPubKey, err := script.PayToPubKeyHashScript(pubKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	return server.client.FindUTXOs(scriptPubKey)
+}
+
+// same as the full node's handler, but only knows the outputs of watched addresses
+func (server *LightServer) FindUTXOsHandler(c *gin.Context) {
+	pubKeyHashStr := c.Query("pubKeyHash")
+	pubKeyHash, err := hex.DecodeString(pubKeyHashStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid public key hash format"})
+		return
+	}
+
+	utxos, err := server.FindUTXOs(pubKeyHash)
+	if errors.Is(err, &blockchain_errors.ErrAddressNotWatched{}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error finding UTXOs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, utxos)
+}
+
+func (server *LightServer) AddWalletRoutes(r *gin.Engine) {
+	walletRoutes := r.Group("/wallet")
+	{
+		walletRoutes.GET("/utxos", server.FindUTXOsHandler)
+	}
+}
+
+func (server *LightServer) Run() {
+	go server.HandleTcpCommands()
+
+	r := gin.Default()
+	server.AddWalletRoutes(r)
+
+	// Start the HTTP server
+	if err := r.Run(":8080"); err != nil {
+		panic("Failed to run server: " + err.Error())
+	}
+}
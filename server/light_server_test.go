@@ -0,0 +1,84 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/light_client"
+	"github.com/pedrogomes29/blockchain_node/script"
+)
+
+// connects a light node watching the given wallet to a full node over a loopback connection
+func newTestLightNode(t *testing.T, fullNode *Server, watched *testWallet) *LightServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go fullNode.NewPeer(conn).ReadInput()
+	}()
+	go fullNode.HandleTcpCommands()
+
+	watchedScript, _ := script.PayToPubKeyHashScript(watched.pubKeyHash)
+	client := light_client.OpenLightClient(t.TempDir(), [][]byte{watchedScript}, &chain_params.MainNetParams)
+	t.Cleanup(func() { client.Close() })
+	lightNode := NewLightServer(client, nil)
+	go lightNode.HandleTcpCommands()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Error connecting to the full node: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	lightNode.connect(conn)
+	return lightNode
+}
+
+func (lightNode *LightServer) waitForScan(t *testing.T, height int) {
+	deadline := time.Now().Add(10 * time.Second)
+	for lightNode.client.ScannedHeight() < height {
+		if time.Now().After(deadline) {
+			t.Fatalf("Light node only scanned up to height %d, expected %d", lightNode.client.ScannedHeight(), height)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// a light node syncs the full node's headers and finds the outputs of the watched wallet from the block filters
+func TestLightNode(t *testing.T) {
+	alice, bob := newTestWallet(t), newTestWallet(t)
+	fullNode := newTestNode(t, alice)
+	fullNode.mineTestBlocks(3)
+
+	lightNode := newTestLightNode(t, fullNode, alice)
+	lightNode.waitForScan(t, fullNode.bc.Height())
+
+	//new blocks are announced to the light node, which downloads them because they pay to alice
+	fullNode.mineTestBlocks(2)
+	lightNode.waitForScan(t, fullNode.bc.Height())
+
+	if lightNode.client.Height() != fullNode.bc.Height() {
+		t.Fatalf("Light node's headers are at height %d, expected %d", lightNode.client.Height(), fullNode.bc.Height())
+	}
+	utxos, err := lightNode.FindUTXOs(alice.pubKeyHash)
+	if err != nil {
+		t.Fatalf("Error finding UTXOs: %s", err)
+	}
+	fullNodeUTXOs, _ := fullNode.FindUTXOs(alice.pubKeyHash)
+	if len(utxos) != len(fullNodeUTXOs) || len(utxos) != fullNode.bc.Height()+1 {
+		t.Fatalf("Light node found %d UTXOs, the full node %d", len(utxos), len(fullNodeUTXOs))
+	}
+
+	if _, err := lightNode.FindUTXOs(bob.pubKeyHash); !errors.Is(err, &blockchain_errors.ErrAddressNotWatched{}) {
+		t.Fatalf("UTXOs of an unwatched address were returned: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/memory_pool"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// a transaction waits until the output it spends has enough confirmations, and waits again if the tip moves back
+func TestRelativeLockTime(t *testing.T) {
+	miner := newTestWallet(t)
+	node := newTestNode(t, miner)
+	node.mineTestBlocks(1)
+	coinbase := node.bc.GetBlock(node.bc.LastBlockHash()).Transactions[0]
+	coinbaseHeight := node.bc.Height()
+
+	const relativeLockTime = 3
+	tx := spendTx(t, node, miner, coinbase.Hash())
+	tx.Vin[0].Sequence = relativeLockTime
+	miner.signInputs(t, node, tx)
+	if err := node.AddTxToMemPool(*tx); !errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) {
+		t.Fatalf("Transaction spending an immature output wasn't sequence locked: %v", err)
+	}
+	for node.bc.Height()+1-coinbaseHeight < relativeLockTime {
+		if node.memoryPool.GetWaitingTxWithLock(tx.Hash()) == nil || node.memoryPool.GetTxWithLock(tx.Hash()) != nil {
+			t.Fatalf("Transaction was queued with %d confirmations", node.bc.Height()+1-coinbaseHeight)
+		}
+		node.mineTestBlocks(1)
+	}
+	if node.memoryPool.GetWaitingTxWithLock(tx.Hash()) != nil || node.memoryPool.GetTxWithLock(tx.Hash()) == nil {
+		t.Fatalf("Transaction wasn't queued once its lock time matured")
+	}
+
+	if err := node.bc.RemoveBlock(node.bc.LastBlockHash()); err != nil {
+		t.Fatalf("Error removing block: %s", err)
+	}
+	node.ReevaluateWaitingTxs()
+	if node.memoryPool.GetWaitingTxWithLock(tx.Hash()) == nil || node.memoryPool.GetTxWithLock(tx.Hash()) != nil {
+		t.Fatalf("Transaction stayed queued after the tip moved back")
+	}
+
+	node.mineTestBlocks(2)
+	_, block, err := node.bc.FindTransaction(tx.Hash())
+	if err != nil {
+		t.Fatalf("Matured transaction wasn't mined: %s", err)
+	}
+
+	//the height of the spent outputs is dropped along with them, and restored if the spend is reverted
+	if _, err := transactions.GetUTXOsHeight(node.bc.ChainstateDB, coinbase.Hash()); !errors.Is(err, &blockchain_errors.ErrInvalidInputUTXO{}) {
+		t.Fatalf("Height of spent outputs was kept: %v", err)
+	}
+	if err := node.bc.RemoveBlock(block.GetBlockHeaderHash()); err != nil {
+		t.Fatalf("Error removing block: %s", err)
+	}
+	if height, err := transactions.GetUTXOsHeight(node.bc.ChainstateDB, coinbase.Hash()); err != nil || height != coinbaseHeight {
+		t.Fatalf("Height of outputs unspent by a removed block wasn't restored: %d, %v", height, err)
+	}
+}
+
+// only transactions which are valid apart from their relative lock times wait, and only up to MaxWaitingTxs of them
+func TestWaitingPoolAdmission(t *testing.T) {
+	miner := newTestWallet(t)
+	node := newTestNode(t, miner)
+	node.mineTestBlocks(1)
+	coinbaseTxid := node.bc.GetBlock(node.bc.LastBlockHash()).Transactions[0].Hash()
+
+	unsigned := spendTx(t, node, miner, coinbaseTxid)
+	unsigned.Vin[0].Sequence = 2
+	unsigned.Vin[0].ScriptSig = nil
+	tooMuchValue := spendTx(t, node, miner, coinbaseTxid)
+	tooMuchValue.Vin[0].Sequence = 2
+	tooMuchValue.Vout[0].Value++
+	miner.signInputs(t, node, tooMuchValue)
+	for name, tx := range map[string]*transactions.Transaction{"no signature": unsigned, "outputs over inputs": tooMuchValue} {
+		if err := node.AddTxToMemPool(*tx); err == nil || errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) {
+			t.Errorf("Transaction with %s was accepted: %v", name, err)
+		}
+		node.ReceiveTxs(nil, [][]byte{tx.Serialize()})
+		if node.memoryPool.GetWaitingTxWithLock(tx.Hash()) != nil {
+			t.Errorf("Transaction with %s is waiting", name)
+		}
+	}
+
+	for i := 0; i < memory_pool.MaxWaitingTxs; i++ {
+		tx := spendTx(t, node, miner, coinbaseTxid)
+		tx.Vin[0].Sequence = 2
+		tx.Vout[0].Value -= transactions.Amount(i)
+		miner.signInputs(t, node, tx)
+		if err := node.AddTxToMemPool(*tx); !errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) {
+			t.Fatalf("Sequence locked transaction %d wasn't kept waiting: %v", i, err)
+		}
+	}
+	tx := spendTx(t, node, miner, coinbaseTxid)
+	tx.Vin[0].Sequence = 2
+	tx.Vout[0].Value -= memory_pool.MaxWaitingTxs
+	miner.signInputs(t, node, tx)
+	if err := node.AddTxToMemPool(*tx); !errors.Is(err, &blockchain_errors.ErrWaitingPoolFull{}) {
+		t.Fatalf("Transaction was kept waiting in a full pool: %v", err)
+	}
+}
+
+// a waiting transaction whose lock time matures is admitted by the same rules as a new one
+func TestReevaluatedTxAdmission(t *testing.T) {
+	miner := newTestWallet(t)
+	node := newTestNode(t, miner)
+	node.mineTestBlocks(1)
+
+	coinbaseTxid := node.bc.GetBlock(node.bc.LastBlockHash()).Transactions[0].Hash()
+	data, _ := transactions.NewDataOutput(make([]byte, 10))
+	tx := spendTx(t, node, miner, coinbaseTxid, *data)
+	tx.Vin[0].Sequence = 2
+	miner.signInputs(t, node, tx)
+	if err := node.AddTxToMemPool(*tx); !errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) {
+		t.Fatalf("Sequence locked transaction wasn't kept waiting: %v", err)
+	}
+
+	node.maxDataCarrierSize = 5
+	node.mineTestBlocks(1)
+	if node.memoryPool.GetWaitingTxWithLock(tx.Hash()) != nil || node.memoryPool.GetTxWithLock(tx.Hash()) != nil {
+		t.Fatalf("Matured transaction with a data output over the maximum size was kept")
+	}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+type outPoint struct {
+	Txid         []byte `json:"txid"`
+	OutIndex     int    `json:"outIndex"`
+	RedeemScript []byte `json:"redeemScript"` //only for outputs paying to a script hash
+}
+
+type payment struct {
+	Value   transactions.Amount `json:"value"`
+	Address string              `json:"address"`
+	Data    []byte              `json:"data"` //if set, the payment is a zero value data output instead
+}
+
+type multisigScriptRequest struct {
+	Required int      `json:"required"`
+	PubKeys  [][]byte `json:"pubKeys"`
+}
+
+type multisigSpendRequest struct {
+	Inputs   []outPoint `json:"inputs"`
+	Outputs  []payment  `json:"outputs"`
+	LockTime uint32     `json:"lockTime"`
+}
+
+type multisigSignRequest struct {
+	Partial    transactions.PartiallySignedTx `json:"partial"`
+	PubKey     []byte                         `json:"pubKey"`
+	Signatures [][]byte                       `json:"signatures"` //one per input, empty for inputs not signed by this key
+}
+
+type multisigCombineRequest struct {
+	Partials []transactions.PartiallySignedTx `json:"partials"`
+}
+
+func (server *Server) BuildPayments(payments []payment) ([]transactions.TXOutput, error) {
+	var outputs []transactions.TXOutput
+	for _, payment := range payments {
+		if payment.Data != nil {
+			output, err := transactions.NewDataOutput(payment.Data)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, *output)
+			continue
+		}
+		output, err := transactions.NewTXOutput(payment.Value, payment.Address)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, *output)
+	}
+	return outputs, nil
+}
+
+// builds an unsigned transaction spending the given multisig outputs
+func (server *Server) BuildMultisigSpend(request multisigSpendRequest) (*transactions.PartiallySignedTx, error) {
+	outputs, err := server.BuildPayments(request.Outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := transactions.Transaction{Version: transactions.TxVersion, Vout: outputs, LockTime: request.LockTime}
+	var spentOutputs []transactions.TXOutput
+	var redeemScripts [][]byte
+	for _, input := range request.Inputs {
+		utxo, err := transactions.GetUTXO(server.bc.ChainstateDB, input.Txid, input.OutIndex)
+		if err != nil {
+			return nil, err
+		}
+		spentOutputs = append(spentOutputs, utxo)
+		redeemScripts = append(redeemScripts, input.RedeemScript)
+
+		sequence := transactions.MaxSequence
+		if request.LockTime != 0 {
+			sequence-- //the lock time is only enforced if some input doesn't have the maximum sequence number
+		}
+		tx.Vin = append(tx.Vin, transactions.TXInput{
+			Txid:     input.Txid,
+			OutIndex: input.OutIndex,
+			Sequence: sequence,
+		})
+	}
+
+	return transactions.NewPartiallySignedTx(tx, spentOutputs, redeemScripts)
+}
+
+func (server *Server) MultisigScriptHandler(c *gin.Context) {
+	var request multisigScriptRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multisig format"})
+		return
+	}
+
+	scriptPubKey, err := script.MultisigScript(request.Required, request.PubKeys)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"scriptPubKey": scriptPubKey,
+		"asm":          script.Disassemble(scriptPubKey),
+		"address":      transactions.ScriptHashAddress(scriptPubKey), //pays to the multisig script as a redeem script
+	})
+}
+
+func (server *Server) MultisigSpendHandler(c *gin.Context) {
+	var request multisigSpendRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spend format"})
+		return
+	}
+
+	partial, err := server.BuildMultisigSpend(request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, partial)
+}
+
+func (server *Server) MultisigSignHandler(c *gin.Context) {
+	var request multisigSignRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signing format"})
+		return
+	}
+
+	partial := request.Partial
+	for inputIdx, signature := range request.Signatures {
+		if len(signature) == 0 {
+			continue
+		}
+		if err := partial.AddSignature(inputIdx, request.PubKey, signature, server.bc.Params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, partial)
+}
+
+func (server *Server) MultisigCombineHandler(c *gin.Context) {
+	var request multisigCombineRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid combine format"})
+		return
+	}
+
+	combined, err := transactions.CombinePartiallySignedTxs(request.Partials, server.bc.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !combined.IsComplete() {
+		c.JSON(http.StatusOK, gin.H{"partial": combined, "complete": false})
+		return
+	}
+
+	tx, err := combined.Finalize()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"partial": combined, "complete": true, "transaction": tx})
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func (server *Server) SigCacheStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, transactions.SignatureCache.Stats())
+}
+
+// how many bytes were received for the blocks reconstructed from compact blocks, compared to the blocks themselves
+func (server *Server) CompactBlockStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, server.CompactBlockStats())
+}
+
+func (server *Server) AddNodeRoutes(r *gin.Engine) {
+	nodeRoutes := r.Group("/node")
+	{
+		nodeRoutes.GET("/sigcache", server.SigCacheStatsHandler)
+		nodeRoutes.GET("/compactblocks", server.CompactBlockStatsHandler)
+	}
+}
@@ -18,6 +18,28 @@ import (
 const BLOCKCHAIN_PORT string = "8333"
 const BLOCK_CONFIRMATIONS int = 6
 
+// objects are hex encoded, so each entry takes twice its serialized size
+const MAX_ENTRY_SIZE int = 2 * blockchain.MaxBlockSize
+
+// the largest message is a single block of the maximum size, with its command, entry type and newline. objects which
+// don't fit in one message are sent in several
+const MAX_MESSAGE_SIZE int = len("DATA BLOCK ") + MAX_ENTRY_SIZE + len("\n")
+
+// most filters sent in response to a single GET_CFILTERS
+const MAX_CFILTERS int = 1000
+
+// most headers sent in response to a single GET_HEADERS
+const MAX_HEADERS int = 2000
+
+// most hashes of blocks which failed validation remembered at once
+const MAX_INVALID_BLOCKS int = 1000
+
+// most compact blocks waiting for transactions at once
+const MAX_PENDING_COMPACT_BLOCKS int = 100
+
+// most transactions requested by a single GET_BLOCKTXN
+const MAX_BLOCKTXN_INDEXES int = blockchain.MaxBlockTxs
+
 func (server *Server) ConnectToAddress(address string) {
 	if _, ok := server.peers[address]; ok { //if address is already known
 		return
@@ -29,13 +51,17 @@ func (server *Server) ConnectToAddress(address string) {
 		return
 	}
 
+	//log.Printf("successfully established connection to a new peer: %s\n", address)
+
+	server.connect(conn)
+}
+
+func (server *Server) connect(conn net.Conn) {
 	newPeer := &peer{
 		conn:     conn,
 		commands: server.commands,
 	}
 
-	//log.Printf("successfully established connection to a new peer: %s\n", address)
-
 	newPeer.sendString("VERSION" + " " + strconv.Itoa(server.bc.Height()))
 
 	go newPeer.ReadInput()
@@ -83,12 +109,35 @@ func (server *Server) ReceiveGetBlocks(requestPeer *peer, payload getBlocksPaylo
 	})
 }
 
+// answers with "HEADERS <header> ..." with the headers of the blocks after the first block of the locator which is
+// known, or after the genesis block's parent if none is
+func (server *Server) ReceiveGetHeaders(requestPeer *peer, locator getBlocksPayload) {
+	highestCommonBlockHash := []byte{}
+	for _, blockHash := range locator {
+		if _, err := server.bc.GetHeader(blockHash); err == nil {
+			highestCommonBlockHash = blockHash
+			break
+		}
+	}
+
+	headers, err := server.bc.HeadersAfter(highestCommonBlockHash, MAX_HEADERS)
+	if err != nil {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("HEADERS")
+	for _, header := range headers {
+		sb.WriteString(" " + hex.EncodeToString(header.Serialize()))
+	}
+	requestPeer.sendString(sb.String())
+}
+
 func (server *Server) ReceiveInv(requestPeer *peer, payload objectEntries) {
 	var unknownBlockHashes [][]byte
 	var unkownTxHashes [][]byte
 	for _, txHash := range payload.txEntries {
 		tx := server.memoryPool.GetTxWithLock(txHash)
-		if tx != nil { //if tx is already known
+		if tx != nil || server.memoryPool.GetWaitingTxWithLock(txHash) != nil { //if tx is already known
 			continue
 		}
 		unkownTxHashes = append(unkownTxHashes, txHash)
@@ -111,8 +160,16 @@ func (server *Server) parseBlocks(serializedBlocks [][]byte) (*blockchain.Block,
 	var newBlocks []*blockchain.Block
 	var highestKnownBlock *blockchain.Block
 
-	firstBlockBytes := serializedBlocks[0]
-	firstRemoteBlock := blockchain.DeserializeBlock(firstBlockBytes)
+	remoteBlocks := make([]*blockchain.Block, len(serializedBlocks))
+	for blockIdx, blockBytes := range serializedBlocks {
+		remoteBlock, err := blockchain.DecodeBlock(blockBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		remoteBlocks[blockIdx] = remoteBlock
+	}
+
+	firstRemoteBlock := remoteBlocks[0]
 
 	highestKnownBlock = server.bc.GetBlock(firstRemoteBlock.Header.PrevBlockHeaderHash)
 	highestKnownBlockIdx := -1
@@ -128,8 +185,7 @@ func (server *Server) parseBlocks(serializedBlocks [][]byte) (*blockchain.Block,
 
 	}
 
-	for blockIdx, blockBytes := range serializedBlocks {
-		remoteBlock := blockchain.DeserializeBlock(blockBytes)
+	for blockIdx, remoteBlock := range remoteBlocks {
 		if !bytes.Equal(prevHash, remoteBlock.Header.PrevBlockHeaderHash) {
 			return nil, nil, &blockchain_errors.ErrOrphanBlock{}
 		}
@@ -143,12 +199,11 @@ func (server *Server) parseBlocks(serializedBlocks [][]byte) (*blockchain.Block,
 		prevHash = highestKnownBlock.GetBlockHeaderHash()
 	}
 
-	if highestKnownBlockIdx == len(serializedBlocks)-1 { //all remote blocks are known
+	if highestKnownBlockIdx == len(remoteBlocks)-1 { //all remote blocks are known
 		return highestKnownBlock, nil, nil
 	}
 
-	for _, blockBytes := range serializedBlocks[highestKnownBlockIdx+1:] {
-		remoteBlock := blockchain.DeserializeBlock(blockBytes)
+	for _, remoteBlock := range remoteBlocks[highestKnownBlockIdx+1:] {
 		if !bytes.Equal(prevHash, remoteBlock.Header.PrevBlockHeaderHash) {
 			return nil, nil, &blockchain_errors.ErrOrphanBlock{}
 		}
@@ -179,7 +234,7 @@ func (server *Server) RemoveBlockFromBc(blockHash []byte) error {
 		return err
 	}
 	for _, tx := range removedBlock.Transactions {
-		if err = tx.Verify(server.bc.ChainstateDB); err != nil {
+		if err = tx.Verify(server.bc.ChainstateDB, server.bc.Height()+1, server.bc.Params); err != nil {
 			server.memoryPool.DeleteTxsSpendingFromTxUTXOsWithLock(tx)
 			server.memoryPool.PushFrontTxWithLock(tx)
 		}
@@ -217,6 +272,17 @@ func (server *Server) ReceiveBlocks(requestPeer *peer, serializedBlocks [][]byte
 	server.mu.Lock()
 	defer server.mu.Unlock()
 
+	for _, block := range newBlocks {
+		if server.invalidBlocks[hex.EncodeToString(block.GetBlockHeaderHash())] {
+			return nil
+		}
+		//the header commits to its parent, so descendants of an invalid block are invalid too
+		if server.invalidBlocks[hex.EncodeToString(block.Header.PrevBlockHeaderHash)] {
+			server.markInvalidBlock(block.GetBlockHeaderHash())
+			return nil
+		}
+	}
+
 	if server.bc.GetBlock(highestKnownBlockHash) == nil && !bytes.Equal(highestKnownBlockHash, []byte{}) {
 		//no block in the receiving blockchain is known and receiving bc doesn't include genesis block
 		return nil
@@ -238,6 +304,12 @@ func (server *Server) ReceiveBlocks(requestPeer *peer, serializedBlocks [][]byte
 	for _, block := range newBlocks {
 		err := server.AddBlockToBc(block)
 		if err != nil {
+			//only blocks failing validation are remembered as invalid. a mutated block may be a tampered copy of a
+			//valid block with the same hash, and a block too far in the future may become valid later
+			if errors.Is(err, &blockchain_errors.ErrBlockFailedValidation{}) &&
+				!errors.Is(err, &blockchain_errors.ErrMutatedBlock{}) && !errors.Is(err, &blockchain_errors.ErrTimestampTooNew{}) {
+				server.markInvalidBlock(block.GetBlockHeaderHash())
+			}
 			//TODO: better error handling
 			fmt.Println("Error adding block")
 			fmt.Println(err.Error())
@@ -248,20 +320,39 @@ func (server *Server) ReceiveBlocks(requestPeer *peer, serializedBlocks [][]byte
 		newBlocksHashes = append(newBlocksHashes, newBlockHash)
 	}
 
+	server.ReevaluateWaitingTxs()
+
 	server.miningChan <- struct{}{}
 	return newBlocksHashes
 }
 
+// remembers a block which failed validation, forgetting an arbitrary one if MAX_INVALID_BLOCKS are already remembered
+func (server *Server) markInvalidBlock(blockHash []byte) {
+	if len(server.invalidBlocks) >= MAX_INVALID_BLOCKS {
+		for forgottenHash := range server.invalidBlocks { //map iteration order is unspecified, so forgotten blocks vary
+			delete(server.invalidBlocks, forgottenHash)
+			break
+		}
+	}
+	server.invalidBlocks[hex.EncodeToString(blockHash)] = true
+}
+
 func (server *Server) ReceiveTxs(requestPeer *peer, serializedTxs [][]byte) [][]byte {
 	var newTxHashes [][]byte
 	for _, txBytes := range serializedTxs {
-		tx := transactions.Deserialize(txBytes)
+		if len(txBytes) > transactions.MaxTxSize {
+			continue
+		}
+		tx, err := transactions.DecodeTransaction(txBytes)
+		if err != nil {
+			continue
+		}
 		txHash := tx.Hash()
 		if server.memoryPool.GetTxWithLock(txHash) != nil {
 			continue
 		}
-		err := server.memoryPool.PushBackTxWithLock(tx)
-		if err != nil {
+		//transactions whose relative lock times aren't satisfied yet wait without being relayed
+		if err = server.admitTx(tx); err != nil {
 			continue
 		}
 		newTxHashes = append(newTxHashes, txHash)
@@ -279,16 +370,18 @@ func (server *Server) ReceiveData(requestPeer *peer, payload objectEntries) {
 		newBlocksHashes = server.ReceiveBlocks(requestPeer, payload.blockEntries)
 	}
 	server.BroadcastObjects(INV, objectEntries{
-		blockEntries: newBlocksHashes,
-		txEntries:    newTxsHashes,
+		txEntries: newTxsHashes,
 	})
+	server.AnnounceBlocks(newBlocksHashes)
 }
 
 func (server *Server) ReceiveGetData(requestPeer *peer, payload objectEntries) {
 	data := objectEntries{}
-
 	for _, txHash := range payload.txEntries {
 		tx := server.memoryPool.GetTxWithLock(txHash)
+		if tx == nil && server.bc.TxIndex {
+			tx, _, _ = server.bc.FindTransaction(txHash)
+		}
 		if tx == nil {
 			continue
 		}
@@ -306,6 +399,43 @@ func (server *Server) ReceiveGetData(requestPeer *peer, payload objectEntries) {
 	requestPeer.SendObjects(DATA, data)
 }
 
+// answers with "CFILTERS <block hash> <filter> ..." for the requested blocks which are known, stopping before the
+// message would exceed MAX_MESSAGE_SIZE. the requester asks again for the filters it didn't receive
+func (server *Server) ReceiveGetCFilters(requestPeer *peer, blockHashes getBlocksPayload) {
+	var sb strings.Builder
+	sb.WriteString("CFILTERS")
+	for i, blockHash := range blockHashes {
+		if i == MAX_CFILTERS {
+			break
+		}
+		filter, err := server.bc.GetBlockFilter(blockHash)
+		if err != nil {
+			continue
+		}
+		entry := " " + hex.EncodeToString(blockHash) + " " + hex.EncodeToString(filter.Serialize())
+		if sb.Len()+len(entry)+len("\n") > MAX_MESSAGE_SIZE {
+			break
+		}
+		sb.WriteString(entry)
+	}
+	requestPeer.sendString(sb.String())
+}
+
+// answers with "CFHEADERS <stop hash> <filter header> ..." with the filter headers from the start height to the stop
+// block, or nothing if the range is invalid
+func (server *Server) ReceiveGetCFHeaders(requestPeer *peer, payload getCFHeadersPayload) {
+	filterHeaders, err := server.bc.FilterHeaders(payload.startHeight, payload.stopHash)
+	if err != nil {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("CFHEADERS " + hex.EncodeToString(payload.stopHash))
+	for _, filterHeader := range filterHeaders {
+		sb.WriteString(" " + hex.EncodeToString(filterHeader))
+	}
+	requestPeer.sendString(sb.String())
+}
+
 func (server *Server) ReceiveVersion(requestPeer *peer, payload versionPayload) {
 	if !payload.ACK {
 		requestPeer.sendString("VERSION" + " " + strconv.Itoa(server.bc.Height()) + " " + "ACK")
@@ -354,6 +484,30 @@ func (server *Server) HandleTcpCommands() {
 			server.ReceiveGetData(cmd.peer, ParseObjects(cmd.args))
 		case DATA:
 			server.ReceiveData(cmd.peer, ParseObjects(cmd.args))
+		case GET_CFILTERS:
+			server.ReceiveGetCFilters(cmd.peer, ParseGetBlocksPayload(cmd.args))
+		case CMPCTBLOCK:
+			compactBlockBytes, err := ParseCompactBlockPayload(cmd.args)
+			if err == nil {
+				server.ReceiveCompactBlock(cmd.peer, compactBlockBytes)
+			}
+		case GET_BLOCKTXN:
+			payload, err := ParseGetBlockTxnPayload(cmd.args)
+			if err == nil {
+				server.ReceiveGetBlockTxn(cmd.peer, payload)
+			}
+		case BLOCKTXN:
+			payload, err := ParseBlockTxnPayload(cmd.args)
+			if err == nil {
+				server.ReceiveBlockTxn(cmd.peer, payload)
+			}
+		case GET_HEADERS:
+			server.ReceiveGetHeaders(cmd.peer, ParseGetBlocksPayload(cmd.args))
+		case GET_CFHEADERS:
+			payload, err := ParseGetCFHeadersPayload(cmd.args)
+			if err == nil {
+				server.ReceiveGetCFHeaders(cmd.peer, payload)
+			}
 		}
 	}
 }
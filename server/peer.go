@@ -29,13 +29,42 @@ func (s *Server) NewPeer(conn net.Conn) *peer {
 	}
 }
 
+// reads a message up to the next \n, discarding (but consuming) messages larger than MAX_MESSAGE_SIZE
+func readMessage(reader *bufio.Reader) (msg string, tooLarge bool, err error) {
+	var msgBytes []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLarge && len(msgBytes)+len(chunk) > MAX_MESSAGE_SIZE {
+			tooLarge = true
+			msgBytes = nil
+		}
+		if !tooLarge {
+			msgBytes = append(msgBytes, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", tooLarge, err
+		}
+		if tooLarge {
+			return "", true, nil
+		}
+		return string(msgBytes[:len(msgBytes)-1]), false, nil //removes \n
+	}
+}
+
 func (p *peer) ReadInput() {
+	reader := bufio.NewReader(p.conn)
 	for {
-		msg, err := bufio.NewReader(p.conn).ReadString('\n')
+		msg, tooLarge, err := readMessage(reader)
 		if err != nil {
 			return
 		}
-		msg = msg[:len(msg)-1] //removes \n
+		if tooLarge {
+			log.Printf("discarded message larger than %d bytes from peer %s", MAX_MESSAGE_SIZE, p.GetAddress())
+			continue
+		}
 		//fmt.Printf("received: %s\n",msg)
 
 		args := strings.Split(msg, " ")
@@ -90,6 +119,60 @@ func (p *peer) ReadInput() {
 				peer: p,
 				args: args[1:],
 			}
+		case "GET_CFILTERS":
+			p.commands <- command{
+				id:   GET_CFILTERS,
+				peer: p,
+				args: args[1:],
+			}
+		case "GET_CFHEADERS":
+			p.commands <- command{
+				id:   GET_CFHEADERS,
+				peer: p,
+				args: args[1:],
+			}
+		case "CFILTERS":
+			p.commands <- command{
+				id:   CFILTERS,
+				peer: p,
+				args: args[1:],
+			}
+		case "CFHEADERS":
+			p.commands <- command{
+				id:   CFHEADERS,
+				peer: p,
+				args: args[1:],
+			}
+		case "GET_HEADERS":
+			p.commands <- command{
+				id:   GET_HEADERS,
+				peer: p,
+				args: args[1:],
+			}
+		case "HEADERS":
+			p.commands <- command{
+				id:   HEADERS,
+				peer: p,
+				args: args[1:],
+			}
+		case "CMPCTBLOCK":
+			p.commands <- command{
+				id:   CMPCTBLOCK,
+				peer: p,
+				args: args[1:],
+			}
+		case "GET_BLOCKTXN":
+			p.commands <- command{
+				id:   GET_BLOCKTXN,
+				peer: p,
+				args: args[1:],
+			}
+		case "BLOCKTXN":
+			p.commands <- command{
+				id:   BLOCKTXN,
+				peer: p,
+				args: args[1:],
+			}
 		}
 	}
 }
@@ -105,26 +188,43 @@ func (c *peer) sendString(msg string) {
 	}
 }
 
+// sends the entries in as few messages as possible, starting a new message whenever the next entry would make the
+// current one exceed MAX_MESSAGE_SIZE
 func (peer *peer) SendObjects(commandID commandID, entries objectEntries) {
 	if len(entries.txEntries) == 0 && len(entries.blockEntries) == 0 {
 		return
 	}
 
-	var sb strings.Builder
+	var commandName string
 	switch commandID {
 	case INV:
-		sb.WriteString("INV")
+		commandName = "INV"
 	case GET_DATA:
-		sb.WriteString("GET_DATA")
+		commandName = "GET_DATA"
 	case DATA:
-		sb.WriteString("DATA")
+		commandName = "DATA"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(commandName)
+	numEntries := 0
+	writeEntry := func(entryType string, entry []byte) {
+		if numEntries > 0 && sb.Len()+len(entryType)+2*len(entry)+len("\n") > MAX_MESSAGE_SIZE {
+			peer.sendString(sb.String())
+			sb.Reset()
+			sb.WriteString(commandName)
+			numEntries = 0
+		}
+		sb.WriteString(entryType + hex.EncodeToString(entry))
+		numEntries++
 	}
+
 	for _, entry := range entries.txEntries {
-		sb.WriteString(" TX " + hex.EncodeToString(entry))
+		writeEntry(" TX ", entry)
 	}
 
 	for _, entry := range entries.blockEntries {
-		sb.WriteString(" BLOCK " + hex.EncodeToString(entry))
+		writeEntry(" BLOCK ", entry)
 	}
 
 	peer.sendString(sb.String())
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+)
+
+// messages of exactly MAX_MESSAGE_SIZE bytes (including the newline) are read, larger ones are discarded without
+// affecting the next message
+func TestReadMessageLimit(t *testing.T) {
+	atLimit := "DATA BLOCK " + strings.Repeat("a", MAX_MESSAGE_SIZE-len("DATA BLOCK \n"))
+	overLimit := atLimit + "a"
+	reader := bufio.NewReader(strings.NewReader(atLimit + "\n" + overLimit + "\n" + "VERSION_ACK\n"))
+
+	msg, tooLarge, err := readMessage(reader)
+	if err != nil || tooLarge || msg != atLimit {
+		t.Fatalf("Message of the maximum size wasn't read (too large: %t, error: %v)", tooLarge, err)
+	}
+	if _, tooLarge, err = readMessage(reader); err != nil || !tooLarge {
+		t.Fatalf("Message one byte over the maximum size wasn't discarded (error: %v)", err)
+	}
+	if msg, _, err = readMessage(reader); err != nil || msg != "VERSION_ACK" {
+		t.Fatalf("Message after a discarded one was read as %q (error: %v)", msg, err)
+	}
+}
+
+// blocks of the maximum size are sent one per message, each of them within the limit
+func TestSendObjectsSplitsMessages(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	blocks := make([][]byte, 3)
+	for i := range blocks {
+		blocks[i] = make([]byte, blockchain.MaxBlockSize)
+	}
+	go (&peer{conn: local}).SendObjects(DATA, objectEntries{txEntries: [][]byte{{1}}, blockEntries: blocks})
+
+	reader := bufio.NewReader(remote)
+	numTxs, numBlocks := 0, 0
+	for numBlocks < len(blocks) {
+		msg, tooLarge, err := readMessage(reader)
+		if err != nil || tooLarge {
+			t.Fatalf("Message wasn't read within the limit (too large: %t, error: %v)", tooLarge, err)
+		}
+		payload := ParseObjects(strings.Split(msg, " ")[1:])
+		if len(payload.blockEntries) > 1 {
+			t.Fatalf("Message carries %d blocks of the maximum size", len(payload.blockEntries))
+		}
+		numTxs += len(payload.txEntries)
+		numBlocks += len(payload.blockEntries)
+	}
+	if numTxs != 1 {
+		t.Fatalf("Received %d transactions, expected 1", numTxs)
+	}
+}
@@ -16,12 +16,19 @@ func (server *Server) AddBlockInProgToBC() error {
 func (server *Server) POW() {
 
 	newBlock := blockchain.NewBlock(
-		[]*transactions.Transaction{transactions.NewCoinbaseTX(server.minerAddress)},
+		[]*transactions.Transaction{transactions.NewCoinbaseTX(server.minerAddress, server.bc.Height()+1)},
 		server.bc.LastBlockHash(),
 		server.bc.Height()+1,
+		server.bc.Params,
 	)
 
-	newBlock.FillWithTxs(server.memoryPool)
+	//the block's timestamp must be later than the median time past of the previous blocks
+	medianTimePast := server.bc.MedianTimePast(newBlock.Header.PrevBlockHeaderHash)
+	if newBlock.Header.Timestamp <= medianTimePast {
+		newBlock.Header.Timestamp = medianTimePast + 1
+	}
+
+	newBlock.FillWithTxs(server.memoryPool, medianTimePast)
 
 	server.blockInProgress = newBlock
 	minedBlock := server.blockInProgress.POW(server.miningChan)
@@ -32,11 +39,11 @@ func (server *Server) POW() {
 		if err != nil {
 			fmt.Println("Error adding block in progress to blockchain")
 			fmt.Println(err.Error())
+		} else {
+			server.ReevaluateWaitingTxs()
 		}
 		blockInProgressHash := server.blockInProgress.GetBlockHeaderHash()
-		server.BroadcastObjects(INV, objectEntries{
-			blockEntries: [][]byte{blockInProgressHash},
-		})
+		server.AnnounceBlocks([][]byte{blockInProgressHash})
 	}
 }
 func (server *Server) POWLoop() {
@@ -1,34 +1,45 @@
 package server
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
 	"github.com/pedrogomes29/blockchain_node/memory_pool"
 	"github.com/pedrogomes29/blockchain_node/transactions"
 )
 
 type Server struct {
-	bc              *blockchain.Blockchain
-	minerAddress    string
-	blockInProgress *blockchain.Block
-	memoryPool      *memory_pool.MemoryPool
-	peers           map[string]*peer
-	commands        chan command
-	miningChan      chan struct{}
-	mu              sync.Mutex
+	bc                   *blockchain.Blockchain
+	minerAddress         string
+	maxDataCarrierSize   int //largest data output accepted into the memory pool
+	blockInProgress      *blockchain.Block
+	memoryPool           *memory_pool.MemoryPool
+	peers                map[string]*peer
+	commands             chan command
+	miningChan           chan struct{}
+	invalidBlocks        map[string]bool                 //hashes of blocks which failed validation, which aren't added while remembered
+	pendingCompactBlocks map[string]*pendingCompactBlock //compact blocks waiting for missing transactions, by block hash
+	compactBlockStats    CompactBlockStats
+	mu                   sync.Mutex
 }
 
-func NewServer(minerAddress string, seedAddrs []string) *Server {
+func NewServer(minerAddress string, seedAddrs []string, txIndex bool, maxDataCarrierSize int, params *chain_params.Params) *Server {
 	miningChan := make(chan struct{})
 	server := &Server{
-		bc:           blockchain.NewBlockchain(miningChan, minerAddress),
-		minerAddress: minerAddress,
-		memoryPool:   memory_pool.NewMemoryPool(),
-		peers:        make(map[string]*peer),
-		commands:     make(chan command),
-		miningChan:   miningChan,
+		bc:                   blockchain.NewBlockchain(miningChan, minerAddress, txIndex, params),
+		minerAddress:         minerAddress,
+		maxDataCarrierSize:   maxDataCarrierSize,
+		memoryPool:           memory_pool.NewMemoryPool(),
+		peers:                make(map[string]*peer),
+		commands:             make(chan command),
+		miningChan:           miningChan,
+		invalidBlocks:        make(map[string]bool),
+		pendingCompactBlocks: make(map[string]*pendingCompactBlock),
 	}
 
 	for _, seedAddres := range seedAddrs {
@@ -38,16 +49,14 @@ func NewServer(minerAddress string, seedAddrs []string) *Server {
 }
 
 func (server *Server) AddTxToMemPool(tx transactions.Transaction) error {
-	if err := tx.Verify(server.bc.ChainstateDB); err != nil {
-		return err
-	}
-
-	err := server.memoryPool.PushBackTxWithLock(&tx)
+	err := server.admitTx(&tx)
 	if err != nil {
 		return err
 	}
 
-	server.blockInProgress.AddTransaction(&tx)
+	if server.blockInProgress != nil {
+		server.blockInProgress.AddTransaction(&tx)
+	}
 
 	server.BroadcastObjects(INV, objectEntries{
 		txEntries: [][]byte{tx.Hash()},
@@ -56,11 +65,58 @@ func (server *Server) AddTxToMemPool(tx transactions.Transaction) error {
 	return nil
 }
 
+// pushes the transaction to the memory pool queue if it can be included in the next block, or to the waiting pool if
+// only its relative lock times aren't satisfied yet (returning ErrSequenceLocked)
+func (server *Server) admitTx(tx *transactions.Transaction) error {
+	if tx.IsCoinbase {
+		return &blockchain_errors.ErrUnexpectedCoinbase{}
+	}
+
+	//legacy transactions only exist in the history migrated from gob
+	if tx.Version == transactions.LegacyTxVersion {
+		return &blockchain_errors.ErrUnsupportedTxVersion{}
+	}
+
+	if len(tx.Serialize()) > transactions.MaxTxSize {
+		return &blockchain_errors.ErrTxTooLarge{}
+	}
+
+	if tx.HasDataLargerThan(server.maxDataCarrierSize) {
+		return &blockchain_errors.ErrDataCarrierTooLarge{}
+	}
+
+	if !tx.IsFinal(server.bc.Height()+1, server.bc.MedianTimePast(server.bc.LastBlockHash())) {
+		return &blockchain_errors.ErrNonFinalTx{}
+	}
+
+	err := tx.Verify(server.bc.ChainstateDB, server.bc.Height()+1, server.bc.Params)
+	if errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) {
+		//kept aside until the spent outputs have enough confirmations
+		if err := server.memoryPool.AddWaitingTxWithLock(tx); err != nil {
+			return err
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	hasUnspentOutputs, err := transactions.HasUnspentOutputs(server.bc.ChainstateDB, tx.Hash())
+	if err != nil {
+		return err
+	}
+	if hasUnspentOutputs {
+		return &blockchain_errors.ErrDuplicateTx{}
+	}
+
+	return server.memoryPool.PushBackTxWithLock(tx)
+}
+
 func (server *Server) FindUTXOs(pubKeyHash []byte) ([]transactions.TXOutput, error) {
 	return server.bc.FindUTXOs(pubKeyHash)
 }
 
-func (server *Server) FindSpendableUTXOs(pubKeyHash []byte, amount int) (int, map[string][]int, error) {
+func (server *Server) FindSpendableUTXOs(pubKeyHash []byte, amount transactions.Amount) (transactions.Amount, map[string][]int, error) {
 	return server.bc.FindSpendableUTXOs(pubKeyHash, amount)
 }
 
@@ -72,9 +128,58 @@ func (server *Server) Run() {
 
 	r := gin.Default()
 	server.AddWalletRoutes(r)
+	server.AddTxRoutes(r)
+	server.AddNodeRoutes(r)
+	server.AddBlockRoutes(r)
 
 	// Start the HTTP server
 	if err := r.Run(":8080"); err != nil {
 		panic("Failed to run server: " + err.Error())
 	}
 }
+
+// looks a transaction up in the memory pool and, if the tx index is enabled, in the blockchain
+func (server *Server) FindTransaction(txHash []byte) (*transactions.Transaction, *blockchain.Block, bool, error) {
+	if tx := server.memoryPool.GetTxWithLock(txHash); tx != nil {
+		return tx, nil, true, nil
+	}
+
+	tx, block, err := server.bc.FindTransaction(txHash)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return tx, block, false, nil
+}
+
+// moves waiting transactions whose relative lock times are now satisfied to the memory pool queue, and queued
+// transactions whose relative lock times are no longer satisfied (after a reorg) back to waiting
+func (server *Server) ReevaluateWaitingTxs() {
+	nextHeight := server.bc.Height() + 1
+
+	for _, tx := range server.memoryPool.GetTxsWithRelativeLockTimesWithLock() {
+		err := tx.VerifySequenceLocks(server.bc.ChainstateDB, nextHeight)
+		if errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) {
+			server.memoryPool.DeleteTxWithLock(tx.Hash())
+			if err := server.memoryPool.AddWaitingTxWithLock(tx); err != nil {
+				fmt.Printf("Dropping transaction %x: %s\n", tx.Hash(), err)
+			}
+		}
+	}
+
+	var readyTxHashes [][]byte
+	for _, tx := range server.memoryPool.TakeWaitingTxsWithLock() {
+		err := server.admitTx(tx)
+		if errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) { //still waiting
+			continue
+		}
+		if err != nil { //tx became invalid, e.g. one of its inputs was spent, or the waiting pool is full
+			fmt.Printf("Dropping transaction %x: %s\n", tx.Hash(), err)
+			continue
+		}
+		readyTxHashes = append(readyTxHashes, tx.Hash())
+	}
+
+	server.BroadcastObjects(INV, objectEntries{
+		txEntries: readyTxHashes,
+	})
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/pedrogomes29/blockchain_node/blockchain"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/memory_pool"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+type testWallet struct {
+	privKey    *btcec.PrivateKey
+	pubKey     []byte //compressed
+	pubKeyHash []byte
+	address    string
+	schnorr    bool //whether the wallet signs with schnorr signatures instead of ECDSA
+}
+
+func newTestWallet(t *testing.T) *testWallet {
+	privKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err)
+	}
+	pubKey := privKey.PubKey().SerializeCompressed()
+	pubKeyHash := utils.HashPublicKey(pubKey)
+	return &testWallet{privKey, pubKey, pubKeyHash, transactions.PubKeyHashAddress(pubKeyHash), false}
+}
+
+func (wallet *testWallet) signHash(t *testing.T, sigHash []byte, hashType transactions.SigHashType) []byte {
+	var signature []byte
+	if wallet.schnorr {
+		schnorrSig, err := schnorr.Sign(wallet.privKey, sigHash)
+		if err != nil {
+			t.Fatalf("Error signing transaction: %s", err)
+		}
+		signature = schnorrSig.Serialize()
+	} else {
+		signature = ecdsa.Sign(wallet.privKey, sigHash).Serialize()
+	}
+	return append(signature, byte(hashType))
+}
+
+func (wallet *testWallet) sign(t *testing.T, server *Server, tx *transactions.Transaction, inputIdx int, hashType transactions.SigHashType) []byte {
+	sigHash, _, err := server.SignatureHash(*tx, inputIdx, hashType)
+	if err != nil {
+		t.Fatalf("Error computing signature hash: %s", err)
+	}
+	return wallet.signHash(t, sigHash, hashType)
+}
+
+// signs every input of the transaction, which must all spend the wallet's pay to pubkey hash outputs
+func (wallet *testWallet) signInputs(t *testing.T, server *Server, tx *transactions.Transaction) {
+	for i := range tx.Vin {
+		tx.Vin[i].ScriptSig, _ = script.PubKeyHashScriptSig(wallet.sign(t, server, tx, i, transactions.SigHashAll), wallet.pubKey)
+	}
+}
+
+// builds a signed transaction spending the first output of the given transaction, or all of the wallet's coins if the
+// txid is nil, back to the wallet after the given outputs' value is paid to them
+func spendTx(t *testing.T, server *Server, wallet *testWallet, txid []byte, outputs ...transactions.TXOutput) *transactions.Transaction {
+	value, spendable, err := server.FindSpendableUTXOs(wallet.pubKeyHash, 1)
+	if txid != nil {
+		var utxo transactions.TXOutput
+		utxo, err = transactions.GetUTXO(server.bc.ChainstateDB, txid, 0)
+		if err == nil {
+			value, spendable = utxo.Value, map[string][]int{hex.EncodeToString(txid): {0}}
+		}
+	}
+	if err != nil || len(spendable) == 0 {
+		t.Fatalf("Wallet has no spendable outputs: %v", err)
+	}
+
+	tx := &transactions.Transaction{Version: transactions.TxVersion}
+	for txidStr, outIndexes := range spendable {
+		txid, _ := hex.DecodeString(txidStr)
+		for _, outIndex := range outIndexes {
+			tx.Vin = append(tx.Vin, transactions.TXInput{Txid: txid, OutIndex: outIndex, Sequence: transactions.MaxSequence})
+		}
+	}
+	for _, output := range outputs {
+		value -= output.Value
+	}
+	change, _ := transactions.NewTXOutput(value, wallet.address)
+	tx.Vout = append([]transactions.TXOutput{*change}, outputs...)
+	wallet.signInputs(t, server, tx)
+	return tx
+}
+
+// mines a block on top of the chain paying the given value to the miner, with the given transactions, without adding it
+func mineCoinbaseBlock(t *testing.T, server *Server, miner *testWallet, value transactions.Amount, txs ...*transactions.Transaction) *blockchain.Block {
+	height := server.bc.Height() + 1
+	coinbase := transactions.NewCoinbaseTX(miner.address, height)
+	coinbase.Vout[0].Value = value
+	block := blockchain.NewBlock(append([]*transactions.Transaction{coinbase}, txs...), server.bc.LastBlockHash(), height, server.bc.Params)
+	if medianTimePast := server.bc.MedianTimePast(block.Header.PrevBlockHeaderHash); block.Header.Timestamp <= medianTimePast {
+		block.Header.Timestamp = medianTimePast + 1
+	}
+	if !block.POW(make(chan struct{})) {
+		t.Fatalf("Error mining block %d", height)
+	}
+	return block
+}
+
+// a node with its own blockchain which isn't connected to any peers, mining to the given wallet
+func newTestNode(t *testing.T, miner *testWallet) *Server {
+	//blocks are mined with almost no work, so that tests don't wait on proof of work
+	target := blockchain.Target
+	t.Cleanup(func() { blockchain.Target = target })
+	blockchain.Target = new(big.Int).Lsh(big.NewInt(1), 255)
+
+	return &Server{
+		bc:                   blockchain.OpenBlockchain(t.TempDir(), true, &chain_params.MainNetParams),
+		minerAddress:         miner.address,
+		maxDataCarrierSize:   transactions.DefaultMaxDataCarrierSize,
+		memoryPool:           memory_pool.NewMemoryPool(),
+		peers:                make(map[string]*peer),
+		commands:             make(chan command),
+		miningChan:           make(chan struct{}),
+		invalidBlocks:        make(map[string]bool),
+		pendingCompactBlocks: make(map[string]*pendingCompactBlock),
+	}
+}
+
+func (server *Server) mineTestBlocks(numBlocks int) {
+	for i := 0; i < numBlocks; i++ {
+		server.POW()
+	}
+}
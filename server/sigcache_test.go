@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// signatures checked when a transaction enters the memory pool aren't checked again when it's mined
+func TestSigCacheSharedWithBlockValidation(t *testing.T) {
+	sigCache := transactions.SignatureCache
+	t.Cleanup(func() { transactions.SignatureCache = sigCache })
+	transactions.SignatureCache = transactions.NewSigCache(transactions.DefaultSigCacheSize)
+
+	alice := newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+
+	tx := spendTx(t, node, alice, nil)
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("Transaction was rejected: %s", err)
+	}
+	afterMempool := transactions.SignatureCache.Stats()
+	if afterMempool.Misses == 0 || afterMempool.Entries == 0 {
+		t.Fatalf("Signature verified by the memory pool wasn't cached: %+v", afterMempool)
+	}
+
+	node.mineTestBlocks(1)
+	afterBlock := transactions.SignatureCache.Stats()
+	if afterBlock.Hits <= afterMempool.Hits || afterBlock.Misses != afterMempool.Misses {
+		t.Fatalf("Block validation verified a cached signature again: %+v", afterBlock)
+	}
+
+	bounded := transactions.NewSigCache(2)
+	for i := byte(0); i < 5; i++ {
+		bounded.Add(chain_params.CurveSecp256k1, []byte{i}, []byte{i}, []byte{i})
+	}
+	if stats := bounded.Stats(); stats.Entries != 2 {
+		t.Fatalf("Cache grew past its maximum size: %+v", stats)
+	}
+}
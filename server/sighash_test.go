@@ -0,0 +1,99 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+func TestSigHashTypes(t *testing.T) {
+	alice, bob, project := newTestWallet(t), newTestWallet(t), newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+	node.minerAddress = bob.address
+	node.mineTestBlocks(1)
+
+	aliceSpend, bobSpend := spendTx(t, node, alice, nil), spendTx(t, node, bob, nil)
+	aliceInput, bobInput := aliceSpend.Vin[0], bobSpend.Vin[0]
+	goal, _ := transactions.NewTXOutput(aliceSpend.Vout[0].Value+bobSpend.Vout[0].Value, project.address)
+
+	//with SigHashAll, adding an input after signing invalidates the signature
+	tx := &transactions.Transaction{Version: transactions.TxVersion, Vin: []transactions.TXInput{aliceInput}, Vout: []transactions.TXOutput{*goal}}
+	tx.Vin[0].ScriptSig, _ = script.PubKeyHashScriptSig(alice.sign(t, node, tx, 0, transactions.SigHashAll), alice.pubKey)
+	tx.Vin = append(tx.Vin, bobInput)
+	tx.Vin[1].ScriptSig, _ = script.PubKeyHashScriptSig(bob.sign(t, node, tx, 1, transactions.SigHashAll), bob.pubKey)
+	if err := node.AddTxToMemPool(*tx); err == nil {
+		t.Fatalf("Signature was still valid after an input was added")
+	}
+
+	//crowdfunding: each contributor only commits to their own input and the goal output
+	pledge := transactions.SigHashAll | transactions.SigHashAnyOneCanPay
+	tx = &transactions.Transaction{Version: transactions.TxVersion, Vin: []transactions.TXInput{aliceInput}, Vout: []transactions.TXOutput{*goal}}
+	tx.Vin[0].ScriptSig, _ = script.PubKeyHashScriptSig(alice.sign(t, node, tx, 0, pledge), alice.pubKey)
+	tx.Vin = append(tx.Vin, bobInput)
+	tx.Vin[1].ScriptSig, _ = script.PubKeyHashScriptSig(bob.sign(t, node, tx, 1, pledge), bob.pubKey)
+
+	//the goal output can't be changed after the pledges were signed
+	redirected, _ := transactions.NewTXOutput(goal.Value, alice.address)
+	changed := *tx
+	changed.Vout = []transactions.TXOutput{*redirected}
+	if err := node.AddTxToMemPool(changed); err == nil {
+		t.Fatalf("Signature was still valid after the signed output was changed")
+	}
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("Crowdfunding transaction was rejected: %s", err)
+	}
+	node.mineTestBlocks(1)
+
+	//SigHashSingle only commits to the output with the same index, so others can be added afterwards
+	projectSpend := spendTx(t, node, project, nil)
+	projectInput, projectValue := projectSpend.Vin[0], projectSpend.Vout[0].Value
+	first, _ := transactions.NewTXOutput(projectValue/2, alice.address)
+	tx = &transactions.Transaction{Version: transactions.TxVersion, Vin: []transactions.TXInput{projectInput}, Vout: []transactions.TXOutput{*first}}
+	tx.Vin[0].ScriptSig, _ = script.PubKeyHashScriptSig(project.sign(t, node, tx, 0, transactions.SigHashSingle), project.pubKey)
+	second, _ := transactions.NewTXOutput(projectValue-first.Value, bob.address)
+	tx.Vout = append(tx.Vout, *second)
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("Output added after a SigHashSingle signature invalidated it: %s", err)
+	}
+
+	if _, err := tx.SignatureHash(0, transactions.SigHashType(0x04), transactions.TXOutput{}); err == nil {
+		t.Fatalf("Invalid hash type was accepted")
+	}
+}
+
+// a signature for one input can't be reused for another input spending an output locked to the same key
+func TestSignatureBindsInput(t *testing.T) {
+	alice := newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(2)
+
+	tx := spendTx(t, node, alice, nil)
+	if len(tx.Vin) != 2 {
+		t.Fatalf("Expected alice to spend two outputs, spent %d", len(tx.Vin))
+	}
+
+	reused := *tx
+	reused.Vin = append([]transactions.TXInput{}, tx.Vin...)
+	reused.Vin[1].ScriptSig = reused.Vin[0].ScriptSig
+	if err := node.AddTxToMemPool(reused); err == nil {
+		t.Fatalf("Signature of one input was accepted for another input")
+	}
+
+	//a signature committing to a different value for the spent output is invalid
+	spentOutput, _ := transactions.GetUTXO(node.bc.ChainstateDB, tx.Vin[0].Txid, tx.Vin[0].OutIndex)
+	spentOutput.Value++
+	sigHash, _ := tx.SignatureHash(0, transactions.SigHashAll, spentOutput)
+	signature := alice.signHash(t, sigHash, transactions.SigHashAll)
+	wrongValue := *tx
+	wrongValue.Vin = append([]transactions.TXInput{}, tx.Vin...)
+	wrongValue.Vin[0].ScriptSig, _ = script.PubKeyHashScriptSig(signature, alice.pubKey)
+	if err := node.AddTxToMemPool(wrongValue); err == nil {
+		t.Fatalf("Signature over the wrong spent value was accepted")
+	}
+
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("Correctly signed transaction was rejected: %s", err)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	btcecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+func TestSchnorrSignatures(t *testing.T) {
+	alice := newTestWallet(t)
+	alice.schnorr = true
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+
+	tx := spendTx(t, node, alice, nil)
+	if len(tx.Vin[0].ScriptSig) == 0 {
+		t.Fatalf("Transaction wasn't signed")
+	}
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("Schnorr signed transaction was rejected: %s", err)
+	}
+}
+
+func TestNonCanonicalSignatures(t *testing.T) {
+	alice := newTestWallet(t)
+	node := newTestNode(t, alice)
+	node.mineTestBlocks(1)
+	tx := spendTx(t, node, alice, nil)
+
+	//negating S keeps the ECDSA signature valid, but it's no longer the canonical low S encoding
+	signature, hashType, _ := transactions.SplitSignature(tx.Vin[0].ScriptSig[1 : 1+tx.Vin[0].ScriptSig[0]])
+	sLen := int(signature[5+signature[3]])
+	sStart := 6 + int(signature[3])
+	var s btcec.ModNScalar
+	s.SetByteSlice(signature[sStart : sStart+sLen])
+	highS := s.Negate().Bytes()
+	highSSignature := append([]byte{}, signature[:sStart-2]...)
+	highSSignature = append(highSSignature, 0x02, 33, 0x00)
+	highSSignature = append(highSSignature, highS[:]...)
+	highSSignature[1] = byte(len(highSSignature) - 2)
+	sigHash, _, _ := node.SignatureHash(*tx, 0, hashType)
+	if parsed, err := btcecdsa.ParseDERSignature(highSSignature); err != nil || !parsed.Verify(sigHash, alice.privKey.PubKey()) {
+		t.Fatalf("Malleated signature isn't a valid ECDSA signature: %v", err)
+	}
+
+	malleated := *tx
+	malleated.Vin = append([]transactions.TXInput{}, tx.Vin...)
+	malleated.Vin[0].ScriptSig, _ = script.PubKeyHashScriptSig(append(highSSignature, byte(hashType)), alice.pubKey)
+	if err := node.AddTxToMemPool(malleated); err == nil {
+		t.Fatalf("Signature with a high S value was accepted")
+	}
+
+	uncompressed := *tx
+	uncompressed.Vin = append([]transactions.TXInput{}, tx.Vin...)
+	uncompressed.Vin[0].ScriptSig, _ = script.PubKeyHashScriptSig(append(signature, byte(hashType)), alice.privKey.PubKey().SerializeUncompressed())
+	if err := node.AddTxToMemPool(uncompressed); err == nil {
+		t.Fatalf("Uncompressed pubkey was accepted")
+	}
+
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("Canonical signature was rejected: %s", err)
+	}
+}
+
+// the signature curve is a network parameter, so P256 signatures are only valid on the legacy network
+func TestSignatureCurveIsNetworkParameter(t *testing.T) {
+	privKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pubKey := append(privKey.X.FillBytes(make([]byte, 32)), privKey.Y.FillBytes(make([]byte, 32))...)
+	miner := newTestWallet(t)
+	miner.pubKey = pubKey
+	miner.pubKeyHash = utils.HashPublicKey(pubKey)
+	miner.address = transactions.PubKeyHashAddress(miner.pubKeyHash)
+
+	node := newTestNode(t, miner)
+	node.mineTestBlocks(1)
+	tx := spendTx(t, node, miner, nil) //signed with a secp256k1 key, which doesn't match the pubkey
+
+	sigHash, _, _ := node.SignatureHash(*tx, 0, transactions.SigHashAll)
+	r, s, _ := ecdsa.Sign(rand.Reader, privKey, sigHash)
+	p256Signature := append(append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...), byte(transactions.SigHashAll))
+	tx.Vin[0].ScriptSig, _ = script.PubKeyHashScriptSig(p256Signature, pubKey)
+
+	if err := node.AddTxToMemPool(*tx); err == nil {
+		t.Fatalf("P256 signature was accepted on mainnet")
+	}
+
+	node.bc.Params = &chain_params.LegacyNetParams
+	if err := node.AddTxToMemPool(*tx); err != nil {
+		t.Fatalf("P256 signature was rejected on the legacy network: %s", err)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/transactions"
+)
+
+// the coinbase may pay at most the block subsidy plus the fees of the block's transactions
+func TestCoinbaseValue(t *testing.T) {
+	miner := newTestWallet(t)
+	node := newTestNode(t, miner)
+	node.mineTestBlocks(1)
+
+	const fee transactions.Amount = 1000
+	coinbaseTxid := node.bc.GetBlock(node.bc.LastBlockHash()).Transactions[0].Hash()
+	tx := spendTx(t, node, miner, coinbaseTxid)
+	tx.Vout[0].Value -= fee
+	miner.signInputs(t, node, tx)
+
+	subsidy := transactions.BlockSubsidy(node.bc.Height() + 1)
+	tooLarge := mineCoinbaseBlock(t, node, miner, subsidy+fee+1, tx)
+	if err := node.bc.VerifyBlock(tooLarge); !errors.Is(err, &blockchain_errors.ErrCoinbaseValueTooLarge{}) {
+		t.Fatalf("Coinbase paying more than the subsidy plus fees was accepted: %v", err)
+	}
+	if err := node.bc.AddBlock(mineCoinbaseBlock(t, node, miner, subsidy+fee, tx)); err != nil {
+		t.Fatalf("Coinbase paying the subsidy plus fees was rejected: %s", err)
+	}
+}
+
+// an output spent by two inputs of the same transaction would be counted twice towards its inputs' value
+func TestDuplicateInputs(t *testing.T) {
+	miner := newTestWallet(t)
+	node := newTestNode(t, miner)
+	node.mineTestBlocks(1)
+
+	coinbaseTxid := node.bc.GetBlock(node.bc.LastBlockHash()).Transactions[0].Hash()
+	tx := spendTx(t, node, miner, coinbaseTxid)
+	tx.Vin = append(tx.Vin, tx.Vin[0])
+	tx.Vout[0].Value *= 2
+	miner.signInputs(t, node, tx)
+
+	if err := node.AddTxToMemPool(*tx); !errors.Is(err, &blockchain_errors.ErrDuplicateInput{}) {
+		t.Fatalf("Transaction spending an output twice was accepted into the memory pool: %v", err)
+	}
+	block := mineCoinbaseBlock(t, node, miner, transactions.BlockSubsidy(node.bc.Height()+1), tx)
+	if err := node.bc.VerifyBlock(block); !errors.Is(err, &blockchain_errors.ErrDuplicateInput{}) {
+		t.Fatalf("Block with a transaction spending an output twice was accepted: %v", err)
+	}
+}
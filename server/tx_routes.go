@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+func (server *Server) GetTransactionHandler(c *gin.Context) {
+	txHash, err := hex.DecodeString(c.Param("txid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction id format"})
+		return
+	}
+
+	tx, block, inMempool, err := server.FindTransaction(txHash)
+	if errors.Is(err, &blockchain_errors.ErrTxNotFound{}) || errors.Is(err, &blockchain_errors.ErrTxIndexDisabled{}) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error finding transaction"})
+		return
+	}
+
+	if inMempool {
+		c.JSON(http.StatusOK, gin.H{
+			"transaction":   tx,
+			"wtxid":         hex.EncodeToString(tx.WitnessHash()),
+			"blockHash":     nil,
+			"blockHeight":   nil,
+			"confirmations": 0,
+			"inMempool":     true,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transaction":   tx,
+		"wtxid":         hex.EncodeToString(tx.WitnessHash()),
+		"blockHash":     hex.EncodeToString(block.GetBlockHeaderHash()),
+		"blockHeight":   block.Header.Height,
+		"confirmations": server.bc.Confirmations(block),
+		"inMempool":     false,
+	})
+}
+
+// returns the data carried by the data outputs of a transaction
+func (server *Server) GetTransactionDataHandler(c *gin.Context) {
+	txHash, err := hex.DecodeString(c.Param("txid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction id format"})
+		return
+	}
+
+	tx, _, inMempool, err := server.FindTransaction(txHash)
+	if errors.Is(err, &blockchain_errors.ErrTxNotFound{}) || errors.Is(err, &blockchain_errors.ErrTxIndexDisabled{}) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error finding transaction"})
+		return
+	}
+
+	dataOutputs := []gin.H{}
+	for outIndex, txoutput := range tx.Vout {
+		if data, isData := txoutput.Data(); isData {
+			dataOutputs = append(dataOutputs, gin.H{
+				"outIndex": outIndex,
+				"data":     hex.EncodeToString(data),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"txid":        hex.EncodeToString(txHash),
+		"dataOutputs": dataOutputs,
+		"inMempool":   inMempool,
+	})
+}
+
+func (server *Server) AddTxRoutes(r *gin.Engine) {
+	r.GET("/tx/:txid", server.GetTransactionHandler)
+	r.GET("/tx/:txid/data", server.GetTransactionDataHandler)
+}
@@ -20,7 +20,23 @@ func (server *Server) AddTransactionHandler(c *gin.Context) {
 	}
 
 	if err := server.AddTxToMemPool(tx); err != nil {
-		if errors.Is(err, &blockchain_errors.ErrInvalidTxInputSignature{}) {
+		if errors.Is(err, &blockchain_errors.ErrSequenceLocked{}) {
+			c.JSON(http.StatusAccepted, gin.H{"message": "Transaction is waiting for its inputs' relative lock times"})
+			return
+		}
+		if errors.Is(err, &blockchain_errors.ErrWaitingPoolFull{}) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, &blockchain_errors.ErrInvalidTxInputSignature{}) ||
+			errors.Is(err, &blockchain_errors.ErrTxTooLarge{}) ||
+			errors.Is(err, &blockchain_errors.ErrDuplicateTx{}) ||
+			errors.Is(err, &blockchain_errors.ErrAmountOutOfRange{}) ||
+			errors.Is(err, &blockchain_errors.ErrUnexpectedCoinbase{}) ||
+			errors.Is(err, &blockchain_errors.ErrNonFinalTx{}) ||
+			errors.Is(err, &blockchain_errors.ErrUnsupportedTxVersion{}) ||
+			errors.Is(err, &blockchain_errors.ErrNonZeroDataOutput{}) ||
+			errors.Is(err, &blockchain_errors.ErrDataCarrierTooLarge{}) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -57,13 +73,13 @@ func (server *Server) FindSpendableUTXOsHandler(c *gin.Context) {
 		return
 	}
 
-	amount, err := strconv.Atoi(amountStr)
-	if err != nil {
+	amount, err := strconv.ParseInt(amountStr, 10, 64)
+	if err != nil || !transactions.MoneyRange(transactions.Amount(amount)) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount format"})
 		return
 	}
 
-	utxosTotal, spendableUTXOs, err := server.FindSpendableUTXOs(pubKeyHash, amount)
+	utxosTotal, spendableUTXOs, err := server.FindSpendableUTXOs(pubKeyHash, transactions.Amount(amount))
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error finding spendable UTXOs"})
@@ -76,11 +92,62 @@ func (server *Server) FindSpendableUTXOsHandler(c *gin.Context) {
 	})
 }
 
+type sigHashRequest struct {
+	Transaction transactions.Transaction `json:"transaction"`
+	InputIdx    int                      `json:"inputIdx"`
+	HashType    transactions.SigHashType `json:"hashType"`
+}
+
+// returns the hash to be signed for an input of a transaction, the signature must then be followed by the hash type
+func (server *Server) SignatureHashHandler(c *gin.Context) {
+	var request sigHashRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature hash format"})
+		return
+	}
+	if request.HashType == 0 {
+		request.HashType = transactions.SigHashAll
+	}
+
+	sigHash, spentOutput, err := server.SignatureHash(request.Transaction, request.InputIdx, request.HashType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	//the spent output is returned so that signers can check the value they're spending, which is part of the hash
+	c.JSON(http.StatusOK, gin.H{"sigHash": sigHash, "hashType": request.HashType, "spentOutput": spentOutput})
+}
+
+// computes the signature hash of an input, looking up the output it spends in the chainstate
+func (server *Server) SignatureHash(tx transactions.Transaction, inputIdx int, hashType transactions.SigHashType) ([]byte, *transactions.TXOutput, error) {
+	if inputIdx < 0 || inputIdx >= len(tx.Vin) {
+		return nil, nil, &blockchain_errors.ErrInvalidInputUTXO{}
+	}
+	spentOutput, err := transactions.GetUTXO(server.bc.ChainstateDB, tx.Vin[inputIdx].Txid, tx.Vin[inputIdx].OutIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+	sigHash, err := tx.SignatureHash(inputIdx, hashType, spentOutput)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sigHash, &spentOutput, nil
+}
+
 func (server *Server) AddWalletRoutes(r *gin.Engine) {
 	walletRoutes := r.Group("/wallet")
 	{
 		walletRoutes.POST("/transactions", server.AddTransactionHandler)
 		walletRoutes.GET("/utxos", server.FindUTXOsHandler)
 		walletRoutes.GET("/spendable_utxos", server.FindSpendableUTXOsHandler)
+		walletRoutes.POST("/sighash", server.SignatureHashHandler)
+		walletRoutes.POST("/multisig/script", server.MultisigScriptHandler)
+		walletRoutes.POST("/multisig/spend", server.MultisigSpendHandler)
+		walletRoutes.POST("/multisig/sign", server.MultisigSignHandler)
+		walletRoutes.POST("/multisig/combine", server.MultisigCombineHandler)
+		walletRoutes.POST("/htlc/script", server.HTLCScriptHandler)
+		walletRoutes.POST("/htlc/claim", server.HTLCClaimHandler)
+		walletRoutes.POST("/htlc/refund", server.HTLCRefundHandler)
 	}
 }
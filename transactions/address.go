@@ -0,0 +1,41 @@
+package transactions
+
+import (
+	"github.com/btcsuite/btcd/btcutil/base58"
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+// version bytes of base58 addresses, which determine whether the payload is a pubkey hash or a redeem script hash
+const PubKeyHashAddrVersion byte = 0x00
+const ScriptHashAddrVersion byte = 0x05
+
+// returns the locking script paying to the given address
+func AddressScript(address string) ([]byte, error) {
+	payload, version, err := base58.CheckDecode(address)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) != 20 {
+		return nil, &blockchain_errors.ErrInvalidAddress{}
+	}
+
+	switch version {
+	case PubKeyHashAddrVersion:
+		return script.PayToPubKeyHashScript(payload)
+	case ScriptHashAddrVersion:
+		return script.PayToScriptHashScript(payload)
+	default:
+		return nil, &blockchain_errors.ErrInvalidAddress{}
+	}
+}
+
+func PubKeyHashAddress(pubKeyHash []byte) string {
+	return base58.CheckEncode(pubKeyHash, PubKeyHashAddrVersion)
+}
+
+// address paying to the hash of the given redeem script, so that senders don't need to know the receiver's policy
+func ScriptHashAddress(redeemScript []byte) string {
+	return base58.CheckEncode(utils.HashPublicKey(redeemScript), ScriptHashAddrVersion)
+}
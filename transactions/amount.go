@@ -0,0 +1,13 @@
+package transactions
+
+// monetary amount in base units, the smallest indivisible unit of the currency
+type Amount int64
+
+const BaseUnitsPerCoin Amount = 100000000
+
+// no valid output or sum of outputs can exceed the total supply of the currency
+const MaxMoney Amount = 21000000 * BaseUnitsPerCoin
+
+func MoneyRange(amount Amount) bool {
+	return amount >= 0 && amount <= MaxMoney
+}
@@ -0,0 +1,61 @@
+package transactions
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+func TestOutputsTotal(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []Amount
+		valid  bool
+	}{
+		{"no outputs", nil, true},
+		{"maximum supply", []Amount{MaxMoney}, true},
+		{"outputs adding up to the maximum supply", []Amount{MaxMoney - 1, 1}, true},
+		{"negative output", []Amount{5, -1}, false},
+		{"output over the maximum supply", []Amount{MaxMoney + 1}, false},
+		{"total over the maximum supply", []Amount{MaxMoney, 1}, false},
+		{"outputs overflowing to zero", []Amount{math.MaxInt64, math.MaxInt64, 2}, false},
+	}
+	for _, test := range tests {
+		tx := Transaction{}
+		var expected Amount
+		for _, value := range test.values {
+			tx.Vout = append(tx.Vout, TXOutput{Value: value})
+			expected += value
+		}
+		total, err := tx.OutputsTotal()
+		if test.valid && (err != nil || total != expected) {
+			t.Fatalf("%s: total %d was rejected or wrong: %v", test.name, expected, err)
+		}
+		if !test.valid && !errors.Is(err, &blockchain_errors.ErrAmountOutOfRange{}) {
+			t.Fatalf("%s: total was accepted: %v", test.name, err)
+		}
+	}
+}
+
+// the subsidy halves at every interval, and all of them together never exceed the maximum supply
+func TestBlockSubsidy(t *testing.T) {
+	if BlockSubsidy(0) != 10*BaseUnitsPerCoin || BlockSubsidy(SubsidyHalvingInterval-1) != 10*BaseUnitsPerCoin {
+		t.Fatalf("Subsidy of the first interval isn't 10 coins")
+	}
+	if BlockSubsidy(SubsidyHalvingInterval) != 5*BaseUnitsPerCoin {
+		t.Fatalf("Subsidy wasn't halved after the first interval")
+	}
+	if BlockSubsidy(64*SubsidyHalvingInterval) != 0 {
+		t.Fatalf("Subsidy isn't zero after 64 halvings")
+	}
+
+	var supply Amount
+	for height := 0; BlockSubsidy(height) > 0; height += SubsidyHalvingInterval {
+		supply += BlockSubsidy(height) * SubsidyHalvingInterval
+	}
+	if !MoneyRange(supply) || supply < MaxMoney-BaseUnitsPerCoin {
+		t.Fatalf("Total subsidy %d doesn't add up to the maximum supply", supply)
+	}
+}
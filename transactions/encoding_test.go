@@ -0,0 +1,38 @@
+package transactions
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// the layout and ids of transaction encoding version 1, which stored blocks and chainstates are keyed by
+func TestTransactionEncoding(t *testing.T) {
+	tx := Transaction{
+		Version:  TxVersion,
+		Vin:      []TXInput{{Txid: bytes.Repeat([]byte{0xab}, 32), OutIndex: 1, ScriptSig: []byte{1, 2, 3}, Sequence: 7}},
+		Vout:     []TXOutput{{Value: 5 * BaseUnitsPerCoin, ScriptPubKey: []byte{0x51}}},
+		LockTime: 42,
+	}
+	for _, test := range []struct {
+		name     string
+		encoded  []byte
+		expected string
+	}{
+		{"encoding", tx.Serialize(), "0101000000000120" + strings.Repeat("ab", 32) + "010000000301020307000000010065cd1d0000000001512a000000"},
+		{"id", tx.Hash(), "a56d7d05b71d384774c0d4c0ca76e43ebb5a205ed0b611c61f38bac7dfb77d61"},
+		{"witness hash", tx.WitnessHash(), "6273df18a3a833412ba68d7322ae958b870372f8e936b5aa064ff8c6cd67221e"},
+	} {
+		if hex.EncodeToString(test.encoded) != test.expected {
+			t.Errorf("Transaction %s changed: %x", test.name, test.encoded)
+		}
+	}
+
+	resigned := tx
+	resigned.Vin = []TXInput{tx.Vin[0]}
+	resigned.Vin[0].ScriptSig = []byte{4, 5}
+	if !bytes.Equal(resigned.Hash(), tx.Hash()) || bytes.Equal(resigned.WitnessHash(), tx.WitnessHash()) {
+		t.Fatalf("Input scripts aren't covered only by the witness hash")
+	}
+}
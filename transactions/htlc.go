@@ -0,0 +1,35 @@
+package transactions
+
+import (
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/script"
+)
+
+func NewHTLCOutput(value Amount, params script.HTLCParams) (*TXOutput, error) {
+	scriptPubKey, err := script.HTLCScript(params)
+	if err != nil {
+		return nil, err
+	}
+	return &TXOutput{value, scriptPubKey}, nil
+}
+
+// builds an unsigned transaction spending an HTLC output to the given output, either claiming it with the preimage or,
+// once its lock time is reached, refunding it to the sender
+func NewHTLCSpendTx(txid []byte, outIndex int, htlc TXOutput, destination TXOutput, refund bool) (*Transaction, error) {
+	params, isHTLC := script.ExtractHTLC(htlc.ScriptPubKey)
+	if !isHTLC {
+		return nil, &blockchain_errors.ErrNotHTLC{}
+	}
+
+	tx := &Transaction{
+		Version: TxVersion,
+		Vin:     []TXInput{{Txid: txid, OutIndex: outIndex, Sequence: MaxSequence}},
+		Vout:    []TXOutput{destination},
+	}
+	if refund {
+		//OP_CHECKLOCKTIMEVERIFY requires the spending transaction's lock time to be enforced
+		tx.LockTime = uint32(params.LockTime)
+		tx.Vin[0].Sequence = MaxSequence - 1
+	}
+	return tx, nil
+}
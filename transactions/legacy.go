@@ -0,0 +1,108 @@
+package transactions
+
+import (
+	"bytes"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	legacy_transactions "github.com/pedrogomes29/blockchain_node/legacy/transactions"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+// version of the transactions converted from the chain stored with gob, before the binary encoding existed. they're
+// only valid on networks whose history has them, and their ids and signatures are still computed over their gob
+// encoding, so that the inputs spending them and their signatures stay valid
+const LegacyTxVersion int32 = 0
+
+// converts a transaction stored with gob: each input's signature and public key are pushed by its input script,
+// outputs are locked to their pubkey hash by pay to pubkey hash scripts and values in coins become base units
+func FromLegacy(legacyTx legacy_transactions.Transaction) (*Transaction, error) {
+	tx := &Transaction{Version: LegacyTxVersion, IsCoinbase: legacyTx.IsCoinbase}
+	for _, legacyIn := range legacyTx.Vin {
+		scriptSig, err := script.NewScriptBuilder().AddData(legacyIn.Signature).AddData(legacyIn.PubKey).Script()
+		if err != nil {
+			return nil, err
+		}
+		tx.Vin = append(tx.Vin, TXInput{
+			Txid:      legacyIn.Txid,
+			OutIndex:  legacyIn.OutIndex,
+			ScriptSig: scriptSig,
+			Sequence:  MaxSequence,
+		})
+	}
+	for _, legacyOut := range legacyTx.Vout {
+		if legacyOut.Value < 0 || Amount(legacyOut.Value) > MaxMoney/BaseUnitsPerCoin {
+			return nil, &blockchain_errors.ErrAmountOutOfRange{}
+		}
+		if len(legacyOut.PubKeyHash) != 20 {
+			return nil, &blockchain_errors.ErrInvalidLegacyTx{}
+		}
+		scriptPubKey, err := script.PayToPubKeyHashScript(legacyOut.PubKeyHash)
+		if err != nil {
+			return nil, err
+		}
+		tx.Vout = append(tx.Vout, TXOutput{Value: Amount(legacyOut.Value) * BaseUnitsPerCoin, ScriptPubKey: scriptPubKey})
+	}
+	return tx, nil
+}
+
+// the transaction as it was stored with gob, failing if it isn't the conversion of one
+func (tx Transaction) toLegacy() (legacy_transactions.Transaction, error) {
+	legacyTx := legacy_transactions.Transaction{IsCoinbase: tx.IsCoinbase}
+	for _, txIn := range tx.Vin {
+		pushes, err := script.PushedData(txIn.ScriptSig)
+		if err != nil || len(pushes) != 2 {
+			return legacyTx, &blockchain_errors.ErrInvalidLegacyTx{}
+		}
+		legacyTx.Vin = append(legacyTx.Vin, legacy_transactions.TXInput{
+			Txid:      txIn.Txid,
+			OutIndex:  txIn.OutIndex,
+			Signature: pushes[0],
+			PubKey:    pushes[1],
+		})
+	}
+	for _, txOut := range tx.Vout {
+		if script.GetScriptClass(txOut.ScriptPubKey) != script.PubKeyHashTy || txOut.Value%BaseUnitsPerCoin != 0 {
+			return legacyTx, &blockchain_errors.ErrInvalidLegacyTx{}
+		}
+		legacyTx.Vout = append(legacyTx.Vout, legacy_transactions.TXOutput{
+			Value:      int(txOut.Value / BaseUnitsPerCoin),
+			PubKeyHash: script.ExtractPubKeyHash(txOut.ScriptPubKey),
+		})
+	}
+	return legacyTx, nil
+}
+
+// a legacy transaction must be exactly the conversion of a transaction stored with gob, so that it has a single
+// encoding: minimal pushes, the maximum sequence numbers and no lock time
+func (tx Transaction) verifyLegacy() error {
+	legacyTx, err := tx.toLegacy()
+	if err != nil {
+		return err
+	}
+	converted, err := FromLegacy(legacyTx)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(converted.Serialize(), tx.Serialize()) {
+		return &blockchain_errors.ErrInvalidLegacyTx{}
+	}
+	return tx.verifyOutputs()
+}
+
+// legacy inputs sign the hash of the gob encoded transaction without any signatures, with a P256 key whose hash the
+// spent output is locked to
+func (check ScriptCheck) executeLegacy() error {
+	legacyTx, err := check.tx.toLegacy()
+	if err != nil {
+		return err
+	}
+	legacyIn := legacyTx.Vin[check.inputIdx]
+	if !bytes.Equal(utils.HashPublicKey(legacyIn.PubKey), script.ExtractPubKeyHash(check.spentOutput.ScriptPubKey)) {
+		return &blockchain_errors.ErrInvalidTxInputSignature{}
+	}
+	if !verifyP256(legacyIn.Signature, legacyIn.PubKey, legacyTx.TrimmedCopy().Hash()) {
+		return &blockchain_errors.ErrInvalidTxInputSignature{}
+	}
+	return nil
+}
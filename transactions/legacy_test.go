@@ -0,0 +1,47 @@
+package transactions
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	legacy_transactions "github.com/pedrogomes29/blockchain_node/legacy/transactions"
+)
+
+// a legacy transaction keeps its gob id, and is only valid as the exact conversion of one on the legacy network
+func TestLegacyTransaction(t *testing.T) {
+	legacyTx := legacy_transactions.Transaction{
+		Vin:  []legacy_transactions.TXInput{{Txid: bytes.Repeat([]byte{1}, 32), OutIndex: 1, Signature: []byte{4, 5}, PubKey: []byte{6, 7}}},
+		Vout: []legacy_transactions.TXOutput{{Value: 7, PubKeyHash: bytes.Repeat([]byte{8}, 20)}},
+	}
+	tx, err := FromLegacy(legacyTx)
+	if err != nil {
+		t.Fatalf("Error converting legacy transaction: %s", err)
+	}
+	if !bytes.Equal(tx.Hash(), legacyTx.Hash()) || tx.Vout[0].Value != 7*BaseUnitsPerCoin {
+		t.Fatalf("Converted transaction doesn't keep the legacy id and value")
+	}
+	if err := tx.verifyStructure(0, &chain_params.LegacyNetParams); err != nil {
+		t.Fatalf("Converted transaction was rejected: %s", err)
+	}
+
+	withSequence := *tx
+	withSequence.Vin = []TXInput{tx.Vin[0]}
+	withSequence.Vin[0].Sequence = 0
+	nonMinimalPush := *tx
+	nonMinimalPush.Vin = []TXInput{tx.Vin[0]}
+	nonMinimalPush.Vin[0].ScriptSig = append([]byte{0x4c, 2}, tx.Vin[0].ScriptSig[1:]...)
+	fractional := *tx
+	fractional.Vout = []TXOutput{{Value: tx.Vout[0].Value + 1, ScriptPubKey: tx.Vout[0].ScriptPubKey}}
+	for name, invalid := range map[string]Transaction{"sequence": withSequence, "non minimal push": nonMinimalPush, "fractional value": fractional} {
+		if err := invalid.verifyStructure(0, &chain_params.LegacyNetParams); !errors.Is(err, &blockchain_errors.ErrInvalidLegacyTx{}) {
+			t.Errorf("Legacy transaction with a %s was accepted: %v", name, err)
+		}
+	}
+
+	if err := tx.verifyStructure(0, &chain_params.MainNetParams); !errors.Is(err, &blockchain_errors.ErrUnsupportedTxVersion{}) {
+		t.Fatalf("Legacy transaction was accepted on %s: %v", chain_params.MainNetParams.Name, err)
+	}
+}
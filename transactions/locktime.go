@@ -0,0 +1,81 @@
+package transactions
+
+import (
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// lock times below this value are block heights, lock times at or above it are unix timestamps
+const LockTimeThreshold uint32 = 500000000
+
+// whether the transaction can be included in a block with the given height and median time past
+func (tx Transaction) IsFinal(blockHeight int, blockTime int64) bool {
+	if tx.LockTime == 0 {
+		return true
+	}
+
+	var lockTimeLimit int64
+	if tx.LockTime < LockTimeThreshold {
+		lockTimeLimit = int64(blockHeight)
+	} else {
+		lockTimeLimit = blockTime
+	}
+	if int64(tx.LockTime) < lockTimeLimit {
+		return true
+	}
+
+	//the lock time is ignored if every input has opted out of it
+	for _, txIn := range tx.Vin {
+		if txIn.Sequence != MaxSequence {
+			return false
+		}
+	}
+	return true
+}
+
+// if set in an input's sequence number, the input has no relative lock time
+const SequenceLockTimeDisableFlag uint32 = 1 << 31
+
+// bits of the sequence number holding the number of confirmations the spent output must have
+const SequenceLockTimeMask uint32 = 0x0000ffff
+
+// number of confirmations the output spent by the input must have, or 0 if the input has no relative lock time
+func (txIn TXInput) RelativeLockTime() int {
+	if txIn.Sequence&SequenceLockTimeDisableFlag != 0 {
+		return 0
+	}
+	return int(txIn.Sequence & SequenceLockTimeMask)
+}
+
+func (tx Transaction) HasRelativeLockTimes() bool {
+	for _, txIn := range tx.Vin {
+		if txIn.RelativeLockTime() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// returns an error if any input spends an output without the confirmations required by the input's relative lock time
+// when included in a block with the given height
+func (tx Transaction) VerifySequenceLocks(chainstateDB *leveldb.DB, blockHeight int) error {
+	if tx.IsCoinbase {
+		return nil
+	}
+
+	for _, txIn := range tx.Vin {
+		relativeLockTime := txIn.RelativeLockTime()
+		if relativeLockTime == 0 {
+			continue
+		}
+
+		utxoHeight, err := GetUTXOsHeight(chainstateDB, txIn.Txid)
+		if err != nil {
+			return err
+		}
+		if blockHeight-utxoHeight < relativeLockTime {
+			return &blockchain_errors.ErrSequenceLocked{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package transactions
+
+import "testing"
+
+func TestIsFinal(t *testing.T) {
+	const heightLock, timeLock = 100, 500001000 //time lock 1000 seconds after LockTimeThreshold
+	tests := []struct {
+		name        string
+		lockTime    uint32
+		sequence    uint32
+		blockHeight int
+		blockTime   int64
+		final       bool
+	}{
+		{"no lock time", 0, 0, 0, 0, true},
+		{"height lock at its height", heightLock, 0, heightLock, timeLock + 1, false},
+		{"height lock after its height", heightLock, 0, heightLock + 1, 0, true},
+		{"height lock opted out of", heightLock, MaxSequence, heightLock, 0, true},
+		{"highest height lock", LockTimeThreshold - 1, 0, int(LockTimeThreshold), 0, true},
+		{"time lock at its time", timeLock, 0, timeLock + 1, timeLock, false},
+		{"time lock after its time", timeLock, 0, 0, timeLock + 1, true},
+		{"time lock opted out of", timeLock, MaxSequence, 0, timeLock, true},
+		{"lowest time lock", LockTimeThreshold, 0, int(LockTimeThreshold) + 1, int64(LockTimeThreshold), false},
+	}
+	for _, test := range tests {
+		tx := Transaction{Vin: []TXInput{{Sequence: test.sequence}}, LockTime: test.lockTime}
+		if final := tx.IsFinal(test.blockHeight, test.blockTime); final != test.final {
+			t.Errorf("%s: final is %t, expected %t", test.name, final, test.final)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package transactions
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/utils"
+)
+
+func NewMultisigOutput(value Amount, numSignatures int, pubKeys [][]byte) (*TXOutput, error) {
+	scriptPubKey, err := script.MultisigScript(numSignatures, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &TXOutput{value, scriptPubKey}, nil
+}
+
+// a transaction spending multisig outputs, collecting the signatures of each input until there are enough to spend it
+type PartiallySignedTx struct {
+	Tx     Transaction
+	Inputs []PartiallySignedInput
+}
+
+type PartiallySignedInput struct {
+	Value        Amount            //value of the output being spent
+	ScriptPubKey []byte            //locking script of the output being spent
+	RedeemScript []byte            //multisig script, if the output being spent pays to its hash
+	SigHash      []byte            //hash which must be signed by the signers of this input, with SigHashAll
+	Signatures   map[string][]byte //maps the hex encoded pubkey of each signer to its signature
+}
+
+func (input PartiallySignedInput) spentOutput() TXOutput {
+	return TXOutput{input.Value, input.ScriptPubKey}
+}
+
+// the multisig script whose signatures are being collected
+func (input PartiallySignedInput) multisigScript() []byte {
+	if input.RedeemScript != nil {
+		return input.RedeemScript
+	}
+	return input.ScriptPubKey
+}
+
+// redeemScripts holds the multisig script of each input spending a pay to script hash output, and nil for the others
+func NewPartiallySignedTx(tx Transaction, spentOutputs []TXOutput, redeemScripts [][]byte) (*PartiallySignedTx, error) {
+	if len(spentOutputs) != len(tx.Vin) || len(redeemScripts) != len(tx.Vin) {
+		return nil, &blockchain_errors.ErrNotMultisig{}
+	}
+
+	partial := &PartiallySignedTx{Tx: tx}
+	midstates := tx.sigHashMidstates()
+	for inputIdx, spentOutput := range spentOutputs {
+		sigHash, err := tx.signatureHash(midstates, inputIdx, SigHashAll, spentOutput)
+		if err != nil {
+			return nil, err
+		}
+		input := PartiallySignedInput{
+			Value:        spentOutput.Value,
+			ScriptPubKey: spentOutput.ScriptPubKey,
+			SigHash:      sigHash,
+			Signatures:   make(map[string][]byte),
+		}
+
+		if script.GetScriptClass(spentOutput.ScriptPubKey) == script.ScriptHashTy {
+			redeemScript := redeemScripts[inputIdx]
+			if !bytes.Equal(utils.HashPublicKey(redeemScript), script.ExtractScriptHash(spentOutput.ScriptPubKey)) {
+				return nil, &blockchain_errors.ErrRedeemScriptMismatch{}
+			}
+			input.RedeemScript = redeemScript
+		}
+
+		if script.GetScriptClass(input.multisigScript()) != script.MultiSigTy {
+			return nil, &blockchain_errors.ErrNotMultisig{}
+		}
+		partial.Inputs = append(partial.Inputs, input)
+	}
+	return partial, nil
+}
+
+// adds the signature of one of the multisig pubkeys to an input, after checking it's valid on the network with the
+// given params
+func (partial *PartiallySignedTx) AddSignature(inputIdx int, pubKey []byte, signature []byte, params *chain_params.Params) error {
+	if inputIdx < 0 || inputIdx >= len(partial.Inputs) {
+		return &blockchain_errors.ErrInvalidInputUTXO{}
+	}
+	input := &partial.Inputs[inputIdx]
+
+	_, pubKeys, isMultisig := script.ExtractMultisig(input.multisigScript())
+	if !isMultisig {
+		return &blockchain_errors.ErrNotMultisig{}
+	}
+	isSigner := false
+	for _, signerPubKey := range pubKeys {
+		isSigner = isSigner || bytes.Equal(signerPubKey, pubKey)
+	}
+	if !isSigner {
+		return &blockchain_errors.ErrUnknownSigner{}
+	}
+
+	checker := &txSignatureChecker{tx: &partial.Tx, inputIdx: inputIdx, spentOutput: input.spentOutput(), sigHashes: newSigHashCache(&partial.Tx), curve: params.SignatureCurve}
+	if !checker.CheckSig(signature, pubKey) {
+		return &blockchain_errors.ErrInvalidTxInputSignature{}
+	}
+
+	if input.Signatures == nil {
+		input.Signatures = make(map[string][]byte)
+	}
+	input.Signatures[hex.EncodeToString(pubKey)] = signature
+	return nil
+}
+
+// merges the signatures of several partially signed versions of the same transaction
+func CombinePartiallySignedTxs(partials []PartiallySignedTx, params *chain_params.Params) (*PartiallySignedTx, error) {
+	if len(partials) == 0 {
+		return nil, &blockchain_errors.ErrPartialTxMismatch{}
+	}
+
+	unsignedTxHash := partials[0].Tx.TrimmedCopy().Hash()
+	spentOutputs, redeemScripts := spentScripts(partials[0])
+	combined, err := NewPartiallySignedTx(partials[0].Tx, spentOutputs, redeemScripts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, partial := range partials {
+		if !bytes.Equal(partial.Tx.TrimmedCopy().Hash(), unsignedTxHash) || len(partial.Inputs) != len(combined.Inputs) {
+			return nil, &blockchain_errors.ErrPartialTxMismatch{}
+		}
+		for inputIdx, input := range partial.Inputs {
+			if input.Value != combined.Inputs[inputIdx].Value ||
+				!bytes.Equal(input.ScriptPubKey, combined.Inputs[inputIdx].ScriptPubKey) ||
+				!bytes.Equal(input.RedeemScript, combined.Inputs[inputIdx].RedeemScript) {
+				return nil, &blockchain_errors.ErrPartialTxMismatch{}
+			}
+			for pubKeyString, signature := range input.Signatures {
+				pubKey, err := hex.DecodeString(pubKeyString)
+				if err != nil {
+					return nil, err
+				}
+				//signatures are checked again, since partially signed transactions may come from untrusted parties
+				if err := combined.AddSignature(inputIdx, pubKey, signature, params); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return combined, nil
+}
+
+func spentScripts(partial PartiallySignedTx) ([]TXOutput, [][]byte) {
+	var spentOutputs []TXOutput
+	var redeemScripts [][]byte
+	for _, input := range partial.Inputs {
+		spentOutputs = append(spentOutputs, input.spentOutput())
+		redeemScripts = append(redeemScripts, input.RedeemScript)
+	}
+	return spentOutputs, redeemScripts
+}
+
+func (partial *PartiallySignedTx) IsComplete() bool {
+	for _, input := range partial.Inputs {
+		numSignatures, _, _ := script.ExtractMultisig(input.multisigScript())
+		if len(input.Signatures) < numSignatures {
+			return false
+		}
+	}
+	return true
+}
+
+// builds the unlocking scripts of every input from the collected signatures, ordered as their pubkeys in the
+// locking script
+func (partial *PartiallySignedTx) Finalize() (*Transaction, error) {
+	tx := partial.Tx
+	tx.Vin = append([]TXInput{}, partial.Tx.Vin...)
+
+	for inputIdx, input := range partial.Inputs {
+		numSignatures, pubKeys, isMultisig := script.ExtractMultisig(input.multisigScript())
+		if !isMultisig {
+			return nil, &blockchain_errors.ErrNotMultisig{}
+		}
+
+		var signatures [][]byte
+		for _, pubKey := range pubKeys {
+			if signature, signed := input.Signatures[hex.EncodeToString(pubKey)]; signed && len(signatures) < numSignatures {
+				signatures = append(signatures, signature)
+			}
+		}
+		if len(signatures) < numSignatures {
+			return nil, &blockchain_errors.ErrNotEnoughSignatures{}
+		}
+
+		scriptSig, err := script.MultisigScriptSig(signatures)
+		if err != nil {
+			return nil, err
+		}
+		if input.RedeemScript != nil {
+			scriptSig, err = script.ScriptHashScriptSig(scriptSig, input.RedeemScript)
+			if err != nil {
+				return nil, err
+			}
+		}
+		tx.Vin[inputIdx].ScriptSig = scriptSig
+	}
+	return &tx, nil
+}
@@ -0,0 +1,65 @@
+package transactions
+
+import (
+	"fmt"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// the execution of an input's unlocking script against the locking script of the output it spends. checks don't
+// access the chainstate, so they can be executed concurrently
+type ScriptCheck struct {
+	tx          *Transaction
+	inputIdx    int
+	spentOutput TXOutput
+	sigHashes   *sigHashCache //shared by the checks of the transaction's inputs
+	curve       chain_params.SignatureCurve
+}
+
+func NewScriptCheck(tx *Transaction, inputIdx int, spentOutput TXOutput, params *chain_params.Params) ScriptCheck {
+	return ScriptCheck{tx, inputIdx, spentOutput, newSigHashCache(tx), params.SignatureCurve}
+}
+
+// executes the check, returning an error naming the transaction and input which failed
+func (check ScriptCheck) Execute() error {
+	if err := check.execute(); err != nil {
+		return fmt.Errorf("input %d of transaction %x: %w", check.inputIdx, check.tx.Hash(), err)
+	}
+	return nil
+}
+
+func (check ScriptCheck) execute() error {
+	if check.tx.Version == LegacyTxVersion {
+		return check.executeLegacy()
+	}
+	checker := &txSignatureChecker{tx: check.tx, inputIdx: check.inputIdx, spentOutput: check.spentOutput, sigHashes: check.sigHashes, curve: check.curve}
+	if err := script.Verify(check.tx.Vin[check.inputIdx].ScriptSig, check.spentOutput.ScriptPubKey, checker); err != nil {
+		return &blockchain_errors.ErrInvalidTxInputSignature{}
+	}
+	return nil
+}
+
+// looks up the outputs spent by the transaction's inputs, returning the script check of each input
+func (tx *Transaction) ScriptChecks(chainstateDB *leveldb.DB, params *chain_params.Params) ([]ScriptCheck, error) {
+	var scriptChecks []ScriptCheck
+	sigHashes := newSigHashCache(tx)
+	for inputIdx, txIn := range tx.Vin {
+		spentOutput, err := GetUTXO(chainstateDB, txIn.Txid, txIn.OutIndex)
+		if err != nil {
+			return nil, &blockchain_errors.ErrInvalidTxInputSignature{}
+		}
+		scriptChecks = append(scriptChecks, ScriptCheck{tx, inputIdx, spentOutput, sigHashes, params.SignatureCurve})
+	}
+	return scriptChecks, nil
+}
+
+func (check ScriptCheck) Tx() *Transaction {
+	return check.tx
+}
+
+func (check ScriptCheck) SpentOutput() TXOutput {
+	return check.spentOutput
+}
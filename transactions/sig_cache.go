@@ -0,0 +1,101 @@
+package transactions
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+)
+
+// default number of successful signature verifications remembered
+const DefaultSigCacheSize = 100000
+
+// bounded, concurrency safe set of signatures which were already verified successfully, so that transactions checked
+// when entering the memory pool don't have their signatures checked again when they're included in a block
+type SigCache struct {
+	entries    map[[sha256.Size]byte]struct{}
+	maxEntries int
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	mux        sync.RWMutex
+}
+
+type SigCacheStats struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Entries    int    `json:"entries"`
+	MaxEntries int    `json:"maxEntries"`
+}
+
+// cache shared by every signature check
+var SignatureCache = NewSigCache(DefaultSigCacheSize)
+
+func NewSigCache(maxEntries int) *SigCache {
+	return &SigCache{
+		entries:    make(map[[sha256.Size]byte]struct{}),
+		maxEntries: maxEntries,
+	}
+}
+
+// the curve is part of the key, since the same bytes may be valid under one curve and not the other
+func sigCacheKey(curve chain_params.SignatureCurve, sigHash []byte, pubKey []byte, signature []byte) [sha256.Size]byte {
+	var data []byte
+	data = append(data, byte(curve))
+	for _, field := range [][]byte{sigHash, pubKey, signature} {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(field)))
+		data = append(data, field...)
+	}
+	return sha256.Sum256(data)
+}
+
+// whether the signature was already verified successfully, counting a hit or a miss
+func (cache *SigCache) Contains(curve chain_params.SignatureCurve, sigHash []byte, pubKey []byte, signature []byte) bool {
+	key := sigCacheKey(curve, sigHash, pubKey, signature)
+
+	cache.mux.RLock()
+	_, found := cache.entries[key]
+	cache.mux.RUnlock()
+
+	if found {
+		cache.hits.Add(1)
+	} else {
+		cache.misses.Add(1)
+	}
+	return found
+}
+
+// remembers a successfully verified signature, evicting an arbitrary entry if the cache is full
+func (cache *SigCache) Add(curve chain_params.SignatureCurve, sigHash []byte, pubKey []byte, signature []byte) {
+	if cache.maxEntries <= 0 {
+		return
+	}
+	key := sigCacheKey(curve, sigHash, pubKey, signature)
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if _, found := cache.entries[key]; found {
+		return
+	}
+	if len(cache.entries) >= cache.maxEntries {
+		for evictedKey := range cache.entries { //map iteration order is unspecified, so evicted entries vary
+			delete(cache.entries, evictedKey)
+			break
+		}
+	}
+	cache.entries[key] = struct{}{}
+}
+
+func (cache *SigCache) Stats() SigCacheStats {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	return SigCacheStats{
+		Hits:       cache.hits.Load(),
+		Misses:     cache.misses.Load(),
+		Entries:    len(cache.entries),
+		MaxEntries: cache.maxEntries,
+	}
+}
@@ -0,0 +1,155 @@
+package transactions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+)
+
+// appended to every signature, determines which parts of the transaction the signature commits to
+type SigHashType byte
+
+const (
+	SigHashAll          SigHashType = 0x01 //commits to all inputs and outputs
+	SigHashNone         SigHashType = 0x02 //commits to all inputs but no outputs
+	SigHashSingle       SigHashType = 0x03 //commits to all inputs and only the output with the same index as the input
+	SigHashAnyOneCanPay SigHashType = 0x80 //modifier which only commits to the signed input, so others can be added
+
+	sigHashMask = 0x1f
+)
+
+func (hashType SigHashType) IsValid() bool {
+	baseType := hashType &^ SigHashAnyOneCanPay
+	return baseType >= SigHashAll && baseType <= SigHashSingle
+}
+
+// splits a signature into the signature itself and its hash type
+func SplitSignature(signature []byte) ([]byte, SigHashType, bool) {
+	if len(signature) == 0 {
+		return nil, 0, false
+	}
+	hashType := SigHashType(signature[len(signature)-1])
+	if !hashType.IsValid() {
+		return nil, 0, false
+	}
+	return signature[:len(signature)-1], hashType, true
+}
+
+// hashes of all the transaction's outpoints, sequences and outputs, which are shared by the signature hashes of its
+// inputs. hashing them once per transaction keeps computing the signature hash of every input linear in its size
+type sigHashMidstates struct {
+	prevOuts  []byte
+	sequences []byte
+	outputs   []byte
+}
+
+func (tx Transaction) sigHashMidstates() *sigHashMidstates {
+	var prevOuts, sequences, outputs bytes.Buffer
+	for _, txIn := range tx.Vin {
+		writeOutPoint(&prevOuts, txIn)
+		binary.Write(&sequences, binary.LittleEndian, txIn.Sequence)
+	}
+	for _, txOut := range tx.Vout {
+		writeOutput(&outputs, txOut)
+	}
+	return &sigHashMidstates{
+		prevOuts:  sha256Hash(prevOuts.Bytes()),
+		sequences: sha256Hash(sequences.Bytes()),
+		outputs:   sha256Hash(outputs.Bytes()),
+	}
+}
+
+// computes the midstates of a transaction the first time they're needed, shared by the script checks of its inputs
+type sigHashCache struct {
+	tx        *Transaction
+	once      sync.Once
+	midstates *sigHashMidstates
+}
+
+func newSigHashCache(tx *Transaction) *sigHashCache {
+	return &sigHashCache{tx: tx}
+}
+
+func (cache *sigHashCache) get() *sigHashMidstates {
+	cache.once.Do(func() { cache.midstates = cache.tx.sigHashMidstates() })
+	return cache.midstates
+}
+
+// hash which must be signed by the signature of the given input, according to the signature's hash type. besides the
+// parts of the transaction selected by the hash type, it always commits to the input's position and outpoint, the value
+// and locking script of the output it spends and the transaction's version and lock time
+func (tx Transaction) SignatureHash(inputIdx int, hashType SigHashType, spentOutput TXOutput) ([]byte, error) {
+	return tx.signatureHash(tx.sigHashMidstates(), inputIdx, hashType, spentOutput)
+}
+
+func (tx Transaction) signatureHash(midstates *sigHashMidstates, inputIdx int, hashType SigHashType, spentOutput TXOutput) ([]byte, error) {
+	if !hashType.IsValid() || inputIdx < 0 || inputIdx >= len(tx.Vin) {
+		return nil, &blockchain_errors.ErrInvalidSigHashType{}
+	}
+	baseType := hashType & sigHashMask
+	anyOneCanPay := hashType&SigHashAnyOneCanPay != 0
+	if baseType == SigHashSingle && inputIdx >= len(tx.Vout) {
+		return nil, &blockchain_errors.ErrInvalidSigHashType{}
+	}
+
+	//the other inputs and outputs are replaced by zeros when the hash type doesn't commit to them
+	hashPrevOuts := make([]byte, sha256.Size)
+	hashSequences := make([]byte, sha256.Size)
+	hashOutputs := make([]byte, sha256.Size)
+
+	if !anyOneCanPay {
+		hashPrevOuts = midstates.prevOuts
+	}
+
+	if !anyOneCanPay && baseType == SigHashAll {
+		hashSequences = midstates.sequences
+	}
+
+	switch baseType {
+	case SigHashAll:
+		hashOutputs = midstates.outputs
+	case SigHashSingle:
+		var output bytes.Buffer
+		writeOutput(&output, tx.Vout[inputIdx])
+		hashOutputs = sha256Hash(output.Bytes())
+	}
+
+	txIn := tx.Vin[inputIdx]
+	var preimage bytes.Buffer
+	binary.Write(&preimage, binary.LittleEndian, tx.Version)
+	preimage.Write(hashPrevOuts)
+	preimage.Write(hashSequences)
+	binary.Write(&preimage, binary.LittleEndian, uint32(inputIdx))
+	writeOutPoint(&preimage, txIn)
+	writeOutput(&preimage, spentOutput)
+	binary.Write(&preimage, binary.LittleEndian, txIn.Sequence)
+	preimage.Write(hashOutputs)
+	binary.Write(&preimage, binary.LittleEndian, tx.LockTime)
+	binary.Write(&preimage, binary.LittleEndian, uint32(hashType))
+
+	return sha256Hash(preimage.Bytes()), nil
+}
+
+func sha256Hash(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// variable length fields are prefixed with their length, so that different fields can't produce the same preimage
+func writeVarBytes(buf *bytes.Buffer, data []byte) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+}
+
+func writeOutPoint(buf *bytes.Buffer, txIn TXInput) {
+	writeVarBytes(buf, txIn.Txid)
+	binary.Write(buf, binary.LittleEndian, int32(txIn.OutIndex))
+}
+
+func writeOutput(buf *bytes.Buffer, txOut TXOutput) {
+	binary.Write(buf, binary.LittleEndian, int64(txOut.Value))
+	writeVarBytes(buf, txOut.ScriptPubKey)
+}
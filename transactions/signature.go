@@ -0,0 +1,126 @@
+package transactions
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	btcecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+)
+
+// length of BIP340 Schnorr signatures, DER encoded ECDSA signatures of this length aren't accepted so that both can
+// be told apart
+const SchnorrSignatureLen = 64
+
+// checks signatures (followed by their hash type) over the signature hash of one of a transaction's inputs, and the
+// lock times required by that input's locking script
+type txSignatureChecker struct {
+	tx          *Transaction
+	inputIdx    int
+	spentOutput TXOutput //output spent by the input, whose value and locking script are signed
+	sigHashes   *sigHashCache
+	curve       chain_params.SignatureCurve
+}
+
+func (checker *txSignatureChecker) CheckSig(signature []byte, pubKey []byte) bool {
+	signature, hashType, validHashType := SplitSignature(signature)
+	if !validHashType || len(signature) == 0 {
+		return false
+	}
+	//the midstates are shared by the transaction's inputs, so the cache is looked up without rehashing the transaction
+	sigHash, err := checker.tx.signatureHash(checker.sigHashes.get(), checker.inputIdx, hashType, checker.spentOutput)
+	if err != nil {
+		return false
+	}
+
+	if SignatureCache.Contains(checker.curve, sigHash, pubKey, signature) {
+		return true
+	}
+
+	var valid bool
+	switch checker.curve {
+	case chain_params.CurveSecp256k1:
+		valid = verifySecp256k1(signature, pubKey, sigHash)
+	default:
+		valid = verifyP256(signature, pubKey, sigHash)
+	}
+
+	if valid {
+		SignatureCache.Add(checker.curve, sigHash, pubKey, signature)
+	}
+	return valid
+}
+
+// verifies r||s ECDSA signatures made with raw X||Y P256 pubkeys
+func verifyP256(signature []byte, pubKey []byte, sigHash []byte) bool {
+	curve := elliptic.P256()
+
+	r := big.Int{}
+	s := big.Int{}
+	sigLen := len(signature)
+	r.SetBytes(signature[:(sigLen / 2)])
+	s.SetBytes(signature[(sigLen / 2):])
+
+	x := big.Int{}
+	y := big.Int{}
+	keyLen := len(pubKey)
+	x.SetBytes(pubKey[:(keyLen / 2)])
+	y.SetBytes(pubKey[(keyLen / 2):])
+
+	if !curve.IsOnCurve(&x, &y) {
+		return false
+	}
+
+	rawPubKey := ecdsa.PublicKey{Curve: curve, X: &x, Y: &y}
+	return ecdsa.Verify(&rawPubKey, sigHash, &r, &s)
+}
+
+// verifies BIP340 Schnorr signatures or strict DER encoded ECDSA signatures with a low S value, made with compressed
+// secp256k1 pubkeys
+func verifySecp256k1(signature []byte, pubKey []byte, sigHash []byte) bool {
+	if len(pubKey) != btcec.PubKeyBytesLenCompressed {
+		return false
+	}
+	key, err := btcec.ParsePubKey(pubKey)
+	if err != nil {
+		return false
+	}
+
+	if len(signature) == SchnorrSignatureLen {
+		//schnorr signatures commit to the x coordinate of the key only
+		schnorrSig, err := schnorr.ParseSignature(signature)
+		if err != nil {
+			return false
+		}
+		return schnorrSig.Verify(sigHash, key)
+	}
+
+	ecdsaSig, err := btcecdsa.ParseDERSignature(signature)
+	if err != nil {
+		return false
+	}
+	//serializing produces the canonical encoding with a low S value, so malleated signatures don't match it
+	if !bytes.Equal(ecdsaSig.Serialize(), signature) {
+		return false
+	}
+	return ecdsaSig.Verify(sigHash, key)
+}
+
+func (checker *txSignatureChecker) CheckLockTime(lockTime int64) bool {
+	txLockTime := int64(checker.tx.LockTime)
+
+	//lock times by height and by timestamp can't be compared
+	if (txLockTime < int64(LockTimeThreshold)) != (lockTime < int64(LockTimeThreshold)) {
+		return false
+	}
+	if lockTime > txLockTime {
+		return false
+	}
+
+	//the transaction's lock time isn't enforced if the input has the maximum sequence number
+	return checker.tx.Vin[checker.inputIdx].Sequence != MaxSequence
+}
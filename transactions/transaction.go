@@ -2,119 +2,367 @@ package transactions
 
 import (
 	"bytes"
-	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/sha256"
-	"encoding/gob"
+	"encoding/binary"
 	"encoding/hex"
 	"log"
-	"math/big"
 
 	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/chain_params"
+	"github.com/pedrogomes29/blockchain_node/serialization"
 	"github.com/pedrogomes29/blockchain_node/utils"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type Transaction struct {
+	Version    int32 //committed to by signatures, so that the meaning of fields can change in later versions
 	Vin        []TXInput
 	Vout       []TXOutput
 	IsCoinbase bool
+	LockTime   uint32 //block height or unix timestamp before which the transaction can't be included in a block
 }
 
+const TxVersion int32 = 1
+
 const UTXO_PREFIX string = "utxo:"
 const REV_UTXO_PREFIX string = "rev:"
+const UTXO_HEIGHT_PREFIX string = "utxoheight:"
+const REV_UTXO_HEIGHT_PREFIX string = "revheight:"
+
+// the subsidy halves every SubsidyHalvingInterval blocks, so that the coins ever created add up to MaxMoney
+const InitialSubsidy Amount = 10 * BaseUnitsPerCoin
+const SubsidyHalvingInterval = 1050000
 
-const subsidy = 10 //TODO calculate dynamically given number of blocks (deflationary)
+const MaxTxSize = 100 * 1024 //100 KB
 
-func NewCoinbaseTX(receiverAddress string) *Transaction {
-	txout, err := NewTXOutput(subsidy, receiverAddress)
+func BlockSubsidy(height int) Amount {
+	return InitialSubsidy >> (height / SubsidyHalvingInterval) //zero once it has been halved 64 times
+}
+
+// pays the subsidy of a block at the given height to the receiver
+func NewCoinbaseTX(receiverAddress string, height int) *Transaction {
+	txout, err := NewTXOutput(BlockSubsidy(height), receiverAddress)
 	if err != nil {
 		log.Panic(err)
 	}
-	txin := TXInput{[]byte{}, -1, nil, []byte(utils.GenerateRandomString(20))}
-	tx := Transaction{[]TXInput{txin}, []TXOutput{*txout}, true}
+	txin := TXInput{
+		Txid:      []byte{},
+		OutIndex:  -1,
+		ScriptSig: []byte(utils.GenerateRandomString(20)),
+		Sequence:  MaxSequence,
+	}
+	tx := Transaction{Version: TxVersion, Vin: []TXInput{txin}, Vout: []TXOutput{*txout}, IsCoinbase: true}
 	return &tx
 }
 
+const coinbaseFlag uint8 = 1
+
+// encodes the transaction as described in the serialization package, which is what its hash commits to
 func (tx Transaction) Serialize() []byte {
-	var encoded bytes.Buffer
+	w := serialization.NewVersionedWriter()
+	w.WriteInt32(tx.Version)
+	var flags uint8
+	if tx.IsCoinbase {
+		flags |= coinbaseFlag
+	}
+	w.WriteUint8(flags)
+
+	w.WriteVarInt(uint64(len(tx.Vin)))
+	for _, in := range tx.Vin {
+		w.WriteVarBytes(in.Txid)
+		w.WriteInt32(int32(in.OutIndex))
+		w.WriteVarBytes(in.ScriptSig)
+		w.WriteUint32(in.Sequence)
+	}
+	w.WriteVarInt(uint64(len(tx.Vout)))
+	for _, out := range tx.Vout {
+		writeTXOutput(w, out)
+	}
+	w.WriteUint32(tx.LockTime)
 
-	enc := gob.NewEncoder(&encoded)
-	err := enc.Encode(tx)
-	if err != nil {
-		log.Panic(err)
+	return w.Bytes()
+}
+
+// decodes a transaction received from a peer, rejecting anything but its canonical encoding
+func DecodeTransaction(data []byte) (*Transaction, error) {
+	r := serialization.NewVersionedReader(data)
+	tx := Transaction{Version: r.ReadInt32()}
+	flags := r.ReadUint8()
+	if flags&^coinbaseFlag != 0 {
+		return nil, &blockchain_errors.ErrMalformedEncoding{}
+	}
+	tx.IsCoinbase = flags&coinbaseFlag != 0
+
+	tx.Vin = make([]TXInput, r.ReadCount(minInputSize))
+	for i := range tx.Vin {
+		tx.Vin[i] = TXInput{
+			Txid:      r.ReadVarBytes(),
+			OutIndex:  int(r.ReadInt32()),
+			ScriptSig: r.ReadVarBytes(),
+			Sequence:  r.ReadUint32(),
+		}
+	}
+	tx.Vout = make([]TXOutput, r.ReadCount(minOutputSize))
+	for i := range tx.Vout {
+		tx.Vout[i] = readTXOutput(r)
 	}
+	tx.LockTime = r.ReadUint32()
 
-	return encoded.Bytes()
+	if err := r.Finish(); err != nil {
+		return nil, err
+	}
+	return &tx, nil
 }
 
+// decodes a transaction read from storage, which was encoded by this node
 func Deserialize(data []byte) *Transaction {
-	var tx Transaction
-
-	decoder := gob.NewDecoder(bytes.NewReader(data))
-	err := decoder.Decode(&tx)
+	tx, err := DecodeTransaction(data)
 	if err != nil {
 		log.Panic(err)
 	}
+	return tx
+}
 
-	return &tx
+// encodes the transaction without its input scripts, which hold the signatures and can be changed by anyone relaying
+// the transaction without invalidating it. the coinbase's input script isn't a signature and is kept, since it
+// makes coinbases paying the same address distinct
+func (tx Transaction) SerializeWithoutWitness() []byte {
+	if tx.IsCoinbase {
+		return tx.Serialize()
+	}
+	return tx.TrimmedCopy().Serialize()
 }
 
+// the transaction's id, which doesn't change if its signatures are re-encoded. legacy transactions keep the id they
+// had when they were stored with gob (which covers their signatures), unless they aren't a conversion of one
 func (tx Transaction) Hash() []byte {
+	if tx.Version == LegacyTxVersion {
+		if legacyTx, err := tx.toLegacy(); err == nil {
+			return legacyTx.Hash()
+		}
+	}
+	hash := sha256.Sum256(tx.SerializeWithoutWitness())
+
+	return hash[:]
+}
+
+// hash of the whole transaction, including the input scripts
+func (tx Transaction) WitnessHash() []byte {
 	hash := sha256.Sum256(tx.Serialize())
 
 	return hash[:]
 }
 
-func (tx Transaction) Verify(chainstateDB *leveldb.DB) error {
+// verifies the transaction against the current chainstate, assuming it's included in a block with the given height
+// on the network with the given params
+func (tx Transaction) Verify(chainstateDB *leveldb.DB, blockHeight int, params *chain_params.Params) error {
+	if err := tx.verifyStructure(blockHeight, params); err != nil {
+		return err
+	}
+
 	if tx.IsCoinbase {
 		return nil
 	}
 
-	if !tx.VerifyInputSignatures(chainstateDB) {
+	if !tx.VerifyInputSignatures(chainstateDB, params) {
 		return &blockchain_errors.ErrInvalidTxInputSignature{}
 	}
 
-	txInputTotal := 0
+	if _, err := tx.Fee(chainstateDB); err != nil {
+		return err
+	}
+
+	//checked last, so that a transaction which is only sequence locked is otherwise valid
+	return tx.VerifySequenceLocks(chainstateDB, blockHeight)
+}
+
+// same as Verify, except the input scripts aren't executed but returned, so that the caller can run them (e.g. in
+// parallel with the scripts of other transactions)
+func (tx Transaction) VerifyWithoutScripts(chainstateDB *leveldb.DB, blockHeight int, params *chain_params.Params) ([]ScriptCheck, error) {
+	if err := tx.verifyStructure(blockHeight, params); err != nil {
+		return nil, err
+	}
+
+	if tx.IsCoinbase {
+		return nil, nil
+	}
+
+	scriptChecks, err := tx.ScriptChecks(chainstateDB, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Fee(chainstateDB); err != nil {
+		return nil, err
+	}
+
+	if err := tx.VerifySequenceLocks(chainstateDB, blockHeight); err != nil {
+		return nil, err
+	}
+	return scriptChecks, nil
+}
+
+// checks the rules which don't depend on the chainstate, according to the transaction's version.
+// a deployment introducing a new version adds a case with that version's rules
+func (tx Transaction) verifyStructure(blockHeight int, params *chain_params.Params) error {
+	minVersion := TxVersion
+	if params.LegacyTxs {
+		minVersion = LegacyTxVersion
+	}
+	if tx.Version < minVersion || tx.Version > params.MaxTxVersion(blockHeight) {
+		return &blockchain_errors.ErrUnsupportedTxVersion{}
+	}
+
+	//an output spent twice would have its value counted twice
+	spentOutputs := make(map[string]map[int]bool)
+	for _, txInput := range tx.Vin {
+		inputTxHash := hex.EncodeToString(txInput.Txid)
+		if spentOutputs[inputTxHash] == nil {
+			spentOutputs[inputTxHash] = make(map[int]bool)
+		}
+		if spentOutputs[inputTxHash][txInput.OutIndex] {
+			return &blockchain_errors.ErrDuplicateInput{}
+		}
+		spentOutputs[inputTxHash][txInput.OutIndex] = true
+	}
+
+	switch tx.Version {
+	case LegacyTxVersion:
+		return tx.verifyLegacy()
+	case 1:
+		return tx.verifyOutputs()
+	default:
+		return &blockchain_errors.ErrUnsupportedTxVersion{}
+	}
+}
+
+func (tx Transaction) verifyOutputs() error {
+	if _, err := tx.OutputsTotal(); err != nil {
+		return err
+	}
+	return tx.VerifyDataOutputs()
+}
+
+// sums the transaction's outputs, returning an error if any output or the running total is out of range
+func (tx Transaction) OutputsTotal() (Amount, error) {
+	var txOutputTotal Amount
+	for _, txoutput := range tx.Vout {
+		if !MoneyRange(txoutput.Value) {
+			return 0, &blockchain_errors.ErrAmountOutOfRange{}
+		}
+		txOutputTotal += txoutput.Value
+		if !MoneyRange(txOutputTotal) {
+			return 0, &blockchain_errors.ErrAmountOutOfRange{}
+		}
+	}
+	return txOutputTotal, nil
+}
+
+// value sent to a data output could never be spent, so it must be zero
+func (tx Transaction) VerifyDataOutputs() error {
+	for _, txoutput := range tx.Vout {
+		if txoutput.IsData() && txoutput.Value != 0 {
+			return &blockchain_errors.ErrNonZeroDataOutput{}
+		}
+	}
+	return nil
+}
+
+// whether any of the transaction's data outputs carries more than maxDataCarrierSize bytes
+func (tx Transaction) HasDataLargerThan(maxDataCarrierSize int) bool {
+	for _, txoutput := range tx.Vout {
+		if data, isData := txoutput.Data(); isData && len(data) > maxDataCarrierSize {
+			return true
+		}
+	}
+	return false
+}
+
+// sums the UTXOs spent by the transaction, returning an error if any of them is already spent or out of range
+func (tx Transaction) InputsTotal(chainstateDB *leveldb.DB) (Amount, error) {
+	var txInputTotal Amount
 
 	for _, txInput := range tx.Vin {
 		inputTxHash := txInput.Txid
 		inputTxUTXObytes, err := chainstateDB.Get(append([]byte(UTXO_PREFIX), inputTxHash...), nil)
+		if err == leveldb.ErrNotFound {
+			return 0, &blockchain_errors.ErrInvalidInputUTXO{}
+		}
 		if err != nil {
-			return err
+			return 0, err
 		}
 		inputTxUTXOs := DeserializeUTXOs(inputTxUTXObytes)
 		prevUTXO, isUTXO := inputTxUTXOs[txInput.OutIndex]
 		if !isUTXO {
-			return &blockchain_errors.ErrInvalidInputUTXO{}
+			return 0, &blockchain_errors.ErrInvalidInputUTXO{}
 		}
 
+		if !MoneyRange(prevUTXO.Value) {
+			return 0, &blockchain_errors.ErrAmountOutOfRange{}
+		}
 		txInputTotal += prevUTXO.Value
+		if !MoneyRange(txInputTotal) {
+			return 0, &blockchain_errors.ErrAmountOutOfRange{}
+		}
 	}
 
-	txOutputTotal := 0
-	for _, txoutput := range tx.Vout {
-		txOutputTotal += txoutput.Value
+	return txInputTotal, nil
+}
+
+// the difference between the transaction's inputs and outputs, which is collected by the miner (0 for coinbases)
+func (tx Transaction) Fee(chainstateDB *leveldb.DB) (Amount, error) {
+	txOutputTotal, err := tx.OutputsTotal()
+	if err != nil {
+		return 0, err
+	}
+
+	if tx.IsCoinbase {
+		return 0, nil
+	}
+
+	txInputTotal, err := tx.InputsTotal(chainstateDB)
+	if err != nil {
+		return 0, err
 	}
 
 	if txInputTotal < txOutputTotal {
-		return &blockchain_errors.ErrOutputValLGTInputVal{}
+		return 0, &blockchain_errors.ErrOutputValLGTInputVal{}
 	}
 
-	return nil
+	return txInputTotal - txOutputTotal, nil
 }
 
-func (tx Transaction) IndexUTXOs(chainstateDB *leveldb.DB) error {
-	err := tx.Verify(chainstateDB)
+func (tx Transaction) IndexUTXOs(chainstateDB *leveldb.DB, blockHeight int, params *chain_params.Params) error {
+	err := tx.Verify(chainstateDB, blockHeight, params)
 	if err != nil {
 		return err
 	}
+	return tx.ConnectUTXOs(chainstateDB, blockHeight)
+}
 
-	if tx.IsCoinbase {
-		txUTXOs := make(UTXOs)
-		for i, txoutput := range tx.Vout {
-			txUTXOs[i] = txoutput
+// spends the transaction's inputs and adds its outputs to the UTXOs, without verifying that it's valid
+func (tx Transaction) ConnectUTXOs(chainstateDB *leveldb.DB, blockHeight int) error {
+	//indexing would overwrite the unspent outputs of a transaction with the same hash
+	hasUnspentOutputs, err := HasUnspentOutputs(chainstateDB, tx.Hash())
+	if err != nil {
+		return err
+	}
+	if hasUnspentOutputs {
+		return &blockchain_errors.ErrDuplicateTx{}
+	}
+
+	//stores the height at which the outputs were created, used for the relative lock times of inputs spending them
+	if len(tx.spendableOutputs()) > 0 {
+		heightBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(heightBytes, uint32(blockHeight))
+		err = chainstateDB.Put(utxosHeightKey(tx.Hash()), heightBytes, nil)
+		if err != nil {
+			return err
 		}
+	}
+
+	if tx.IsCoinbase {
+		txUTXOs := tx.spendableOutputs()
 		err := chainstateDB.Put(append([]byte(UTXO_PREFIX), tx.Hash()...), txUTXOs.Serialize(), nil)
 		if err != nil {
 			return err
@@ -122,8 +370,6 @@ func (tx Transaction) IndexUTXOs(chainstateDB *leveldb.DB) error {
 		return nil
 	}
 
-	txInputTotal := 0
-
 	//stores updated UTXOs temporarily to only update after verifying transaction is valid
 	inputTxsUpdatedUTXOs := make(map[string]UTXOs)
 
@@ -137,10 +383,6 @@ func (tx Transaction) IndexUTXOs(chainstateDB *leveldb.DB) error {
 			return err
 		}
 		inputTxUTXOs := DeserializeUTXOs(inputTxUTXObytes)
-		prevUTXO := inputTxUTXOs[txInput.OutIndex]
-
-		txInputTotal += prevUTXO.Value
-
 		inputHashString := hex.EncodeToString(inputTxHash)
 		_, spentUTXOFromInputTx := inputTxsSpentUTXOs[inputHashString]
 		if !spentUTXOFromInputTx {
@@ -152,14 +394,7 @@ func (tx Transaction) IndexUTXOs(chainstateDB *leveldb.DB) error {
 		inputTxsUpdatedUTXOs[inputHashString] = inputTxUTXOs
 	}
 
-	txUTXOs := make(UTXOs)
-	txOutputTotal := 0
-	for i, txoutput := range tx.Vout {
-		txUTXOs[i] = txoutput
-		txOutputTotal += txoutput.Value
-	}
-
-	inputTxsUpdatedUTXOs[hex.EncodeToString(tx.Hash())] = txUTXOs
+	inputTxsUpdatedUTXOs[hex.EncodeToString(tx.Hash())] = tx.spendableOutputs()
 
 	for inputTxHashString := range inputTxsUpdatedUTXOs {
 		inputTxHash, err := hex.DecodeString(inputTxHashString)
@@ -187,6 +422,14 @@ func (tx Transaction) IndexUTXOs(chainstateDB *leveldb.DB) error {
 			if err != nil {
 				return err
 			}
+
+			//the height of outputs which were all spent is no longer needed, but is kept to be restored if reversed
+			if len(inputTxUpdatedUTXOs) == 0 {
+				err = moveKey(chainstateDB, utxosHeightKey(inputTxHash), revUTXOsHeightKey(tx.Hash(), inputTxHash))
+				if err != nil {
+					return err
+				}
+			}
 		}
 
 	}
@@ -194,12 +437,33 @@ func (tx Transaction) IndexUTXOs(chainstateDB *leveldb.DB) error {
 	return nil
 }
 
+// the transaction's outputs which are stored in the UTXO set, indexed by their position in the transaction
+func (tx Transaction) spendableOutputs() UTXOs {
+	txUTXOs := make(UTXOs)
+	for i, txoutput := range tx.Vout {
+		if txoutput.IsData() {
+			continue
+		}
+		txUTXOs[i] = txoutput
+	}
+	return txUTXOs
+}
+
 func (tx Transaction) RevertUTXOIndex(chainstateDB *leveldb.DB) error {
 	err := chainstateDB.Delete(append([]byte(UTXO_PREFIX), tx.Hash()...), nil) //deletes UTXOs of the current transaction
 	if err != nil {
 		return err
 	}
 
+	err = chainstateDB.Delete(utxosHeightKey(tx.Hash()), nil)
+	if err != nil {
+		return err
+	}
+
+	if tx.IsCoinbase { //a coinbase's input doesn't spend anything
+		return nil
+	}
+
 	for _, txInput := range tx.Vin {
 		inputTxHash := txInput.Txid
 		inputTxUTXObytes, err := chainstateDB.Get(append([]byte(UTXO_PREFIX), inputTxHash...), nil)
@@ -239,6 +503,11 @@ func (tx Transaction) RevertUTXOIndex(chainstateDB *leveldb.DB) error {
 		if err != nil {
 			return err
 		}
+
+		err = moveKey(chainstateDB, revUTXOsHeightKey(tx.Hash(), inputTxHash), utxosHeightKey(inputTxHash))
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -250,55 +519,33 @@ func (tx Transaction) TrimmedCopy() Transaction {
 
 	for _, txIn := range tx.Vin {
 		inputs = append(inputs, TXInput{
-			txIn.Txid,
-			txIn.OutIndex,
-			nil,
-			nil,
+			Txid:     txIn.Txid,
+			OutIndex: txIn.OutIndex,
+			Sequence: txIn.Sequence,
 		})
 	}
 	for _, txOut := range tx.Vout {
-		outputs = append(outputs, TXOutput{txOut.Value, txOut.PubKeyHash})
+		outputs = append(outputs, TXOutput{txOut.Value, txOut.ScriptPubKey})
 	}
 
 	txTrimmed := Transaction{
-		inputs,
-		outputs,
-		tx.IsCoinbase,
+		Version:    tx.Version,
+		Vin:        inputs,
+		Vout:       outputs,
+		IsCoinbase: tx.IsCoinbase,
+		LockTime:   tx.LockTime,
 	}
 
 	return txTrimmed
 }
 
-func (tx Transaction) VerifyInputSignatures(chainstateDB *leveldb.DB) bool {
-	txCopy := tx.TrimmedCopy()
-	curve := elliptic.P256()
-
-	for _, txIn := range tx.Vin {
-		inputTxUTXObytes, err := chainstateDB.Get(append([]byte(UTXO_PREFIX), txIn.Txid...), nil)
-		if err != nil {
-			return false
-		}
-		inputTxUTXOs := DeserializeUTXOs(inputTxUTXObytes)
-		inputTxUTXO := inputTxUTXOs[txIn.OutIndex]
-
-		if !bytes.Equal(utils.HashPublicKey(txIn.PubKey), inputTxUTXO.PubKeyHash) {
-			return false
-		}
-
-		r := big.Int{}
-		s := big.Int{}
-		sigLen := len(txIn.Signature)
-		r.SetBytes(txIn.Signature[:(sigLen / 2)])
-		s.SetBytes(txIn.Signature[(sigLen / 2):])
-
-		x := big.Int{}
-		y := big.Int{}
-		keyLen := len(txIn.PubKey)
-		x.SetBytes(txIn.PubKey[:(keyLen / 2)])
-		y.SetBytes(txIn.PubKey[(keyLen / 2):])
-
-		rawPubKey := ecdsa.PublicKey{Curve: curve, X: &x, Y: &y}
-		if !ecdsa.Verify(&rawPubKey, txCopy.Hash(), &r, &s) {
+func (tx Transaction) VerifyInputSignatures(chainstateDB *leveldb.DB, params *chain_params.Params) bool {
+	scriptChecks, err := tx.ScriptChecks(chainstateDB, params)
+	if err != nil {
+		return false
+	}
+	for _, scriptCheck := range scriptChecks {
+		if err := scriptCheck.Execute(); err != nil {
 			return false
 		}
 	}
@@ -1,8 +1,11 @@
 package transactions
 
+// inputs with the maximum sequence number don't enforce the transaction's lock time
+const MaxSequence uint32 = 0xffffffff
+
 type TXInput struct {
 	Txid      []byte
 	OutIndex  int
-	Signature []byte
-	PubKey    []byte
+	ScriptSig []byte //unlocking script, satisfying the locking script of the output being spent
+	Sequence  uint32
 }
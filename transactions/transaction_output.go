@@ -3,27 +3,59 @@ package transactions
 import (
 	"bytes"
 
-	"github.com/btcsuite/btcd/btcutil/base58"
-	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/script"
+	"github.com/pedrogomes29/blockchain_node/serialization"
 )
 
 type TXOutput struct {
-	Value      int
-	PubKeyHash []byte
+	Value        Amount
+	ScriptPubKey []byte //locking script, which must be satisfied to spend the output
+}
+
+// smallest encodings of an input and an output, which bound how many of them a transaction's bytes can hold
+const minInputSize = 1 + 4 + 1 + 4
+const minOutputSize = 8 + 1
+
+func writeTXOutput(w *serialization.Writer, out TXOutput) {
+	w.WriteInt64(int64(out.Value))
+	w.WriteVarBytes(out.ScriptPubKey)
+}
+
+func readTXOutput(r *serialization.Reader) TXOutput {
+	return TXOutput{Value: Amount(r.ReadInt64()), ScriptPubKey: r.ReadVarBytes()}
 }
 
 func (out *TXOutput) IsLockedWithKey(pubKeyHash []byte) bool {
-	return bytes.Equal(out.PubKeyHash, pubKeyHash)
+	return bytes.Equal(script.ExtractPubKeyHash(out.ScriptPubKey), pubKeyHash)
 }
 
-func NewTXOutput(value int, address string) (txOutput *TXOutput, err error) {
-	pubKeyHash, version, err := base58.CheckDecode(address)
+func NewTXOutput(value Amount, address string) (txOutput *TXOutput, err error) {
+	scriptPubKey, err := AddressScript(address)
 	if err != nil {
 		return nil, err
 	}
-	if version != 0x00 {
-		return nil, &blockchain_errors.ErrInvalidAddress{}
-	}
-	txo := &TXOutput{value, pubKeyHash}
+	txo := &TXOutput{value, scriptPubKey}
 	return txo, nil
 }
+
+// default maximum number of bytes carried by a data output that a node accepts into its memory pool
+const DefaultMaxDataCarrierSize = 80
+
+// builds a zero value output carrying the given data, which can never be spent
+func NewDataOutput(data []byte) (*TXOutput, error) {
+	scriptPubKey, err := script.NullDataScript(data)
+	if err != nil {
+		return nil, err
+	}
+	return &TXOutput{0, scriptPubKey}, nil
+}
+
+// data outputs are provably unspendable, so they're never stored in the UTXO set
+func (out *TXOutput) IsData() bool {
+	return script.GetScriptClass(out.ScriptPubKey) == script.NullDataTy
+}
+
+// returns the data carried by a data output
+func (out *TXOutput) Data() ([]byte, bool) {
+	return script.ExtractNullData(out.ScriptPubKey)
+}
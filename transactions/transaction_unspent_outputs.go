@@ -2,32 +2,120 @@ package transactions
 
 import (
 	"bytes"
-	"encoding/gob"
+	"encoding/binary"
 	"log"
+	"sort"
+
+	"github.com/pedrogomes29/blockchain_node/blockchain_errors"
+	"github.com/pedrogomes29/blockchain_node/serialization"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type UTXOs map[int]TXOutput
 
+// encodes the outputs by increasing output index, so that the same outputs always have the same encoding
 func (utxos UTXOs) Serialize() []byte {
-	var encoded bytes.Buffer
+	outIndexes := make([]int, 0, len(utxos))
+	for outIndex := range utxos {
+		outIndexes = append(outIndexes, outIndex)
+	}
+	sort.Ints(outIndexes)
+
+	w := serialization.NewVersionedWriter()
+	w.WriteVarInt(uint64(len(outIndexes)))
+	for _, outIndex := range outIndexes {
+		w.WriteUint32(uint32(outIndex))
+		writeTXOutput(w, utxos[outIndex])
+	}
+	return w.Bytes()
+}
+
+func DecodeUTXOs(utxoBytes []byte) (UTXOs, error) {
+	r := serialization.NewVersionedReader(utxoBytes)
+	count := r.ReadCount(4 + minOutputSize)
+	utxos := make(UTXOs, count)
+	prevOutIndex := -1
+	for i := 0; i < count; i++ {
+		outIndex := int(r.ReadUint32())
+		if r.Err() == nil && outIndex <= prevOutIndex {
+			return nil, &blockchain_errors.ErrMalformedEncoding{}
+		}
+		utxos[outIndex] = readTXOutput(r)
+		prevOutIndex = outIndex
+	}
+	if err := r.Finish(); err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
 
-	enc := gob.NewEncoder(&encoded)
-	err := enc.Encode(utxos)
+func DeserializeUTXOs(utxoBytes []byte) UTXOs {
+	utxos, err := DecodeUTXOs(utxoBytes)
 	if err != nil {
 		log.Panic(err)
 	}
+	return utxos
+}
 
-	return encoded.Bytes()
+// whether the transaction with the given hash has outputs which haven't been spent yet
+func HasUnspentOutputs(chainstateDB *leveldb.DB, txHash []byte) (bool, error) {
+	txUTXObytes, err := chainstateDB.Get(append([]byte(UTXO_PREFIX), txHash...), nil)
+	if err == leveldb.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(DeserializeUTXOs(txUTXObytes)) > 0, nil
 }
 
-func DeserializeUTXOs(utxoBytes []byte) UTXOs {
-	var utxos UTXOs
+func utxosHeightKey(txHash []byte) []byte {
+	return append([]byte(UTXO_HEIGHT_PREFIX), txHash...)
+}
 
-	decoder := gob.NewDecoder(bytes.NewReader(utxoBytes))
-	err := decoder.Decode(&utxos)
+func revUTXOsHeightKey(spendingTxHash []byte, txHash []byte) []byte {
+	return bytes.Join([][]byte{[]byte(REV_UTXO_HEIGHT_PREFIX), spendingTxHash, []byte(":"), txHash}, nil)
+}
+
+// moves the value of a key to another key, doing nothing if the key doesn't exist
+func moveKey(chainstateDB *leveldb.DB, from []byte, to []byte) error {
+	value, err := chainstateDB.Get(from, nil)
+	if err == leveldb.ErrNotFound {
+		return nil
+	}
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
+	if err := chainstateDB.Put(to, value, nil); err != nil {
+		return err
+	}
+	return chainstateDB.Delete(from, nil)
+}
 
-	return utxos
+// height of the block which created the unspent outputs of the transaction with the given hash
+func GetUTXOsHeight(chainstateDB *leveldb.DB, txHash []byte) (int, error) {
+	heightBytes, err := chainstateDB.Get(utxosHeightKey(txHash), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, &blockchain_errors.ErrInvalidInputUTXO{}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.LittleEndian.Uint32(heightBytes)), nil
+}
+
+// returns the unspent output with the given index of the transaction with the given hash
+func GetUTXO(chainstateDB *leveldb.DB, txHash []byte, outIndex int) (TXOutput, error) {
+	txUTXObytes, err := chainstateDB.Get(append([]byte(UTXO_PREFIX), txHash...), nil)
+	if err == leveldb.ErrNotFound {
+		return TXOutput{}, &blockchain_errors.ErrInvalidInputUTXO{}
+	}
+	if err != nil {
+		return TXOutput{}, err
+	}
+	utxo, isUTXO := DeserializeUTXOs(txUTXObytes)[outIndex]
+	if !isUTXO {
+		return TXOutput{}, &blockchain_errors.ErrInvalidInputUTXO{}
+	}
+	return utxo, nil
 }
@@ -0,0 +1,20 @@
+package utils
+
+import "testing"
+
+func TestSipHash(t *testing.T) {
+	//reference vectors of SipHash-2-4 with key 00 01 ... 0f and message 00 01 ... (n-1)
+	var key [16]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	message := make([]byte, 15)
+	for i := range message {
+		message[i] = byte(i)
+	}
+	for length, want := range map[int]uint64{0: 0x726fdb47dd0e0e31, 8: 0x93f5f5799a932462, 15: 0xa129ca6149be45e5} {
+		if got := SipHash(key, message[:length]); got != want {
+			t.Fatalf("SipHash of %d bytes is %x instead of %x", length, got, want)
+		}
+	}
+}
@@ -16,6 +16,12 @@ func Uint32ToHex(num uint32) []byte {
 	return hex
 }
 
+func Int64ToHex(num int64) []byte {
+	hex := make([]byte, 8)
+	binary.LittleEndian.PutUint64(hex, uint64(num))
+	return hex
+}
+
 func GenerateRandomString(nrBytes int) string {
 	randData := make([]byte, nrBytes)
 	_, err := rand.Read(randData)